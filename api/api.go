@@ -1,121 +1,175 @@
 package api
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/sammcj/tabload/logging"
 )
 
+// DefaultTimeout bounds regular API calls made by clients from NewClient.
+// Model loads and downloads are long-running and only end when their context
+// is cancelled.
+const DefaultTimeout = 30 * time.Second
+
+// defaultHTTPClient is shared by every Client that doesn't set HTTPClient so
+// connections to the server are reused.
+var defaultHTTPClient = &http.Client{}
+
 func NewClient(baseURL, adminKey string) *Client {
 	return &Client{
 		BaseURL:  baseURL,
 		AdminKey: adminKey,
+		Timeout:  DefaultTimeout,
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
 	}
+	return defaultHTTPClient
 }
 
-func (c *Client) makeHTTPRequest(method, endpoint string, body io.Reader) ([]byte, error) {
+// withTimeout applies the client's timeout to ctx, unless the caller has
+// already set a deadline of its own.
+func (c *Client) withTimeout(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || c.Timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, c.Timeout)
+}
+
+func (c *Client) newRequest(ctx context.Context, method, endpoint string, body io.Reader) (*http.Request, error) {
 	url := c.BaseURL + endpoint
-	req, err := http.NewRequest(method, url, body)
+	req, err := http.NewRequestWithContext(ctx, method, url, body)
 	if err != nil {
 		return nil, fmt.Errorf("creating request: %w", err)
 	}
-	req.Header.Add("X-api-key", c.AdminKey)
+	apiKey := c.APIKey
+	if apiKey == "" {
+		apiKey = c.AdminKey
+	}
+	req.Header.Add("X-api-key", apiKey)
+	if c.AdminKey != "" {
+		req.Header.Add("X-admin-key", c.AdminKey)
+	}
 	if method == http.MethodPost {
 		req.Header.Add("Content-Type", "application/json")
 	}
+	return req, nil
+}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+// doRequest sends a request and returns the response if the server answered
+// with 200 OK, or an *APIError otherwise. The caller must close the response
+// body.
+func (c *Client) doRequest(req *http.Request) (*http.Response, error) {
+	resp, err := c.httpClient().Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("sending request: %w", err)
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		return nil, newAPIError(req, resp.StatusCode, body)
 	}
 
-	return io.ReadAll(resp.Body)
+	return resp, nil
 }
 
-func (c *Client) FetchModels() ([]string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/model/list", nil)
+// makeHTTPRequest sends a request bounded by the client's timeout and returns
+// the response body.
+func (c *Client) makeHTTPRequest(ctx context.Context, method, endpoint string, body io.Reader) ([]byte, error) {
+	ctx, cancel := c.withTimeout(ctx)
+	defer cancel()
+	return c.makeLongHTTPRequest(ctx, method, endpoint, body)
+}
+
+// makeLongHTTPRequest sends a request that is only bounded by ctx, for calls
+// such as downloads that legitimately run for a long time.
+func (c *Client) makeLongHTTPRequest(ctx context.Context, method, endpoint string, body io.Reader) ([]byte, error) {
+	req, err := c.newRequest(ctx, method, endpoint, body)
 	if err != nil {
-		return nil, fmt.Errorf("fetching models: %w", err)
+		return nil, err
 	}
 
-	var response struct {
-		Data []struct {
-			ID string `json:"id"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
 
-	models := make([]string, len(response.Data))
-	for i, model := range response.Data {
-		models[i] = model.ID
-	}
+	return io.ReadAll(resp.Body)
+}
 
-	return models, nil
+func (c *Client) FetchModels() ([]ModelCard, error) {
+	return c.FetchModelsCtx(context.Background())
 }
 
-func (c *Client) FetchDraftModels() ([]string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/model/draft/list", nil)
+func (c *Client) FetchModelsCtx(ctx context.Context) ([]ModelCard, error) {
+	models, err := c.fetchModelCards(ctx, "/v1/model/list")
 	if err != nil {
-		return nil, fmt.Errorf("fetching draft models: %w", err)
+		return nil, fmt.Errorf("fetching models: %w", err)
 	}
+	return models, nil
+}
 
-	var response struct {
-		Data []struct {
-			ID string `json:"id"`
-		} `json:"data"`
-	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return nil, fmt.Errorf("unmarshalling response: %w", err)
-	}
+func (c *Client) FetchDraftModels() ([]ModelCard, error) {
+	return c.FetchDraftModelsCtx(context.Background())
+}
 
-	draftModels := make([]string, len(response.Data))
-	for i, model := range response.Data {
-		draftModels[i] = model.ID
+func (c *Client) FetchDraftModelsCtx(ctx context.Context) ([]ModelCard, error) {
+	draftModels, err := c.fetchModelCards(ctx, "/v1/model/draft/list")
+	if err != nil {
+		return nil, fmt.Errorf("fetching draft models: %w", err)
 	}
-
 	return draftModels, nil
 }
 
-func (c *Client) FetchLoras() ([]string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/lora/list", nil)
+func (c *Client) FetchLoras() ([]ModelCard, error) {
+	return c.FetchLorasCtx(context.Background())
+}
+
+func (c *Client) FetchLorasCtx(ctx context.Context) ([]ModelCard, error) {
+	loras, err := c.fetchModelCards(ctx, "/v1/lora/list")
 	if err != nil {
 		return nil, fmt.Errorf("fetching loras: %w", err)
 	}
+	return loras, nil
+}
+
+// fetchModelCards fetches one of TabbyAPI's model list endpoints.
+func (c *Client) fetchModelCards(ctx context.Context, endpoint string) ([]ModelCard, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
 
 	var response struct {
-		Data []struct {
-			ID string `json:"id"`
-		} `json:"data"`
+		Data []ModelCard `json:"data"`
 	}
 	if err := json.Unmarshal(body, &response); err != nil {
 		return nil, fmt.Errorf("unmarshalling response: %w", err)
 	}
 
-	loras := make([]string, len(response.Data))
-	for i, lora := range response.Data {
-		loras[i] = lora.ID
-	}
-
-	return loras, nil
+	return response.Data, nil
 }
 
 func (c *Client) FetchTemplates() ([]string, error) {
+	return c.FetchTemplatesCtx(context.Background())
+}
+
+func (c *Client) FetchTemplatesCtx(ctx context.Context) ([]string, error) {
 	url := c.BaseURL + "/v1/template/list"
 	logging.Info(fmt.Sprintf("Fetching templates from: %s", url))
 
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/template/list", nil)
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/template/list", nil)
 	if err != nil {
 		return nil, fmt.Errorf("fetching templates: %w", err)
 	}
@@ -131,7 +185,11 @@ func (c *Client) FetchTemplates() ([]string, error) {
 }
 
 func (c *Client) FetchOverrides() ([]string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/sampling/override/list", nil)
+	return c.FetchOverridesCtx(context.Background())
+}
+
+func (c *Client) FetchOverridesCtx(ctx context.Context) ([]string, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/sampling/override/list", nil)
 	if err != nil {
 		return nil, fmt.Errorf("fetching overrides: %w", err)
 	}
@@ -146,114 +204,108 @@ func (c *Client) FetchOverrides() ([]string, error) {
 	return response.Presets, nil
 }
 
-type Model struct {
-	ID         string
-	Parameters struct {
-		MaxSeqLen int
-		CacheSize int
-		RopeScale float64
-		RopeAlpha float64
-		Draft     *struct {
-			ID         string
-			Parameters struct {
-				RopeScale float64
-				RopeAlpha float64
-			}
-		}
-	}
+func (c *Client) FetchOverrideStatus() (*OverrideStatus, error) {
+	return c.FetchOverrideStatusCtx(context.Background())
 }
 
-func (c *Client) FetchCurrentModel() (*Model, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/model", nil)
+// FetchOverrideStatusCtx returns the server's sampler override presets along
+// with the active preset and the overrides in effect.
+func (c *Client) FetchOverrideStatusCtx(ctx context.Context) (*OverrideStatus, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/sampling/override/list", nil)
 	if err != nil {
-		return nil, fmt.Errorf("fetching current model: %w", err)
+		return nil, fmt.Errorf("fetching overrides: %w", err)
 	}
-	var response struct {
-		ID         string `json:"id"`
-		Parameters struct {
-			MaxSeqLen int     `json:"max_seq_len"`
-			CacheSize int     `json:"cache_size"`
-			RopeScale float64 `json:"rope_scale"`
-			RopeAlpha float64 `json:"rope_alpha"`
-			Draft     struct {
-				ID         string `json:"id"`
-				Parameters struct {
-					RopeScale float64 `json:"rope_scale"`
-					RopeAlpha float64 `json:"rope_alpha"`
-				} `json:"parameters"`
-			} `json:"draft"`
-		} `json:"parameters"`
+
+	var status OverrideStatus
+	if err := json.Unmarshal(body, &status); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
 	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return &Model{}, fmt.Errorf("unmarshalling response: %w", err)
-	}
-
-	model := fmt.Sprintf("%s (context: %d, cache size: %d, rope scale: %.2f, rope alpha: %.2f)",
-		response.ID, response.Parameters.MaxSeqLen, response.Parameters.CacheSize,
-		response.Parameters.RopeScale, response.Parameters.RopeAlpha)
-
-	if response.Parameters.Draft.ID != "" {
-		model += fmt.Sprintf(" | %s (rope scale: %.2f, rope alpha: %.2f)",
-			response.Parameters.Draft.ID, response.Parameters.Draft.Parameters.RopeScale,
-			response.Parameters.Draft.Parameters.RopeAlpha)
-	}
-
-	return &Model{
-		ID: response.ID,
-		Parameters: struct {
-			MaxSeqLen int
-			CacheSize int
-			RopeScale float64
-			RopeAlpha float64
-			Draft     *struct {
-				ID         string
-				Parameters struct {
-					RopeScale float64
-					RopeAlpha float64
-				}
-			}
-		}{
-			MaxSeqLen: response.Parameters.MaxSeqLen,
-			CacheSize: response.Parameters.CacheSize,
-		},
-	}, nil
+
+	return &status, nil
 }
 
-func (c *Client) FetchCurrentLoras() (string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/lora", nil)
+func (c *Client) FetchCurrentModel() (*ModelCard, error) {
+	return c.FetchCurrentModelCtx(context.Background())
+}
+
+func (c *Client) FetchCurrentModelCtx(ctx context.Context) (*ModelCard, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/model", nil)
 	if err != nil {
-		return "", fmt.Errorf("fetching current loras: %w", err)
+		return nil, fmt.Errorf("fetching current model: %w", err)
 	}
 
-	var response struct {
-		Data []struct {
-			ID      string  `json:"id"`
-			Scaling float64 `json:"scaling"`
-		} `json:"data"`
+	var model ModelCard
+	if err := json.Unmarshal(body, &model); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
 	}
-	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("unmarshalling response: %w", err)
+
+	return &model, nil
+}
+
+func (c *Client) FetchCurrentLoras() (string, error) {
+	return c.FetchCurrentLorasCtx(context.Background())
+}
+
+func (c *Client) FetchCurrentLorasCtx(ctx context.Context) (string, error) {
+	cards, err := c.FetchLoadedLorasCtx(ctx)
+	if err != nil {
+		return "", err
 	}
 
-	loras := make([]string, len(response.Data))
-	for i, lora := range response.Data {
+	loras := make([]string, len(cards))
+	for i, lora := range cards {
 		loras[i] = fmt.Sprintf("%s (scaling: %.2f)", lora.ID, lora.Scaling)
 	}
 
 	return strings.Join(loras, ", "), nil
 }
 
-func (c *Client) LoadModel(modelName string, params map[string]interface{}) error {
-	jsonData, err := json.Marshal(params)
+func (c *Client) FetchLoadedLoras() ([]LoraCard, error) {
+	return c.FetchLoadedLorasCtx(context.Background())
+}
+
+// FetchLoadedLorasCtx returns the LoRAs currently loaded on the server with
+// their scalings.
+func (c *Client) FetchLoadedLorasCtx(ctx context.Context) ([]LoraCard, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/lora", nil)
 	if err != nil {
-		return fmt.Errorf("marshalling params: %w", err)
+		return nil, fmt.Errorf("fetching current loras: %w", err)
+	}
+
+	var response struct {
+		Data []LoraCard `json:"data"`
 	}
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	}
+
+	return response.Data, nil
+}
+
+func (c *Client) LoadModel(modelName string, params map[string]interface{}) error {
+	return c.LoadModelCtx(context.Background(), modelName, params)
+}
 
-	req, err := c.makeHTTPRequest(http.MethodPost, "/v1/model/load", strings.NewReader(string(jsonData)))
-	logging.Debug("Request: " + string(jsonData))
+func (c *Client) LoadModelCtx(ctx context.Context, modelName string, params map[string]interface{}) error {
+	return c.LoadModelStream(ctx, params, nil)
+}
+
+// LoadModelStream loads a model and reports the per-module progress events
+// that TabbyAPI streams back. onProgress may be nil. Cancelling ctx aborts the
+// request, which makes TabbyAPI stop loading.
+func (c *Client) LoadModelStream(ctx context.Context, params map[string]interface{}, onProgress func(LoadProgress)) error {
+	err := c.streamRequest(ctx, "/v1/model/load", params, func(data []byte) error {
+		var progress LoadProgress
+		if err := json.Unmarshal(data, &progress); err != nil {
+			return fmt.Errorf("unmarshalling progress: %w", err)
+		}
+		if onProgress != nil {
+			onProgress(progress)
+		}
+		return nil
+	})
 	if err != nil {
 		logging.Debug("Error loading model: " + err.Error())
-		logging.Debug("Response: " + string(req))
 		return fmt.Errorf("loading model: %w", err)
 	}
 
@@ -261,6 +313,25 @@ func (c *Client) LoadModel(modelName string, params map[string]interface{}) erro
 }
 
 func (c *Client) LoadLoras(loras []string, scalings []float64) error {
+	return c.LoadLorasCtx(context.Background(), loras, scalings)
+}
+
+func (c *Client) LoadLorasCtx(ctx context.Context, loras []string, scalings []float64) error {
+	if len(loras) == 0 {
+		return fmt.Errorf("no loras to load")
+	}
+	if len(scalings) != len(loras) {
+		return fmt.Errorf("got %d scalings for %d loras", len(scalings), len(loras))
+	}
+	for i, lora := range loras {
+		if lora == "" {
+			return fmt.Errorf("lora %d has no name", i+1)
+		}
+		if scalings[i] < 0 {
+			return fmt.Errorf("lora %s has negative scaling %g", lora, scalings[i])
+		}
+	}
+
 	loadList := make([]map[string]interface{}, len(loras))
 	for i, lora := range loras {
 		loadList[i] = map[string]interface{}{
@@ -278,7 +349,7 @@ func (c *Client) LoadLoras(loras []string, scalings []float64) error {
 		return fmt.Errorf("marshalling request: %w", err)
 	}
 
-	_, err = c.makeHTTPRequest(http.MethodPost, "/v1/lora/load", strings.NewReader(string(jsonData)))
+	_, err = c.makeHTTPRequest(ctx, http.MethodPost, "/v1/lora/load", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return fmt.Errorf("loading loras: %w", err)
 	}
@@ -287,7 +358,11 @@ func (c *Client) LoadLoras(loras []string, scalings []float64) error {
 }
 
 func (c *Client) UnloadModel() error {
-	_, err := c.makeHTTPRequest(http.MethodPost, "/v1/model/unload", nil)
+	return c.UnloadModelCtx(context.Background())
+}
+
+func (c *Client) UnloadModelCtx(ctx context.Context) error {
+	_, err := c.makeHTTPRequest(ctx, http.MethodPost, "/v1/model/unload", nil)
 	if err != nil {
 		return fmt.Errorf("unloading model: %w", err)
 	}
@@ -295,7 +370,11 @@ func (c *Client) UnloadModel() error {
 }
 
 func (c *Client) UnloadLoras() error {
-	_, err := c.makeHTTPRequest(http.MethodPost, "/v1/lora/unload", nil)
+	return c.UnloadLorasCtx(context.Background())
+}
+
+func (c *Client) UnloadLorasCtx(ctx context.Context) error {
+	_, err := c.makeHTTPRequest(ctx, http.MethodPost, "/v1/lora/unload", nil)
 	if err != nil {
 		return fmt.Errorf("unloading loras: %w", err)
 	}
@@ -303,6 +382,10 @@ func (c *Client) UnloadLoras() error {
 }
 
 func (c *Client) LoadTemplate(promptTemplate string) error {
+	return c.LoadTemplateCtx(context.Background(), promptTemplate)
+}
+
+func (c *Client) LoadTemplateCtx(ctx context.Context, promptTemplate string) error {
 	request := map[string]string{
 		"name": promptTemplate,
 	}
@@ -312,7 +395,7 @@ func (c *Client) LoadTemplate(promptTemplate string) error {
 		return fmt.Errorf("marshalling request: %w", err)
 	}
 
-	_, err = c.makeHTTPRequest(http.MethodPost, "/v1/template/switch", strings.NewReader(string(jsonData)))
+	_, err = c.makeHTTPRequest(ctx, http.MethodPost, "/v1/template/switch", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return fmt.Errorf("loading template: %w", err)
 	}
@@ -321,7 +404,11 @@ func (c *Client) LoadTemplate(promptTemplate string) error {
 }
 
 func (c *Client) UnloadTemplate() error {
-	_, err := c.makeHTTPRequest(http.MethodPost, "/v1/template/unload", nil)
+	return c.UnloadTemplateCtx(context.Background())
+}
+
+func (c *Client) UnloadTemplateCtx(ctx context.Context) error {
+	_, err := c.makeHTTPRequest(ctx, http.MethodPost, "/v1/template/unload", nil)
 	if err != nil {
 		return fmt.Errorf("unloading template: %w", err)
 	}
@@ -329,6 +416,10 @@ func (c *Client) UnloadTemplate() error {
 }
 
 func (c *Client) LoadOverride(samplerOverride string) error {
+	return c.LoadOverrideCtx(context.Background(), samplerOverride)
+}
+
+func (c *Client) LoadOverrideCtx(ctx context.Context, samplerOverride string) error {
 	request := map[string]string{
 		"preset": samplerOverride,
 	}
@@ -338,7 +429,7 @@ func (c *Client) LoadOverride(samplerOverride string) error {
 		return fmt.Errorf("marshalling request: %w", err)
 	}
 
-	_, err = c.makeHTTPRequest(http.MethodPost, "/v1/sampling/override/switch", strings.NewReader(string(jsonData)))
+	_, err = c.makeHTTPRequest(ctx, http.MethodPost, "/v1/sampling/override/switch", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return fmt.Errorf("loading override: %w", err)
 	}
@@ -346,8 +437,37 @@ func (c *Client) LoadOverride(samplerOverride string) error {
 	return nil
 }
 
+func (c *Client) ApplyOverrides(overrides map[string]interface{}) error {
+	return c.ApplyOverridesCtx(context.Background(), overrides)
+}
+
+// ApplyOverridesCtx replaces the server's sampler overrides with overrides,
+// keyed by sampler parameter, each holding "override", "force" and optionally
+// "additive" fields.
+func (c *Client) ApplyOverridesCtx(ctx context.Context, overrides map[string]interface{}) error {
+	request := map[string]interface{}{
+		"overrides": overrides,
+	}
+
+	jsonData, err := json.Marshal(request)
+	if err != nil {
+		return fmt.Errorf("marshalling request: %w", err)
+	}
+
+	_, err = c.makeHTTPRequest(ctx, http.MethodPost, "/v1/sampling/override/switch", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return fmt.Errorf("applying overrides: %w", err)
+	}
+
+	return nil
+}
+
 func (c *Client) UnloadOverride() error {
-	_, err := c.makeHTTPRequest(http.MethodPost, "/v1/sampling/override/unload", nil)
+	return c.UnloadOverrideCtx(context.Background())
+}
+
+func (c *Client) UnloadOverrideCtx(ctx context.Context) error {
+	_, err := c.makeHTTPRequest(ctx, http.MethodPost, "/v1/sampling/override/unload", nil)
 	if err != nil {
 		return fmt.Errorf("unloading override: %w", err)
 	}
@@ -355,12 +475,16 @@ func (c *Client) UnloadOverride() error {
 }
 
 func (c *Client) Download(params map[string]interface{}) (string, error) {
+	return c.DownloadCtx(context.Background(), params)
+}
+
+func (c *Client) DownloadCtx(ctx context.Context, params map[string]interface{}) (string, error) {
 	jsonData, err := json.Marshal(params)
 	if err != nil {
 		return "", fmt.Errorf("marshalling params: %w", err)
 	}
 
-	body, err := c.makeHTTPRequest(http.MethodPost, "/v1/download", strings.NewReader(string(jsonData)))
+	body, err := c.makeLongHTTPRequest(ctx, http.MethodPost, "/v1/download", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return "", fmt.Errorf("downloading: %w", err)
 	}
@@ -376,7 +500,11 @@ func (c *Client) Download(params map[string]interface{}) (string, error) {
 }
 
 func (c *Client) CancelDownload() error {
-	_, err := c.makeHTTPRequest(http.MethodPost, "/v1/download/cancel", nil)
+	return c.CancelDownloadCtx(context.Background())
+}
+
+func (c *Client) CancelDownloadCtx(ctx context.Context) error {
+	_, err := c.makeHTTPRequest(ctx, http.MethodPost, "/v1/download/cancel", nil)
 	if err != nil {
 		return fmt.Errorf("cancelling download: %w", err)
 	}
@@ -384,6 +512,10 @@ func (c *Client) CancelDownload() error {
 }
 
 func (c *Client) SaveTemplate(name, content string) error {
+	return c.SaveTemplateCtx(context.Background(), name, content)
+}
+
+func (c *Client) SaveTemplateCtx(ctx context.Context, name, content string) error {
 	params := map[string]string{
 		"name":    name,
 		"content": content,
@@ -394,7 +526,7 @@ func (c *Client) SaveTemplate(name, content string) error {
 		return fmt.Errorf("marshalling params: %w", err)
 	}
 
-	_, err = c.makeHTTPRequest(http.MethodPost, "/v1/template/save", strings.NewReader(string(jsonData)))
+	_, err = c.makeHTTPRequest(ctx, http.MethodPost, "/v1/template/save", strings.NewReader(string(jsonData)))
 	if err != nil {
 		return fmt.Errorf("saving template: %w", err)
 	}
@@ -403,7 +535,11 @@ func (c *Client) SaveTemplate(name, content string) error {
 }
 
 func (c *Client) FetchServerTemplates() ([]string, error) {
-	body, err := c.makeHTTPRequest(http.MethodGet, "/v1/template/list", nil)
+	return c.FetchServerTemplatesCtx(context.Background())
+}
+
+func (c *Client) FetchServerTemplatesCtx(ctx context.Context) ([]string, error) {
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/v1/template/list", nil)
 	if err != nil {
 		return nil, fmt.Errorf("fetching templates: %w", err)
 	}
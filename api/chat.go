@@ -0,0 +1,168 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatMessage is a single turn of a chat conversation.
+type ChatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// Usage is the token accounting TabbyAPI reports with a completion.
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+// GenerationStats times a streamed generation.
+type GenerationStats struct {
+	// TimeToFirstToken is the time from sending the request to receiving the
+	// first generated text. It is zero when the response wasn't streamed.
+	TimeToFirstToken time.Duration
+	// Duration is the time from sending the request to the end of the stream.
+	Duration time.Duration
+	// Chunks is the number of chunks that carried text, used to estimate the
+	// token count when the server doesn't report usage.
+	Chunks int
+	// Usage is the server's token accounting, if it sent any.
+	Usage *Usage
+}
+
+// CompletionTokens returns the number of generated tokens, estimated from
+// the chunk count if the server didn't report usage.
+func (s GenerationStats) CompletionTokens() int {
+	if s.Usage != nil {
+		return s.Usage.CompletionTokens
+	}
+	return s.Chunks
+}
+
+// TokensPerSecond returns the generation speed after the first token, or over
+// the whole request if the time to first token isn't known.
+func (s GenerationStats) TokensPerSecond() float64 {
+	generating := s.Duration - s.TimeToFirstToken
+	tokens := s.CompletionTokens()
+	if s.TimeToFirstToken > 0 {
+		tokens--
+	}
+	if generating <= 0 || tokens <= 0 {
+		return 0
+	}
+	return float64(tokens) / generating.Seconds()
+}
+
+func (s GenerationStats) String() string {
+	stats := fmt.Sprintf("%d tokens, %.1f tokens/s", s.CompletionTokens(), s.TokensPerSecond())
+	if s.TimeToFirstToken > 0 {
+		stats += fmt.Sprintf(", first token after %s", s.TimeToFirstToken.Round(time.Millisecond))
+	}
+	return stats + fmt.Sprintf(", total %s", s.Duration.Round(time.Millisecond))
+}
+
+// statsRecorder tracks the timing of a generation as its chunks arrive.
+type statsRecorder struct {
+	start time.Time
+	stats GenerationStats
+}
+
+func newStatsRecorder() *statsRecorder {
+	return &statsRecorder{start: time.Now()}
+}
+
+func (r *statsRecorder) chunk(text string) {
+	if text == "" {
+		return
+	}
+	if r.stats.Chunks == 0 {
+		r.stats.TimeToFirstToken = time.Since(r.start)
+	}
+	r.stats.Chunks++
+}
+
+func (r *statsRecorder) finish(usage *Usage) GenerationStats {
+	r.stats.Duration = time.Since(r.start)
+	if usage != nil {
+		r.stats.Usage = usage
+	}
+	return r.stats
+}
+
+// ChatResult is the outcome of a chat completion.
+type ChatResult struct {
+	Message      ChatMessage
+	FinishReason string
+	Stats        GenerationStats
+}
+
+type chatCompletionChunk struct {
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+// ChatCompletionStream sends messages to /v1/chat/completions and streams the
+// reply, calling onDelta with each piece of text as it arrives. params holds
+// the sampling parameters. Cancelling ctx stops generation; the text received
+// so far is still returned alongside ctx's error.
+func (c *Client) ChatCompletionStream(ctx context.Context, messages []ChatMessage, params map[string]interface{}, onDelta func(string)) (*ChatResult, error) {
+	request := make(map[string]interface{}, len(params)+3)
+	for k, v := range params {
+		request[k] = v
+	}
+	request["messages"] = messages
+	request["stream"] = true
+	request["stream_options"] = map[string]interface{}{"include_usage": true}
+
+	var content strings.Builder
+	var usage *Usage
+	result := &ChatResult{Message: ChatMessage{Role: RoleAssistant}}
+	recorder := newStatsRecorder()
+
+	err := c.streamRequest(ctx, "/v1/chat/completions", request, func(data []byte) error {
+		var chunk chatCompletionChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("unmarshalling chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+			if choice.Delta.Content == "" {
+				continue
+			}
+			recorder.chunk(choice.Delta.Content)
+			content.WriteString(choice.Delta.Content)
+			if onDelta != nil {
+				onDelta(choice.Delta.Content)
+			}
+		}
+		return nil
+	})
+
+	result.Message.Content = content.String()
+	result.Stats = recorder.finish(usage)
+	if err != nil {
+		return result, fmt.Errorf("chat completion: %w", err)
+	}
+	return result, nil
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// TokenLogprob is the log probability of a generated token and, if requested,
+// of the most likely alternatives at its position.
+type TokenLogprob struct {
+	Token   string             `json:"token"`
+	Logprob float64            `json:"logprob"`
+	Top     map[string]float64 `json:"top_logprobs,omitempty"`
+}
+
+// CompletionResult is the outcome of a text completion.
+type CompletionResult struct {
+	Text         string
+	FinishReason string
+	// Logprobs holds one entry per generated token when logprobs were
+	// requested.
+	Logprobs []TokenLogprob
+	Stats    GenerationStats
+}
+
+type completionLogprobs struct {
+	Tokens        []string             `json:"tokens"`
+	TokenLogprobs []*float64           `json:"token_logprobs"`
+	TopLogprobs   []map[string]float64 `json:"top_logprobs"`
+}
+
+func (l *completionLogprobs) entries() []TokenLogprob {
+	if l == nil {
+		return nil
+	}
+	entries := make([]TokenLogprob, len(l.Tokens))
+	for i, token := range l.Tokens {
+		entries[i].Token = token
+		if i < len(l.TokenLogprobs) && l.TokenLogprobs[i] != nil {
+			entries[i].Logprob = *l.TokenLogprobs[i]
+		}
+		if i < len(l.TopLogprobs) {
+			entries[i].Top = l.TopLogprobs[i]
+		}
+	}
+	return entries
+}
+
+type completionResponse struct {
+	Choices []struct {
+		Text         string              `json:"text"`
+		FinishReason string              `json:"finish_reason"`
+		Logprobs     *completionLogprobs `json:"logprobs"`
+	} `json:"choices"`
+	Usage *Usage `json:"usage"`
+}
+
+func completionRequest(prompt string, params map[string]interface{}, stream bool) map[string]interface{} {
+	request := make(map[string]interface{}, len(params)+3)
+	for k, v := range params {
+		request[k] = v
+	}
+	request["prompt"] = prompt
+	request["stream"] = stream
+	if stream {
+		request["stream_options"] = map[string]interface{}{"include_usage": true}
+	}
+	return request
+}
+
+func (c *Client) Completion(prompt string, params map[string]interface{}) (*CompletionResult, error) {
+	return c.CompletionCtx(context.Background(), prompt, params)
+}
+
+// CompletionCtx sends a raw prompt to /v1/completions and waits for the whole
+// completion. params holds the sampling parameters and options such as
+// max_tokens, stop and logprobs. The request is only bounded by ctx, as
+// generation can take longer than the client's timeout.
+func (c *Client) CompletionCtx(ctx context.Context, prompt string, params map[string]interface{}) (*CompletionResult, error) {
+	jsonData, err := json.Marshal(completionRequest(prompt, params, false))
+	if err != nil {
+		return nil, fmt.Errorf("marshalling params: %w", err)
+	}
+
+	recorder := newStatsRecorder()
+	body, err := c.makeLongHTTPRequest(ctx, http.MethodPost, "/v1/completions", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, fmt.Errorf("completion: %w", err)
+	}
+
+	var response completionResponse
+	if err := json.Unmarshal(body, &response); err != nil {
+		return nil, fmt.Errorf("unmarshalling response: %w", err)
+	}
+
+	result := &CompletionResult{}
+	for _, choice := range response.Choices {
+		result.Text += choice.Text
+		result.FinishReason = choice.FinishReason
+		result.Logprobs = append(result.Logprobs, choice.Logprobs.entries()...)
+	}
+	result.Stats = recorder.finish(response.Usage)
+	if response.Usage == nil && result.Text != "" {
+		result.Stats.Chunks = 1
+	}
+
+	return result, nil
+}
+
+// CompletionStream sends a raw prompt to /v1/completions and streams the
+// completion, calling onChunk with each piece of text and its token
+// logprobs as they arrive. Cancelling ctx stops generation; the text received
+// so far is still returned alongside ctx's error.
+func (c *Client) CompletionStream(ctx context.Context, prompt string, params map[string]interface{}, onChunk func(text string, logprobs []TokenLogprob)) (*CompletionResult, error) {
+	var text strings.Builder
+	var usage *Usage
+	result := &CompletionResult{}
+	recorder := newStatsRecorder()
+
+	err := c.streamRequest(ctx, "/v1/completions", completionRequest(prompt, params, true), func(data []byte) error {
+		var chunk completionResponse
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			return fmt.Errorf("unmarshalling chunk: %w", err)
+		}
+		if chunk.Usage != nil {
+			usage = chunk.Usage
+		}
+		for _, choice := range chunk.Choices {
+			if choice.FinishReason != "" {
+				result.FinishReason = choice.FinishReason
+			}
+			logprobs := choice.Logprobs.entries()
+			result.Logprobs = append(result.Logprobs, logprobs...)
+			if choice.Text == "" && len(logprobs) == 0 {
+				continue
+			}
+			recorder.chunk(choice.Text)
+			text.WriteString(choice.Text)
+			if onChunk != nil {
+				onChunk(choice.Text, logprobs)
+			}
+		}
+		return nil
+	})
+
+	result.Text = text.String()
+	result.Stats = recorder.finish(usage)
+	if err != nil {
+		return result, fmt.Errorf("completion: %w", err)
+	}
+	return result, nil
+}
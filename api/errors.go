@@ -0,0 +1,177 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxErrorBodySize caps how much of an error response body is read.
+const maxErrorBodySize = 64 * 1024
+
+// APIError is returned when TabbyAPI answers with a non-200 status. It carries
+// the decoded "detail" payload so callers can use errors.As to get at the
+// server's message and any field-level validation errors.
+type APIError struct {
+	StatusCode int
+	Method     string
+	Endpoint   string
+
+	// Message is the detail message for errors that aren't validation errors.
+	Message string
+	// Validation holds the pydantic validation errors of a 422 response.
+	Validation []ValidationError
+	// Body is the raw response body, for payloads that couldn't be decoded.
+	Body string
+}
+
+// ValidationError is a single pydantic validation error from a 422 response.
+type ValidationError struct {
+	Loc  []string `json:"-"`
+	Msg  string   `json:"msg"`
+	Type string   `json:"type"`
+}
+
+func (e *APIError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%s %s: %d %s", e.Method, e.Endpoint, e.StatusCode, http.StatusText(e.StatusCode))
+
+	switch {
+	case len(e.Validation) > 0:
+		for i, v := range e.Validation {
+			if i == 0 {
+				sb.WriteString(": ")
+			} else {
+				sb.WriteString("; ")
+			}
+			sb.WriteString(v.String())
+		}
+	case e.Message != "":
+		sb.WriteString(": " + e.Message)
+	case e.Body != "":
+		sb.WriteString(": " + e.Body)
+	}
+
+	return sb.String()
+}
+
+// IsNoModelLoaded reports whether err is TabbyAPI's answer to asking for the
+// current model when none is loaded: a 400 saying "No models are currently
+// loaded." Any other error, such as a bad key or a server fault, is not.
+func IsNoModelLoaded(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		return false
+	}
+	message := strings.ToLower(apiErr.Message + apiErr.Body)
+	return strings.Contains(message, "no model") && strings.Contains(message, "loaded")
+}
+
+// FieldErrors maps request field paths (see ValidationError.Field) to their
+// validation messages. Multiple messages for one field are joined.
+func (e *APIError) FieldErrors() map[string]string {
+	fields := make(map[string]string, len(e.Validation))
+	for _, v := range e.Validation {
+		field := v.Field()
+		if existing, ok := fields[field]; ok {
+			fields[field] = existing + "; " + v.Msg
+		} else {
+			fields[field] = v.Msg
+		}
+	}
+	return fields
+}
+
+// Field returns the dotted path of the request field the error refers to,
+// without the leading "body" location and any list indexes, e.g.
+// "draft.draft_rope_scale" or "gpu_split".
+func (v ValidationError) Field() string {
+	parts := make([]string, 0, len(v.Loc))
+	for i, loc := range v.Loc {
+		if i == 0 && (loc == "body" || loc == "query" || loc == "path") {
+			continue
+		}
+		if _, err := strconv.Atoi(loc); err == nil {
+			continue
+		}
+		parts = append(parts, loc)
+	}
+	return strings.Join(parts, ".")
+}
+
+func (v ValidationError) String() string {
+	if field := v.Field(); field != "" {
+		return field + ": " + v.Msg
+	}
+	return v.Msg
+}
+
+func (v *ValidationError) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Loc  []interface{} `json:"loc"`
+		Msg  string        `json:"msg"`
+		Type string        `json:"type"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	v.Msg = raw.Msg
+	v.Type = raw.Type
+	v.Loc = make([]string, len(raw.Loc))
+	for i, loc := range raw.Loc {
+		v.Loc[i] = fmt.Sprint(loc)
+	}
+	return nil
+}
+
+// newAPIError builds an APIError from a failed response, decoding whichever of
+// TabbyAPI's error shapes the body holds.
+func newAPIError(req *http.Request, statusCode int, body []byte) *APIError {
+	apiErr := &APIError{
+		StatusCode: statusCode,
+		Method:     req.Method,
+		Endpoint:   req.URL.Path,
+	}
+
+	var payload struct {
+		Detail json.RawMessage `json:"detail"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		apiErr.Body = strings.TrimSpace(string(body))
+		return apiErr
+	}
+
+	if payload.Error != nil {
+		apiErr.Message = payload.Error.Message
+		return apiErr
+	}
+
+	var message string
+	var validation []ValidationError
+	var nested struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	switch {
+	case len(payload.Detail) == 0:
+		apiErr.Body = strings.TrimSpace(string(body))
+	case json.Unmarshal(payload.Detail, &message) == nil:
+		apiErr.Message = message
+	case json.Unmarshal(payload.Detail, &validation) == nil:
+		apiErr.Validation = validation
+	case json.Unmarshal(payload.Detail, &nested) == nil && nested.Error.Message != "":
+		apiErr.Message = nested.Error.Message
+	default:
+		apiErr.Body = strings.TrimSpace(string(payload.Detail))
+	}
+
+	return apiErr
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+const (
+	HealthHealthy   = "healthy"
+	HealthUnhealthy = "unhealthy"
+)
+
+// Health is the response of TabbyAPI's /health endpoint.
+type Health struct {
+	Status string        `json:"status"`
+	Issues []HealthIssue `json:"issues,omitempty"`
+}
+
+// HealthIssue is a problem the server reports on /health, such as a failed
+// generation.
+type HealthIssue struct {
+	Time        string `json:"time"`
+	Description string `json:"description"`
+}
+
+// Healthy reports whether the server considers itself healthy. Older servers
+// answer with an empty body, which counts as healthy.
+func (h *Health) Healthy() bool {
+	return h.Status == "" || h.Status == HealthHealthy
+}
+
+func (c *Client) FetchHealth() (*Health, error) {
+	return c.FetchHealthCtx(context.Background())
+}
+
+// FetchHealthCtx returns the server's health. An unhealthy server answers
+// with 503 Service Unavailable, which is returned as its decoded Health rather
+// than as an error.
+func (c *Client) FetchHealthCtx(ctx context.Context) (*Health, error) {
+	var health Health
+
+	body, err := c.makeHTTPRequest(ctx, http.MethodGet, "/health", nil)
+	var apiErr *APIError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusServiceUnavailable:
+		health.Status = HealthUnhealthy
+		if apiErr.Message != "" {
+			health.Issues = []HealthIssue{{Description: apiErr.Message}}
+		} else if apiErr.Body != "" {
+			_ = json.Unmarshal([]byte(apiErr.Body), &health)
+		}
+		return &health, nil
+	case err != nil:
+		return nil, fmt.Errorf("fetching health: %w", err)
+	}
+
+	if len(body) > 0 {
+		if err := json.Unmarshal(body, &health); err != nil {
+			return nil, fmt.Errorf("unmarshalling response: %w", err)
+		}
+	}
+
+	return &health, nil
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/sammcj/tabload/logging"
+)
+
+// maxEventSize caps a single server-sent event so a misbehaving server can't
+// make us buffer without bound.
+const maxEventSize = 1024 * 1024
+
+// streamRequest POSTs params as JSON to endpoint and calls handle with the data
+// of each server-sent event in the response. It is only bounded by ctx.
+func (c *Client) streamRequest(ctx context.Context, endpoint string, params interface{}, handle func(data []byte) error) error {
+	jsonData, err := json.Marshal(params)
+	if err != nil {
+		return fmt.Errorf("marshalling params: %w", err)
+	}
+	logging.Debug("Request: " + string(jsonData))
+
+	req, err := c.newRequest(ctx, http.MethodPost, endpoint, bytes.NewReader(jsonData))
+	if err != nil {
+		return err
+	}
+	req.Header.Add("Accept", "text/event-stream")
+
+	resp, err := c.doRequest(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	return readEvents(resp.Body, handle)
+}
+
+// readEvents reads a text/event-stream body and calls handle with the data of
+// each event. It stops at the end of the stream, on a "[DONE]" sentinel, or
+// when TabbyAPI sends an error payload in place of a regular event.
+func readEvents(r io.Reader, handle func(data []byte) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxEventSize)
+
+	var data []byte
+	dispatch := func() (bool, error) {
+		if len(data) == 0 {
+			return false, nil
+		}
+		event := data
+		data = nil
+
+		if bytes.Equal(event, []byte("[DONE]")) {
+			return true, nil
+		}
+		if err := eventError(event); err != nil {
+			return true, err
+		}
+		return false, handle(event)
+	}
+
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			done, err := dispatch()
+			if done || err != nil {
+				return err
+			}
+			continue
+		}
+
+		field, value, _ := bytes.Cut(line, []byte(":"))
+		if string(field) != "data" {
+			// Comments (pings), event names and ids carry nothing we use
+			continue
+		}
+		value = bytes.TrimPrefix(value, []byte(" "))
+		if len(data) > 0 {
+			data = append(data, '\n')
+		}
+		data = append(data, value...)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading event stream: %w", err)
+	}
+
+	_, err := dispatch()
+	return err
+}
+
+// eventError extracts the error TabbyAPI reports inside an event stream, if
+// the event is one.
+func eventError(data []byte) error {
+	var payload struct {
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err := json.Unmarshal(data, &payload); err != nil || payload.Error == nil {
+		return nil
+	}
+	if payload.Error.Message == "" {
+		return errors.New("server reported an error")
+	}
+	return errors.New(payload.Error.Message)
+}
@@ -0,0 +1,53 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// TLSOptions controls how the client verifies a TabbyAPI server's certificate.
+type TLSOptions struct {
+	// InsecureSkipVerify disables certificate verification entirely.
+	InsecureSkipVerify bool
+	// CACertFile is a PEM file of extra CAs to trust, for self-signed servers.
+	CACertFile string
+}
+
+// ConfigureTLS makes the client verify servers according to opts. Zero options
+// restore the shared default HTTP client.
+func (c *Client) ConfigureTLS(opts TLSOptions) error {
+	if opts == (TLSOptions{}) {
+		c.HTTPClient = nil
+		return nil
+	}
+
+	tlsConfig := &tls.Config{
+		InsecureSkipVerify: opts.InsecureSkipVerify,
+	}
+
+	if opts.CACertFile != "" {
+		pem, err := os.ReadFile(opts.CACertFile)
+		if err != nil {
+			return fmt.Errorf("reading CA certificate: %w", err)
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return errors.New("no certificates found in CA certificate file")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	c.HTTPClient = &http.Client{Transport: transport}
+
+	return nil
+}
@@ -1,6 +1,213 @@
 package api
 
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
 type Client struct {
 	BaseURL  string
 	AdminKey string
+	// APIKey is sent for regular API access. The admin key is used when empty.
+	APIKey string
+
+	// HTTPClient sends every request. A shared default client is used when nil.
+	HTTPClient *http.Client
+	// Timeout bounds each regular API call whose context has no deadline of
+	// its own. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// Model types reported in LoadProgress.ModelType.
+const (
+	ModelTypeMain  = "model"
+	ModelTypeDraft = "draft"
+)
+
+// LoadProgress is a single progress event from the /v1/model/load stream.
+type LoadProgress struct {
+	ModelType string `json:"model_type"`
+	Module    int    `json:"module"`
+	Modules   int    `json:"modules"`
+	Status    string `json:"status"`
+}
+
+// Finished reports whether the event marks the end of a model load.
+func (p LoadProgress) Finished() bool {
+	return p.Status == "finished"
+}
+
+// Fraction returns the load progress as a value between 0 and 1.
+func (p LoadProgress) Fraction() float64 {
+	if p.Modules <= 0 {
+		return 0
+	}
+	return float64(p.Module) / float64(p.Modules)
+}
+
+// ModelCard describes a model, draft model or LoRA as returned by TabbyAPI's
+// list and current-model endpoints.
+type ModelCard struct {
+	ID         string               `json:"id"`
+	Object     string               `json:"object,omitempty"`
+	Created    int64                `json:"created,omitempty"`
+	OwnedBy    string               `json:"owned_by,omitempty"`
+	Logging    *LogPreferences      `json:"logging,omitempty"`
+	Parameters *ModelCardParameters `json:"parameters,omitempty"`
+}
+
+// ModelCardParameters holds the parameters a loaded model is running with.
+// Fields the server leaves null are left at their zero value.
+type ModelCardParameters struct {
+	MaxSeqLen          int        `json:"max_seq_len,omitempty"`
+	RopeScale          float64    `json:"rope_scale,omitempty"`
+	RopeAlpha          float64    `json:"rope_alpha,omitempty"`
+	CacheSize          int        `json:"cache_size,omitempty"`
+	CacheMode          string     `json:"cache_mode,omitempty"`
+	ChunkSize          int        `json:"chunk_size,omitempty"`
+	PromptTemplate     string     `json:"prompt_template,omitempty"`
+	NumExpertsPerToken int        `json:"num_experts_per_token,omitempty"`
+	GPUSplitAuto       bool       `json:"gpu_split_auto,omitempty"`
+	GPUSplit           []float64  `json:"gpu_split,omitempty"`
+	Draft              *ModelCard `json:"draft,omitempty"`
+}
+
+// LogPreferences reports what the server logs for each request.
+type LogPreferences struct {
+	Prompt           bool `json:"prompt"`
+	GenerationParams bool `json:"generation_params"`
+}
+
+// ModelIDs returns the IDs of the given cards, in order.
+func ModelIDs(cards []ModelCard) []string {
+	ids := make([]string, len(cards))
+	for i, card := range cards {
+		ids[i] = card.ID
+	}
+	return ids
+}
+
+// Draft returns the card of the draft model loaded alongside this one, if any.
+func (m *ModelCard) Draft() *ModelCard {
+	if m.Parameters == nil || m.Parameters.Draft == nil || m.Parameters.Draft.ID == "" {
+		return nil
+	}
+	return m.Parameters.Draft
+}
+
+// Summary formats the model and its draft model on a single line.
+func (m *ModelCard) Summary() string {
+	summary := m.ID
+	if p := m.Parameters; p != nil {
+		summary += fmt.Sprintf(" (context: %d, cache size: %d, cache mode: %s, rope scale: %.2f, rope alpha: %.2f)",
+			p.MaxSeqLen, p.CacheSize, p.CacheMode, p.RopeScale, p.RopeAlpha)
+	}
+
+	if draft := m.Draft(); draft != nil {
+		summary += " | " + draft.ID
+		if p := draft.Parameters; p != nil {
+			summary += fmt.Sprintf(" (rope scale: %.2f, rope alpha: %.2f)", p.RopeScale, p.RopeAlpha)
+		}
+	}
+
+	return summary
+}
+
+// LoadParams returns a /v1/model/load request body that loads the model again
+// with the parameters it's running with, e.g. to restore it after loading
+// another model.
+func (m *ModelCard) LoadParams() map[string]interface{} {
+	params := map[string]interface{}{
+		"name": m.ID,
+	}
+
+	p := m.Parameters
+	if p == nil {
+		return params
+	}
+	if p.MaxSeqLen > 0 {
+		params["max_seq_len"] = p.MaxSeqLen
+	}
+	if p.CacheSize > 0 {
+		params["cache_size"] = p.CacheSize
+	}
+	if p.CacheMode != "" {
+		params["cache_mode"] = p.CacheMode
+	}
+	if p.RopeScale > 0 {
+		params["rope_scale"] = p.RopeScale
+	}
+	if p.RopeAlpha > 0 {
+		params["rope_alpha"] = p.RopeAlpha
+	}
+	if p.ChunkSize > 0 {
+		params["chunk_size"] = p.ChunkSize
+	}
+	if p.PromptTemplate != "" {
+		params["prompt_template"] = p.PromptTemplate
+	}
+	if p.NumExpertsPerToken > 0 {
+		params["num_experts_per_token"] = p.NumExpertsPerToken
+	}
+	if p.GPUSplitAuto {
+		params["gpu_split_auto"] = true
+	} else if len(p.GPUSplit) > 0 {
+		params["gpu_split"] = p.GPUSplit
+	}
+
+	if draft := m.Draft(); draft != nil {
+		draftParams := map[string]interface{}{
+			"draft_model_name": draft.ID,
+		}
+		if dp := draft.Parameters; dp != nil {
+			if dp.RopeScale > 0 {
+				draftParams["draft_rope_scale"] = dp.RopeScale
+			}
+			if dp.RopeAlpha > 0 {
+				draftParams["draft_rope_alpha"] = dp.RopeAlpha
+			}
+			if dp.CacheMode != "" {
+				draftParams["draft_cache_mode"] = dp.CacheMode
+			}
+		}
+		params["draft"] = draftParams
+	}
+
+	return params
+}
+
+// OverrideParams returns the overrides in the form ApplyOverrides takes, to
+// put them back after switching to others.
+func (s *OverrideStatus) OverrideParams() map[string]interface{} {
+	params := make(map[string]interface{}, len(s.Overrides))
+	for key, value := range s.Overrides {
+		params[key] = value
+	}
+	return params
+}
+
+// OverrideStatus describes the server's sampler overrides.
+type OverrideStatus struct {
+	// Presets are the override presets in the server's sampler_overrides
+	// directory.
+	Presets []string `json:"presets"`
+	// Preset is the active preset, or "" if none is or the overrides were set
+	// directly.
+	Preset string `json:"selected_preset"`
+	// Overrides are the overrides in effect, keyed by sampler parameter.
+	Overrides map[string]OverrideValue `json:"overrides"`
+}
+
+// OverrideValue is a single sampler override in effect on the server.
+type OverrideValue struct {
+	Override interface{} `json:"override"`
+	Force    bool        `json:"force"`
+	Additive bool        `json:"additive,omitempty"`
+}
+
+// LoraCard is a LoRA loaded on the server, as listed by /v1/lora.
+type LoraCard struct {
+	ID      string  `json:"id"`
+	Scaling float64 `json:"scaling"`
 }
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestFetchOverrideStatus(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want OverrideStatus
+	}{
+		{
+			name: "active preset",
+			body: `{"presets": ["safe_defaults", "creative"], "selected_preset": "creative", "overrides": {"temperature": {"override": 1.2, "force": false}, "top_p": {"override": 0.9, "force": true}}}`,
+			want: OverrideStatus{
+				Presets: []string{"safe_defaults", "creative"},
+				Preset:  "creative",
+				Overrides: map[string]OverrideValue{
+					"temperature": {Override: 1.2},
+					"top_p":       {Override: 0.9, Force: true},
+				},
+			},
+		},
+		{
+			name: "no preset",
+			body: `{"presets": ["safe_defaults"], "selected_preset": null, "overrides": {}}`,
+			want: OverrideStatus{Presets: []string{"safe_defaults"}, Overrides: map[string]OverrideValue{}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/v1/sampling/override/list" {
+					http.NotFound(w, r)
+					return
+				}
+				w.Header().Set("Content-Type", "application/json")
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			got, err := NewClient(server.URL, "").FetchOverrideStatus()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("got %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,432 @@
+// Package apply puts a preset into effect on a TabbyAPI server in one go:
+// the model, its LoRA stack, the prompt template and the sampler override.
+// Each step is reported as it runs, and if one fails the steps already taken
+// are undone so the server is left as it was.
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+// Status is the state of a step.
+type Status int
+
+const (
+	// Pending means the step hasn't run yet.
+	Pending Status = iota
+	// Running means the step is in progress.
+	Running
+	// Done means the step succeeded.
+	Done
+	// Skipped means the step wasn't needed, e.g. the model was already loaded.
+	Skipped
+	// Failed means the step failed, stopping the apply.
+	Failed
+	// RolledBack means the step succeeded but was undone after a later step
+	// failed.
+	RolledBack
+	// RollbackFailed means undoing the step failed, so the server may be left
+	// part way between the old state and the preset.
+	RollbackFailed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pending:
+		return "pending"
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	case Skipped:
+		return "skipped"
+	case Failed:
+		return "failed"
+	case RolledBack:
+		return "rolled back"
+	case RollbackFailed:
+		return "rollback failed"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Step is one action taken to apply a preset.
+type Step struct {
+	// Index is the step's position in the plan, from 0.
+	Index  int
+	Name   string
+	Status Status
+	// Err is why the step or its rollback failed.
+	Err error
+
+	run  func(ctx context.Context) error
+	undo func(ctx context.Context) error
+}
+
+// Applier applies presets to a server.
+type Applier struct {
+	client   *api.Client
+	samplers store.SamplerStore
+
+	// OnStep, if set, is called whenever a step changes status, from the
+	// goroutine running Apply.
+	OnStep func(Step)
+	// OnLoadProgress, if set, is called with the model's load progress.
+	OnLoadProgress func(api.LoadProgress)
+}
+
+// New returns an Applier for client. samplers resolves a preset's sampler
+// override to a local sampler profile; it may be nil to only use the server's
+// override presets.
+func New(client *api.Client, samplers store.SamplerStore) *Applier {
+	return &Applier{client: client, samplers: samplers}
+}
+
+// snapshot is the server state before applying, used to roll back.
+type snapshot struct {
+	model     *api.ModelCard
+	loras     []api.LoraCard
+	overrides *api.OverrideStatus
+}
+
+// Apply puts preset into effect. It returns the steps taken and, if one
+// failed, an error naming it along with any errors rolling back. Rollback
+// runs even if ctx is cancelled, so cancelling restores the previous state.
+func (a *Applier) Apply(ctx context.Context, preset store.Preset) ([]Step, error) {
+	if preset.ModelID == "" {
+		return nil, fmt.Errorf("preset %q has no model", preset.Name)
+	}
+	if errs := preset.ValidateLoadParams(); len(errs) > 0 {
+		return nil, fmt.Errorf("preset %q is invalid: %w", preset.Name, errs)
+	}
+
+	snap, err := a.snapshot(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("reading current server state: %w", err)
+	}
+
+	steps, err := a.plan(preset, snap)
+	if err != nil {
+		return nil, err
+	}
+	for i := range steps {
+		steps[i].Index = i
+		a.report(steps[i])
+	}
+
+	for i := range steps {
+		step := &steps[i]
+		if step.Status == Skipped {
+			continue
+		}
+
+		step.Status = Running
+		a.report(*step)
+		logging.Info(fmt.Sprintf("Applying preset %s: %s", preset.Name, step.Name))
+
+		if err := step.run(ctx); err != nil {
+			step.Status = Failed
+			step.Err = err
+			a.report(*step)
+			logging.Error(fmt.Sprintf("Applying preset %s: %s failed", preset.Name, step.Name), err)
+
+			stepErr := fmt.Errorf("%s: %w", step.Name, err)
+			if rollbackErr := a.rollback(context.WithoutCancel(ctx), steps[:i]); rollbackErr != nil {
+				return steps, errors.Join(stepErr, fmt.Errorf("rolling back: %w", rollbackErr))
+			}
+			return steps, stepErr
+		}
+
+		step.Status = Done
+		a.report(*step)
+	}
+
+	return steps, nil
+}
+
+func (a *Applier) report(step Step) {
+	if a.OnStep != nil {
+		a.OnStep(step)
+	}
+}
+
+// rollback undoes the completed steps in reverse order, carrying on past
+// failures so as much as possible is restored.
+func (a *Applier) rollback(ctx context.Context, done []Step) error {
+	var errs []error
+	for i := len(done) - 1; i >= 0; i-- {
+		step := &done[i]
+		if step.Status != Done {
+			continue
+		}
+		if step.undo == nil {
+			step.Status = RolledBack
+			a.report(*step)
+			continue
+		}
+
+		logging.Info(fmt.Sprintf("Rolling back: %s", step.Name))
+		if err := step.undo(ctx); err != nil {
+			step.Status = RollbackFailed
+			step.Err = err
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+			logging.Error(fmt.Sprintf("Rolling back %s failed", step.Name), err)
+		} else {
+			step.Status = RolledBack
+		}
+		a.report(*step)
+	}
+	return errors.Join(errs...)
+}
+
+func (a *Applier) snapshot(ctx context.Context) (*snapshot, error) {
+	snap := &snapshot{}
+
+	model, err := a.client.FetchCurrentModelCtx(ctx)
+	switch {
+	case err == nil:
+		snap.model = model
+	case api.IsNoModelLoaded(err):
+		// Nothing to restore
+	default:
+		return nil, err
+	}
+
+	if snap.model != nil {
+		if snap.loras, err = a.client.FetchLoadedLorasCtx(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	// Older servers can't report their overrides; carry on without being
+	// able to restore them
+	if snap.overrides, err = a.client.FetchOverrideStatusCtx(ctx); err != nil {
+		logging.Warn(fmt.Sprintf("Can't read sampler overrides, they won't be restored on failure: %v", err))
+	}
+
+	return snap, nil
+}
+
+func (a *Applier) plan(preset store.Preset, snap *snapshot) ([]Step, error) {
+	params, err := preset.LoadParams()
+	if err != nil {
+		return nil, err
+	}
+
+	var steps []Step
+	// The loaded model is only kept if it is running with the preset's
+	// parameters; otherwise it is reloaded with them
+	sameModel := snap.model != nil && snap.model.ID == preset.ModelID &&
+		sameLoadParams(snap.model.LoadParams(), params)
+
+	if snap.model != nil && !sameModel {
+		name := "Unload " + snap.model.ID
+		if snap.model.ID == preset.ModelID {
+			name += " to reload it with the preset's parameters"
+		}
+		steps = append(steps, Step{
+			Name: name,
+			run:  a.client.UnloadModelCtx,
+			undo: func(ctx context.Context) error {
+				if err := a.loadModel(ctx, snap.model.LoadParams()); err != nil {
+					return err
+				}
+				return a.loadLoras(ctx, snap.loras)
+			},
+		})
+	}
+
+	load := Step{
+		Name: "Load " + preset.ModelID,
+		run: func(ctx context.Context) error {
+			return a.loadModel(ctx, params)
+		},
+		undo: a.client.UnloadModelCtx,
+	}
+	if sameModel {
+		load.Name += " (already loaded with these parameters)"
+		load.Status = Skipped
+	}
+	steps = append(steps, load)
+
+	// Unloading and loading LoRAs are separate steps, so if loading the
+	// preset's fails the rollback puts the previous ones back
+	if sameModel && len(snap.loras) > 0 {
+		steps = append(steps, Step{
+			Name: "Unload LoRAs",
+			run:  a.client.UnloadLorasCtx,
+			undo: func(ctx context.Context) error {
+				return a.loadLoras(ctx, snap.loras)
+			},
+		})
+	}
+	if len(preset.Loras) > 0 {
+		names, _ := preset.LoraLists()
+		step := Step{
+			Name: fmt.Sprintf("Load LoRAs %v", names),
+			run: func(ctx context.Context) error {
+				return a.loadLoras(ctx, loraCards(preset.Loras))
+			},
+		}
+		// A newly loaded model is unloaded with its LoRAs on rollback, so
+		// only a model that stays loaded needs them unloaded
+		if sameModel {
+			step.undo = a.client.UnloadLorasCtx
+		}
+		steps = append(steps, step)
+	}
+
+	if preset.PromptTemplate != nil && *preset.PromptTemplate != "" {
+		template := *preset.PromptTemplate
+		steps = append(steps, Step{
+			Name: "Switch template to " + template,
+			run: func(ctx context.Context) error {
+				return a.client.LoadTemplateCtx(ctx, template)
+			},
+			undo: func(ctx context.Context) error {
+				// The model's own template comes back with it if it was
+				// reloaded; this restores it when the model stayed loaded
+				if !sameModel {
+					return nil
+				}
+				if snap.model.Parameters != nil && snap.model.Parameters.PromptTemplate != "" {
+					return a.client.LoadTemplateCtx(ctx, snap.model.Parameters.PromptTemplate)
+				}
+				return a.client.UnloadTemplateCtx(ctx)
+			},
+		})
+	}
+
+	if preset.SamplerOverride != "" {
+		steps = append(steps, Step{
+			Name: "Switch sampler override to " + preset.SamplerOverride,
+			run: func(ctx context.Context) error {
+				return a.switchOverride(ctx, preset.SamplerOverride)
+			},
+			undo: func(ctx context.Context) error {
+				return a.restoreOverrides(ctx, snap.overrides)
+			},
+		})
+	}
+
+	return steps, nil
+}
+
+// sameLoadParams reports whether a model loaded with loaded is running with
+// the parameters in wanted, the preset's load request. Only the parameters
+// the preset sets are compared, as the others are left to the server, except
+// that a draft model must be loaded exactly when the preset has one. A
+// parameter the server doesn't report can't be confirmed, so counts as
+// different. The prompt template is left out, as it is switched separately.
+func sameLoadParams(loaded, wanted map[string]interface{}) bool {
+	if _, ok := loaded["draft"]; ok {
+		if _, ok := wanted["draft"]; !ok {
+			return false
+		}
+	}
+	for key, value := range wanted {
+		if key == "prompt_template" {
+			continue
+		}
+		current, ok := loaded[key]
+		if !ok {
+			return false
+		}
+		if nested, ok := value.(map[string]interface{}); ok {
+			currentNested, ok := current.(map[string]interface{})
+			if !ok || !sameLoadParams(currentNested, nested) {
+				return false
+			}
+			continue
+		}
+		if !sameValue(current, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameValue compares two parameter values as they would be sent, so that
+// e.g. an int and a float of the same number are equal.
+func sameValue(a, b interface{}) bool {
+	normalise := func(v interface{}) interface{} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return v
+		}
+		var out interface{}
+		if err := json.Unmarshal(data, &out); err != nil {
+			return v
+		}
+		return out
+	}
+	return reflect.DeepEqual(normalise(a), normalise(b))
+}
+
+func (a *Applier) loadModel(ctx context.Context, params map[string]interface{}) error {
+	return a.client.LoadModelStream(ctx, params, func(progress api.LoadProgress) {
+		if a.OnLoadProgress != nil {
+			a.OnLoadProgress(progress)
+		}
+	})
+}
+
+func (a *Applier) loadLoras(ctx context.Context, loras []api.LoraCard) error {
+	if len(loras) == 0 {
+		return nil
+	}
+	names := make([]string, len(loras))
+	scalings := make([]float64, len(loras))
+	for i, lora := range loras {
+		names[i] = lora.ID
+		scalings[i] = lora.Scaling
+	}
+	return a.client.LoadLorasCtx(ctx, names, scalings)
+}
+
+// switchOverride applies the local sampler profile called name, or the
+// server's override preset of that name if there's no such profile.
+func (a *Applier) switchOverride(ctx context.Context, name string) error {
+	if a.samplers != nil {
+		profiles, err := a.samplers.SamplerProfiles()
+		if err != nil {
+			return fmt.Errorf("loading sampler profiles: %w", err)
+		}
+		for _, profile := range profiles {
+			if profile.Name == name {
+				return a.client.ApplyOverridesCtx(ctx, profile.OverrideParams())
+			}
+		}
+	}
+	return a.client.LoadOverrideCtx(ctx, name)
+}
+
+func (a *Applier) restoreOverrides(ctx context.Context, previous *api.OverrideStatus) error {
+	switch {
+	case previous == nil:
+		return fmt.Errorf("previous sampler overrides unknown, not restored")
+	case previous.Preset != "":
+		return a.client.LoadOverrideCtx(ctx, previous.Preset)
+	case len(previous.Overrides) > 0:
+		return a.client.ApplyOverridesCtx(ctx, previous.OverrideParams())
+	default:
+		return a.client.UnloadOverrideCtx(ctx)
+	}
+}
+
+func loraCards(loras []store.LoraSpec) []api.LoraCard {
+	cards := make([]api.LoraCard, len(loras))
+	for i, lora := range loras {
+		cards[i] = api.LoraCard{ID: lora.Name, Scaling: lora.Scaling}
+	}
+	return cards
+}
@@ -0,0 +1,271 @@
+package apply
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/store"
+)
+
+// stubState is what a stubServer has loaded.
+type stubState struct {
+	// model is the loaded model's ID, and maxSeqLen what it was loaded with.
+	model     string
+	maxSeqLen int
+	loras     []api.LoraCard
+	// failLoad names models and LoRAs that fail to load.
+	failLoad map[string]bool
+}
+
+// stubServer is a TabbyAPI that keeps the loaded model and LoRAs, and fails
+// to load the ones it's told to.
+type stubServer struct {
+	mu sync.Mutex
+	stubState
+}
+
+func (s *stubServer) client(t *testing.T) *api.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(server.Close)
+	return api.NewClient(server.URL, "")
+}
+
+func (s *stubServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reply := func(status int, body interface{}) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		_ = json.NewEncoder(w).Encode(body)
+	}
+	fail := func(detail string) {
+		reply(http.StatusInternalServerError, map[string]string{"detail": detail})
+	}
+
+	switch r.URL.Path {
+	case "/v1/model":
+		if s.model == "" {
+			reply(http.StatusBadRequest, map[string]string{"detail": "No models are currently loaded."})
+			return
+		}
+		reply(http.StatusOK, api.ModelCard{ID: s.model, Parameters: &api.ModelCardParameters{MaxSeqLen: s.maxSeqLen}})
+	case "/v1/lora":
+		reply(http.StatusOK, map[string]interface{}{"data": s.loras})
+	case "/v1/sampling/override/list":
+		reply(http.StatusOK, map[string]interface{}{"presets": []string{}, "selected_preset": nil, "overrides": map[string]interface{}{}})
+	case "/v1/model/load":
+		var params struct {
+			Name      string `json:"name"`
+			MaxSeqLen int    `json:"max_seq_len"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&params)
+		if s.failLoad[params.Name] {
+			fail("out of memory loading " + params.Name)
+			return
+		}
+		s.model, s.maxSeqLen, s.loras = params.Name, params.MaxSeqLen, nil
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"model_type": "model", "module": 1, "modules": 1, "status": "finished"}` + "\n\n"))
+	case "/v1/model/unload":
+		s.model, s.maxSeqLen, s.loras = "", 0, nil
+		reply(http.StatusOK, map[string]string{})
+	case "/v1/lora/load":
+		var request struct {
+			Loras []struct {
+				Name    string  `json:"name"`
+				Scaling float64 `json:"scaling"`
+			} `json:"loras"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		var loras []api.LoraCard
+		for _, lora := range request.Loras {
+			if s.failLoad[lora.Name] {
+				fail("can't load LoRA " + lora.Name)
+				return
+			}
+			loras = append(loras, api.LoraCard{ID: lora.Name, Scaling: lora.Scaling})
+		}
+		s.loras = loras
+		reply(http.StatusOK, map[string]string{})
+	case "/v1/lora/unload":
+		s.loras = nil
+		reply(http.StatusOK, map[string]string{})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func intPtr(v int) *int { return &v }
+
+// stepStatuses maps each step's name to its final status.
+func stepStatuses(steps []Step) map[string]Status {
+	statuses := make(map[string]Status, len(steps))
+	for _, step := range steps {
+		statuses[step.Name] = step.Status
+	}
+	return statuses
+}
+
+func TestApply(t *testing.T) {
+	style := []api.LoraCard{{ID: "style", Scaling: 0.5}}
+	tests := []struct {
+		name   string
+		server stubState
+		preset store.Preset
+
+		wantErr   bool
+		wantSteps map[string]Status
+		// What the server is left with
+		wantModel     string
+		wantMaxSeqLen int
+		wantLoras     []api.LoraCard
+	}{
+		{
+			name:      "nothing loaded",
+			preset:    store.Preset{Name: "Fast", ModelID: "llama-8b", MaxSeqLen: intPtr(8192), Loras: []store.LoraSpec{{Name: "chat", Scaling: 1}}},
+			wantSteps: map[string]Status{"Load llama-8b": Done, "Load LoRAs [chat]": Done},
+			wantModel: "llama-8b", wantMaxSeqLen: 8192, wantLoras: []api.LoraCard{{ID: "chat", Scaling: 1}},
+		},
+		{
+			name:      "nothing loaded, load fails",
+			server:    stubState{failLoad: map[string]bool{"llama-8b": true}},
+			preset:    store.Preset{Name: "Fast", ModelID: "llama-8b"},
+			wantErr:   true,
+			wantSteps: map[string]Status{"Load llama-8b": Failed},
+		},
+		{
+			name:      "failed model load restores the previous model",
+			server:    stubState{model: "mistral-7b", maxSeqLen: 4096, loras: style, failLoad: map[string]bool{"llama-70b": true}},
+			preset:    store.Preset{Name: "Big", ModelID: "llama-70b"},
+			wantErr:   true,
+			wantSteps: map[string]Status{"Unload mistral-7b": RolledBack, "Load llama-70b": Failed},
+			wantModel: "mistral-7b", wantMaxSeqLen: 4096, wantLoras: style,
+		},
+		{
+			name:      "failed LoRA load on a new model restores the previous model",
+			server:    stubState{model: "mistral-7b", maxSeqLen: 4096, loras: style, failLoad: map[string]bool{"broken": true}},
+			preset:    store.Preset{Name: "Big", ModelID: "llama-70b", Loras: []store.LoraSpec{{Name: "broken", Scaling: 1}}},
+			wantErr:   true,
+			wantSteps: map[string]Status{"Unload mistral-7b": RolledBack, "Load llama-70b": RolledBack, "Load LoRAs [broken]": Failed},
+			wantModel: "mistral-7b", wantMaxSeqLen: 4096, wantLoras: style,
+		},
+		{
+			name:      "failed LoRA load reloads the previous stack",
+			server:    stubState{model: "llama-8b", maxSeqLen: 8192, loras: style, failLoad: map[string]bool{"broken": true}},
+			preset:    store.Preset{Name: "Fast", ModelID: "llama-8b", MaxSeqLen: intPtr(8192), Loras: []store.LoraSpec{{Name: "broken", Scaling: 1}}},
+			wantErr:   true,
+			wantSteps: map[string]Status{"Load llama-8b (already loaded with these parameters)": Skipped, "Unload LoRAs": RolledBack, "Load LoRAs [broken]": Failed},
+			wantModel: "llama-8b", wantMaxSeqLen: 8192, wantLoras: style,
+		},
+		{
+			name:      "same model, new LoRAs",
+			server:    stubState{model: "llama-8b", maxSeqLen: 8192, loras: style},
+			preset:    store.Preset{Name: "Fast", ModelID: "llama-8b", MaxSeqLen: intPtr(8192), Loras: []store.LoraSpec{{Name: "chat", Scaling: 1}}},
+			wantSteps: map[string]Status{"Load llama-8b (already loaded with these parameters)": Skipped, "Unload LoRAs": Done, "Load LoRAs [chat]": Done},
+			wantModel: "llama-8b", wantMaxSeqLen: 8192, wantLoras: []api.LoraCard{{ID: "chat", Scaling: 1}},
+		},
+		{
+			name:      "same model, other parameters",
+			server:    stubState{model: "llama-8b", maxSeqLen: 4096},
+			preset:    store.Preset{Name: "Long", ModelID: "llama-8b", MaxSeqLen: intPtr(32768)},
+			wantSteps: map[string]Status{"Unload llama-8b to reload it with the preset's parameters": Done, "Load llama-8b": Done},
+			wantModel: "llama-8b", wantMaxSeqLen: 32768,
+		},
+		{
+			name:      "parameter the server doesn't report",
+			server:    stubState{model: "llama-8b", maxSeqLen: 8192},
+			preset:    store.Preset{Name: "Fast", ModelID: "llama-8b", MaxSeqLen: intPtr(8192), OverrideBaseSeqLen: intPtr(4096)},
+			wantSteps: map[string]Status{"Unload llama-8b to reload it with the preset's parameters": Done, "Load llama-8b": Done},
+			wantModel: "llama-8b", wantMaxSeqLen: 8192,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := &stubServer{stubState: tt.server}
+			steps, err := New(server.client(t), nil).Apply(context.Background(), tt.preset)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error: %v", err, tt.wantErr)
+			}
+			if got := stepStatuses(steps); !reflect.DeepEqual(got, tt.wantSteps) {
+				t.Errorf("steps = %v, want %v", got, tt.wantSteps)
+			}
+
+			server.mu.Lock()
+			defer server.mu.Unlock()
+			if server.model != tt.wantModel || server.maxSeqLen != tt.wantMaxSeqLen {
+				t.Errorf("server has %q with max_seq_len %d, want %q with %d", server.model, server.maxSeqLen, tt.wantModel, tt.wantMaxSeqLen)
+			}
+			if !reflect.DeepEqual(server.loras, tt.wantLoras) {
+				t.Errorf("server has LoRAs %v, want %v", server.loras, tt.wantLoras)
+			}
+		})
+	}
+}
+
+func TestSameLoadParams(t *testing.T) {
+	tests := []struct {
+		name           string
+		loaded, wanted map[string]interface{}
+		want           bool
+	}{
+		{
+			name:   "same",
+			loaded: map[string]interface{}{"name": "a", "max_seq_len": 8192, "cache_mode": "Q4"},
+			wanted: map[string]interface{}{"name": "a", "max_seq_len": 8192},
+			want:   true,
+		},
+		{
+			name:   "int and float of the same number",
+			loaded: map[string]interface{}{"rope_alpha": 2},
+			wanted: map[string]interface{}{"rope_alpha": 2.0},
+			want:   true,
+		},
+		{
+			name:   "prompt template is ignored",
+			loaded: map[string]interface{}{"prompt_template": "chatml"},
+			wanted: map[string]interface{}{"prompt_template": "llama3"},
+			want:   true,
+		},
+		{
+			name:   "different value",
+			loaded: map[string]interface{}{"max_seq_len": 4096},
+			wanted: map[string]interface{}{"max_seq_len": 8192},
+		},
+		{
+			name:   "not reported",
+			loaded: map[string]interface{}{},
+			wanted: map[string]interface{}{"fasttensors": true},
+		},
+		{
+			name:   "draft loaded but not wanted",
+			loaded: map[string]interface{}{"draft": map[string]interface{}{"draft_model_name": "tiny"}},
+			wanted: map[string]interface{}{},
+		},
+		{
+			name:   "same draft",
+			loaded: map[string]interface{}{"draft": map[string]interface{}{"draft_model_name": "tiny", "draft_rope_alpha": 1.0}},
+			wanted: map[string]interface{}{"draft": map[string]interface{}{"draft_model_name": "tiny"}},
+			want:   true,
+		},
+		{
+			name:   "other draft",
+			loaded: map[string]interface{}{"draft": map[string]interface{}{"draft_model_name": "tiny"}},
+			wanted: map[string]interface{}{"draft": map[string]interface{}{"draft_model_name": "small"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sameLoadParams(tt.loaded, tt.wanted); got != tt.want {
+				t.Errorf("sameLoadParams() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
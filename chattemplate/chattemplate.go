@@ -0,0 +1,72 @@
+// Package chattemplate renders TabbyAPI's Jinja2 prompt templates locally, so
+// TabLoad can show the exact prompt a model will see without asking the
+// server.
+package chattemplate
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nikolalohinski/gonja/v2"
+	"github.com/nikolalohinski/gonja/v2/exec"
+	"github.com/sammcj/tabload/api"
+)
+
+// Vars are the variables TabbyAPI passes to a template besides the messages.
+type Vars struct {
+	BOSToken string
+	EOSToken string
+	// AddGenerationPrompt asks the template to end with the start of an
+	// assistant turn.
+	AddGenerationPrompt bool
+}
+
+// DefaultVars are the variables used when the model's special tokens aren't
+// known.
+var DefaultVars = Vars{
+	BOSToken:            "<s>",
+	EOSToken:            "</s>",
+	AddGenerationPrompt: true,
+}
+
+// Render renders a template's source with messages.
+func Render(source string, messages []api.ChatMessage, vars Vars) (string, error) {
+	template, err := gonja.FromString(source)
+	if err != nil {
+		return "", fmt.Errorf("parsing template: %w", err)
+	}
+
+	output, err := template.ExecuteToString(exec.NewContext(templateContext(messages, vars)))
+	if err != nil {
+		return "", fmt.Errorf("rendering template: %w", err)
+	}
+	return output, nil
+}
+
+func templateContext(messages []api.ChatMessage, vars Vars) map[string]interface{} {
+	messageMaps := make([]map[string]interface{}, len(messages))
+	for i, message := range messages {
+		messageMaps[i] = map[string]interface{}{
+			"role":    message.Role,
+			"content": message.Content,
+		}
+	}
+
+	return map[string]interface{}{
+		"messages":              messageMaps,
+		"bos_token":             vars.BOSToken,
+		"eos_token":             vars.EOSToken,
+		"add_generation_prompt": vars.AddGenerationPrompt,
+		"raise_exception":       raiseException,
+	}
+}
+
+// raiseException implements the raise_exception function Hugging Face
+// templates use to reject unsupported conversations.
+func raiseException(_ *exec.Evaluator, params *exec.VarArgs) (interface{}, error) {
+	message := "template raised an exception"
+	if len(params.Args) > 0 {
+		message = params.Args[0].String()
+	}
+	return nil, errors.New(message)
+}
@@ -0,0 +1,405 @@
+package chattemplate
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/nikolalohinski/gonja/v2"
+	"github.com/nikolalohinski/gonja/v2/builtins"
+	"github.com/nikolalohinski/gonja/v2/parser"
+)
+
+// Problem is a syntax error in a template. Line and Column are 1-based, with
+// columns counted in characters.
+type Problem struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("line %d, column %d: %s", p.Line, p.Column, p.Message)
+}
+
+// blockTags are the statements that need a matching end tag.
+var blockTags = map[string]bool{
+	"if": true, "for": true, "macro": true, "call": true, "filter": true,
+	"block": true, "with": true, "autoescape": true, "trans": true,
+}
+
+// middleTags are the statements that may only appear inside a block, mapped
+// to the blocks that allow them.
+var middleTags = map[string][]string{
+	"elif":      {"if"},
+	"else":      {"if", "for"},
+	"pluralize": {"trans"},
+}
+
+// otherTags are statements that stand alone.
+var otherTags = map[string]bool{
+	"set": true, "include": true, "import": true, "from": true, "extends": true,
+	"do": true, "break": true, "continue": true, "raw": true,
+}
+
+// Check looks for syntax errors in a template: unterminated tags, block tags
+// that are unbalanced or wrongly nested, unknown tags and filters, and then
+// anything else the parser rejects. It returns nil for a valid template.
+func Check(source string) []Problem {
+	scan := scanTags(source)
+	problems := scan.problems
+	problems = append(problems, checkFilters(scan)...)
+	if len(problems) > 0 {
+		sortProblems(problems)
+		return problems
+	}
+
+	// The scan has found nothing, so leave the rest to the parser
+	if _, err := gonja.FromString(source); err != nil {
+		return []Problem{parseProblem(err)}
+	}
+	return nil
+}
+
+// parseProblem turns a gonja parse error into a Problem, without the whole
+// template source gonja includes in its message.
+func parseProblem(err error) Problem {
+	var syntaxErr *parser.SyntaxError
+	if errors.As(err, &syntaxErr) && syntaxErr.Line > 0 {
+		return Problem{Line: syntaxErr.Line, Column: syntaxErr.Column, Message: syntaxErr.Message}
+	}
+	if inner := errors.Unwrap(err); inner != nil {
+		err = inner
+	}
+	return Problem{Line: 1, Column: 1, Message: err.Error()}
+}
+
+func sortProblems(problems []Problem) {
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Column < problems[j].Column
+	})
+}
+
+// TokenKind is the kind of a piece of template source.
+type TokenKind int
+
+const (
+	// Text is literal output.
+	Text TokenKind = iota
+	// Statement is a {% %} tag.
+	Statement
+	// Expression is a {{ }} tag.
+	Expression
+	// Comment is a {# #} tag.
+	Comment
+)
+
+// Token is a piece of template source, from byte offset Start to End.
+type Token struct {
+	Kind       TokenKind
+	Start, End int
+}
+
+// tag is a {% %}, {{ }} or {# #} tag found in a template.
+type tag struct {
+	kind TokenKind
+	// start and end are the offsets of the whole tag, delimiters included;
+	// contentStart and contentEnd those of what's between the delimiters and
+	// any whitespace control markers.
+	start, end               int
+	contentStart, contentEnd int
+	// depth is how many blocks the tag is nested in.
+	depth int
+}
+
+func (t tag) content(source string) string {
+	return source[t.contentStart:t.contentEnd]
+}
+
+// name returns a statement's keyword, e.g. "if" or "endfor".
+func (t tag) name(source string) string {
+	fields := strings.Fields(t.content(source))
+	if len(fields) == 0 {
+		return ""
+	}
+	name := fields[0]
+	if i := strings.IndexFunc(name, func(r rune) bool { return !isIdentRune(r) }); i > 0 {
+		name = name[:i]
+	}
+	return name
+}
+
+type scanResult struct {
+	source   string
+	tags     []tag
+	problems []Problem
+	// lineStarts are the offsets each line starts at.
+	lineStarts []int
+}
+
+// position returns the 1-based line and column of a byte offset.
+func (s *scanResult) position(offset int) (int, int) {
+	line := 0
+	for line+1 < len(s.lineStarts) && s.lineStarts[line+1] <= offset {
+		line++
+	}
+	return line + 1, utf8.RuneCountInString(s.source[s.lineStarts[line]:offset]) + 1
+}
+
+func (s *scanResult) problem(offset int, format string, args ...interface{}) {
+	line, column := s.position(offset)
+	s.problems = append(s.problems, Problem{Line: line, Column: column, Message: fmt.Sprintf(format, args...)})
+}
+
+var endRawPattern = regexp.MustCompile(`\{%[-+]?\s*endraw\s*[-+]?%\}`)
+
+// opener is a block tag waiting for its end tag.
+type opener struct {
+	name   string
+	offset int
+}
+
+// scanTags finds the tags in a template and checks its blocks are balanced.
+func scanTags(source string) *scanResult {
+	s := &scanResult{source: source, lineStarts: []int{0}}
+	for i, c := range source {
+		if c == '\n' {
+			s.lineStarts = append(s.lineStarts, i+1)
+		}
+	}
+
+	var stack []opener
+	describe := func(o opener) string {
+		line, column := s.position(o.offset)
+		return fmt.Sprintf("{%% %s %%} opened at line %d, column %d", o.name, line, column)
+	}
+
+	for i := 0; i < len(source); {
+		open := strings.IndexByte(source[i:], '{')
+		if open < 0 || i+open+1 >= len(source) {
+			break
+		}
+		open += i
+
+		var kind TokenKind
+		var closing string
+		switch source[open+1] {
+		case '%':
+			kind, closing = Statement, "%}"
+		case '{':
+			kind, closing = Expression, "}}"
+		case '#':
+			kind, closing = Comment, "#}"
+		default:
+			i = open + 1
+			continue
+		}
+
+		contentStart := open + 2
+		if contentStart < len(source) && (source[contentStart] == '-' || source[contentStart] == '+') {
+			contentStart++
+		}
+		close := findClosing(source, contentStart, closing, kind != Comment)
+		if close < 0 {
+			s.problem(open, "%s is never closed with %s", source[open:open+2], closing)
+			break
+		}
+		contentEnd := close
+		if contentEnd > contentStart && (source[contentEnd-1] == '-' || source[contentEnd-1] == '+') {
+			contentEnd--
+		}
+
+		t := tag{kind: kind, start: open, end: close + 2, contentStart: contentStart, contentEnd: contentEnd, depth: len(stack)}
+		s.tags = append(s.tags, t)
+		i = t.end
+		if kind != Statement {
+			continue
+		}
+
+		name := t.name(source)
+		switch {
+		case name == "":
+			s.problem(open, "empty statement")
+		case name == "raw":
+			// Nothing inside a raw block is a tag
+			loc := endRawPattern.FindStringIndex(source[i:])
+			if loc == nil {
+				s.problem(open, "{%% raw %%} is never closed with {%% endraw %%}")
+				i = len(source)
+			} else {
+				i += loc[1]
+			}
+		case blockTags[name] || (name == "set" && isBlockSet(t.content(source))):
+			stack = append(stack, opener{name: name, offset: open})
+		case middleTags[name] != nil:
+			parents := middleTags[name]
+			if len(stack) == 0 || !containsString(parents, stack[len(stack)-1].name) {
+				s.problem(open, "{%% %s %%} is only allowed inside {%% %s %%}", name, strings.Join(parents, " %} or {% "))
+			}
+		case strings.HasPrefix(name, "end"):
+			block := strings.TrimPrefix(name, "end")
+			match := -1
+			for j := len(stack) - 1; j >= 0; j-- {
+				if stack[j].name == block {
+					match = j
+					break
+				}
+			}
+			switch {
+			case match < 0 && len(stack) == 0:
+				s.problem(open, "{%% %s %%} has no {%% %s %%} to close", name, block)
+			case match < 0:
+				s.problem(open, "{%% %s %%} found while %s is still open", name, describe(stack[len(stack)-1]))
+			default:
+				for _, unclosed := range stack[match+1:] {
+					s.problem(unclosed.offset, "{%% %s %%} is never closed, found {%% %s %%} first", unclosed.name, name)
+				}
+				stack = stack[:match]
+			}
+		case !otherTags[name]:
+			s.problem(open+2, "unknown tag %q", name)
+		}
+	}
+
+	for _, unclosed := range stack {
+		s.problem(unclosed.offset, "{%% %s %%} is never closed with {%% end%s %%}", unclosed.name, unclosed.name)
+	}
+	return s
+}
+
+// findClosing returns the offset of closing in source from start, skipping
+// over string literals if quoted is set, or -1 if there is none.
+func findClosing(source string, start int, closing string, quoted bool) int {
+	if !quoted {
+		if i := strings.Index(source[start:], closing); i >= 0 {
+			return start + i
+		}
+		return -1
+	}
+	for i := start; i < len(source); i++ {
+		switch c := source[i]; {
+		case c == '\'' || c == '"':
+			end := skipString(source, i)
+			if end < 0 {
+				return -1
+			}
+			i = end - 1
+		case strings.HasPrefix(source[i:], closing):
+			return i
+		}
+	}
+	return -1
+}
+
+// skipString returns the offset after the string literal starting at start,
+// or -1 if it isn't terminated.
+func skipString(source string, start int) int {
+	quote := source[start]
+	for i := start + 1; i < len(source); i++ {
+		switch source[i] {
+		case '\\':
+			i++
+		case quote:
+			return i + 1
+		}
+	}
+	return -1
+}
+
+// isBlockSet reports whether a set statement's content is the block form,
+// {% set name %}...{% endset %}, rather than an assignment.
+func isBlockSet(content string) bool {
+	for i := 0; i < len(content); i++ {
+		switch c := content[i]; c {
+		case '\'', '"':
+			end := skipString(content, i)
+			if end < 0 {
+				return false
+			}
+			i = end - 1
+		case '=':
+			return false
+		}
+	}
+	return true
+}
+
+var filterNamePattern = regexp.MustCompile(`^\s*([A-Za-z_][A-Za-z0-9_]*)`)
+
+// checkFilters reports filters gonja, like Jinja, doesn't provide.
+func checkFilters(s *scanResult) []Problem {
+	var problems []Problem
+	check := func(offset int, name string) {
+		if !builtins.Filters.Exists(name) {
+			line, column := s.position(offset)
+			problems = append(problems, Problem{Line: line, Column: column, Message: fmt.Sprintf("unknown filter %q", name)})
+		}
+	}
+
+	for _, t := range s.tags {
+		if t.kind == Comment {
+			continue
+		}
+		content := t.content(s.source)
+		if t.kind == Statement && t.name(s.source) == "filter" {
+			rest := strings.TrimLeft(content, " \t\r\n")[len("filter"):]
+			if match := filterNamePattern.FindStringSubmatchIndex(rest); match != nil {
+				offset := t.contentEnd - len(rest) + match[2]
+				check(offset, rest[match[2]:match[3]])
+			}
+		}
+		for i := 0; i < len(content); i++ {
+			switch content[i] {
+			case '\'', '"':
+				end := skipString(content, i)
+				if end < 0 {
+					break
+				}
+				i = end - 1
+			case '|':
+				match := filterNamePattern.FindStringSubmatchIndex(content[i+1:])
+				if match == nil {
+					continue
+				}
+				check(t.contentStart+i+1+match[2], content[i+1+match[2]:i+1+match[3]])
+			}
+		}
+	}
+	return problems
+}
+
+// Tokens splits a template into text and tags, for highlighting.
+func Tokens(source string) []Token {
+	var tokens []Token
+	offset := 0
+	for _, t := range scanTags(source).tags {
+		if t.start > offset {
+			tokens = append(tokens, Token{Kind: Text, Start: offset, End: t.start})
+		}
+		tokens = append(tokens, Token{Kind: t.kind, Start: t.start, End: t.end})
+		offset = t.end
+	}
+	if offset < len(source) {
+		tokens = append(tokens, Token{Kind: Text, Start: offset, End: len(source)})
+	}
+	return tokens
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9'
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,131 @@
+package chattemplate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		name   string
+		source string
+		want   []Problem
+	}{
+		{
+			name:   "valid",
+			source: "{% for m in messages %}{{ m.content | trim }}{% endfor %}",
+		},
+		{
+			name:   "delimiters inside strings",
+			source: "{{ '%}' }}{% if x == '}}' %}ok{% endif %}",
+		},
+		{
+			name:   "raw blocks aren't checked",
+			source: "{% raw %}{% if %}{{ x | nope }}{% endraw %}",
+		},
+		{
+			name:   "block set",
+			source: "{% set greeting %}hi{% endset %}{{ greeting }}",
+		},
+		{
+			name:   "whitespace control",
+			source: "{%- if x -%}a{%- else -%}b{%- endif -%}",
+		},
+		{
+			name:   "unclosed block",
+			source: "{% for m in messages %}\n  {% if m.role %}{{ m.content }}\n{% endfor %}",
+			want:   []Problem{{Line: 2, Column: 3, Message: "{% if %} is never closed, found {% endfor %} first"}},
+		},
+		{
+			name:   "block never closed",
+			source: "a\n{% if x %}b",
+			want:   []Problem{{Line: 2, Column: 1, Message: "{% if %} is never closed with {% endif %}"}},
+		},
+		{
+			name:   "end with nothing open",
+			source: "a {% endif %}",
+			want:   []Problem{{Line: 1, Column: 3, Message: "{% endif %} has no {% if %} to close"}},
+		},
+		{
+			name:   "mismatched end",
+			source: "{% if x %}\n{% endfor %}{% endif %}",
+			want:   []Problem{{Line: 2, Column: 1, Message: "{% endfor %} found while {% if %} opened at line 1, column 1 is still open"}},
+		},
+		{
+			name:   "misplaced else",
+			source: "{% else %}",
+			want:   []Problem{{Line: 1, Column: 1, Message: "{% else %} is only allowed inside {% if %} or {% for %}"}},
+		},
+		{
+			name:   "misplaced elif",
+			source: "{% for x in y %}{% elif z %}{% endfor %}",
+			want:   []Problem{{Line: 1, Column: 17, Message: "{% elif %} is only allowed inside {% if %}"}},
+		},
+		{
+			name:   "unterminated tag",
+			source: "ok\nhello {{ name",
+			want:   []Problem{{Line: 2, Column: 7, Message: "{{ is never closed with }}"}},
+		},
+		{
+			name:   "unknown tag",
+			source: "{% frobnicate %}",
+			want:   []Problem{{Line: 1, Column: 3, Message: `unknown tag "frobnicate"`}},
+		},
+		{
+			name:   "unknown filters",
+			source: "{{ x | upper | bogus }}\n{% filter nope %}a{% endfilter %}",
+			want: []Problem{
+				{Line: 1, Column: 16, Message: `unknown filter "bogus"`},
+				{Line: 2, Column: 11, Message: `unknown filter "nope"`},
+			},
+		},
+		{
+			name:   "columns count characters",
+			source: "héllo {{ x | nope }}",
+			want:   []Problem{{Line: 1, Column: 14, Message: `unknown filter "nope"`}},
+		},
+		{
+			name:   "problems are sorted",
+			source: "{% if x %}\n{{ y | nope }}",
+			want: []Problem{
+				{Line: 1, Column: 1, Message: "{% if %} is never closed with {% endif %}"},
+				{Line: 2, Column: 8, Message: `unknown filter "nope"`},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Check(tt.source); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCheckParseError(t *testing.T) {
+	problems := Check("{{ x }}\n{{ x + }}")
+	if len(problems) != 1 {
+		t.Fatalf("got %v, want one problem", problems)
+	}
+	if problems[0].Line != 2 {
+		t.Errorf("problem on line %d, want 2", problems[0].Line)
+	}
+	if strings.Contains(problems[0].Message, "{{ x }}") {
+		t.Errorf("message includes the template source: %s", problems[0].Message)
+	}
+}
+
+func TestTokens(t *testing.T) {
+	source := "a{{ b }}c{% d %}{# e #}"
+	want := []Token{
+		{Kind: Text, Start: 0, End: 1},
+		{Kind: Expression, Start: 1, End: 8},
+		{Kind: Text, Start: 8, End: 9},
+		{Kind: Statement, Start: 9, End: 16},
+		{Kind: Comment, Start: 16, End: 23},
+	}
+	if got := Tokens(source); !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokens() = %v, want %v", got, want)
+	}
+}
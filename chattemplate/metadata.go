@@ -0,0 +1,151 @@
+package chattemplate
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/nikolalohinski/gonja/v2"
+	"github.com/nikolalohinski/gonja/v2/exec"
+	"github.com/sammcj/tabload/api"
+)
+
+// Metadata is what TabbyAPI reads from a template besides rendering it: the
+// variables its top-level {% set %} statements define.
+type Metadata struct {
+	// StopStrings are added to the stop strings of every generation.
+	StopStrings []string `json:"stop_strings"`
+	// ToolStart is the text the model emits to start a tool call.
+	ToolStart string `json:"tool_start"`
+	// ToolStartToken is the token ID of ToolStart, for older TabbyAPI
+	// versions that match on it.
+	ToolStartToken *int `json:"tool_start_token"`
+}
+
+// metadataVars are the variables Metadata is read from, in the order
+// SetMetadata writes them.
+var metadataVars = []string{"stop_strings", "tool_start", "tool_start_token"}
+
+// IsZero reports whether no metadata is set.
+func (m Metadata) IsZero() bool {
+	return len(m.StopStrings) == 0 && m.ToolStart == "" && m.ToolStartToken == nil
+}
+
+// ExtractMetadata reads a template's metadata. Like TabbyAPI, it only sees
+// variables set outside any block, and runs those statements alone, without
+// the conversation.
+func ExtractMetadata(source string) (Metadata, error) {
+	var sb strings.Builder
+	for _, t := range topLevelSets(scanTags(source), source) {
+		sb.WriteString(source[t.start:t.end])
+	}
+	vars := make([]string, len(metadataVars))
+	for i, name := range metadataVars {
+		vars[i] = fmt.Sprintf("%q: %s", name, name)
+	}
+	sb.WriteString("{{ {" + strings.Join(vars, ", ") + "} | tojson }}")
+
+	template, err := gonja.FromString(sb.String())
+	if err != nil {
+		return Metadata{}, fmt.Errorf("parsing template metadata: %w", err)
+	}
+	output, err := template.ExecuteToString(exec.NewContext(templateContext(nil, DefaultVars)))
+	if err != nil {
+		return Metadata{}, fmt.Errorf("reading template metadata: %w", err)
+	}
+
+	var md Metadata
+	if err := json.Unmarshal([]byte(output), &md); err != nil {
+		return Metadata{}, fmt.Errorf("decoding template metadata: %w", err)
+	}
+	return md, nil
+}
+
+// SetMetadata returns source with its metadata statements replaced by md's
+// values. Existing statements are rewritten where they are, new ones are
+// added at the top, and those for empty values are removed.
+func SetMetadata(source string, md Metadata) string {
+	values := map[string]string{}
+	if len(md.StopStrings) > 0 {
+		values["stop_strings"] = literal(md.StopStrings)
+	}
+	if md.ToolStart != "" {
+		values["tool_start"] = literal(md.ToolStart)
+	}
+	if md.ToolStartToken != nil {
+		values["tool_start_token"] = fmt.Sprint(*md.ToolStartToken)
+	}
+
+	// Work from the end, so earlier offsets stay valid
+	sets := topLevelSets(scanTags(source), source)
+	found := map[string]bool{}
+	for i := len(sets) - 1; i >= 0; i-- {
+		t := sets[i]
+		name := setName(t.content(source))
+		if !containsString(metadataVars, name) {
+			continue
+		}
+		value, ok := values[name]
+		switch {
+		case ok && !found[name]:
+			source = source[:t.contentStart] + fmt.Sprintf(" set %s = %s ", name, value) + source[t.contentEnd:]
+		default:
+			// Cleared, or set again later, which is the value that counts
+			end := t.end
+			if strings.HasPrefix(source[end:], "\n") {
+				end++
+			}
+			source = source[:t.start] + source[end:]
+		}
+		found[name] = true
+	}
+
+	var header strings.Builder
+	for _, name := range metadataVars {
+		if value, ok := values[name]; ok && !found[name] {
+			fmt.Fprintf(&header, "{%%- set %s = %s -%%}\n", name, value)
+		}
+	}
+	return header.String() + source
+}
+
+// topLevelSets returns the assignments outside any block.
+func topLevelSets(s *scanResult, source string) []tag {
+	var sets []tag
+	for _, t := range s.tags {
+		if t.kind == Statement && t.depth == 0 && t.name(source) == "set" && !isBlockSet(t.content(source)) {
+			sets = append(sets, t)
+		}
+	}
+	return sets
+}
+
+// setName returns the variable a set statement assigns.
+func setName(content string) string {
+	fields := strings.Fields(strings.SplitN(content, "=", 2)[0])
+	if len(fields) != 2 {
+		return ""
+	}
+	return fields[1]
+}
+
+// literal writes a string or list of strings as a template literal. JSON's
+// are valid, as long as <, > and & aren't escaped.
+func literal(v interface{}) string {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return `""`
+	}
+	return strings.TrimSpace(buf.String())
+}
+
+// SampleMessages is a short conversation to preview templates with.
+var SampleMessages = []api.ChatMessage{
+	{Role: "system", Content: "You are a helpful assistant."},
+	{Role: "user", Content: "What is the capital of France?"},
+	{Role: "assistant", Content: "The capital of France is Paris."},
+	{Role: "user", Content: "And of Italy?"},
+}
@@ -0,0 +1,111 @@
+package chattemplate
+
+import (
+	"reflect"
+	"testing"
+)
+
+const chatML = "{% for m in messages %}<|im_start|>{{ m.role }}\n{{ m.content }}<|im_end|>\n{% endfor %}"
+
+func TestExtractMetadata(t *testing.T) {
+	token := 7
+	tests := []struct {
+		name   string
+		source string
+		want   Metadata
+	}{
+		{
+			name:   "none",
+			source: chatML,
+		},
+		{
+			name:   "top level sets",
+			source: "{%- set stop_strings = [\"<|im_end|>\", \"\\n\\nUser:\"] -%}\n{% set tool_start = '<tool_call>' %}{% set tool_start_token = 7 %}" + chatML,
+			want:   Metadata{StopStrings: []string{"<|im_end|>", "\n\nUser:"}, ToolStart: "<tool_call>", ToolStartToken: &token},
+		},
+		{
+			name:   "sets inside blocks are ignored",
+			source: "{% if true %}{% set tool_start = '<x>' %}{% endif %}" + chatML,
+		},
+		{
+			name:   "the last set wins",
+			source: "{% set tool_start = 'a' %}{% set tool_start = 'b' %}",
+			want:   Metadata{ToolStart: "b"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ExtractMetadata(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetMetadata(t *testing.T) {
+	token := 12
+	tests := []struct {
+		name   string
+		source string
+		md     Metadata
+		want   string
+	}{
+		{
+			name:   "added at the top",
+			source: chatML,
+			md:     Metadata{StopStrings: []string{"<|im_end|>"}, ToolStart: "<tool_call>"},
+			want:   "{%- set stop_strings = [\"<|im_end|>\"] -%}\n{%- set tool_start = \"<tool_call>\" -%}\n" + chatML,
+		},
+		{
+			name:   "replaced in place, keeping whitespace control",
+			source: "{# ChatML #}\n{%- set stop_strings = ['x'] %}\n" + chatML,
+			md:     Metadata{StopStrings: []string{"a\"b", "\n"}},
+			want:   "{# ChatML #}\n{%- set stop_strings = [\"a\\\"b\",\"\\n\"] %}\n" + chatML,
+		},
+		{
+			name:   "cleared values are removed",
+			source: "{% set tool_start = 'x' %}\n{% set stop_strings = ['y'] %}\n" + chatML,
+			md:     Metadata{StopStrings: []string{"y"}},
+			want:   "{% set stop_strings = [\"y\"] %}\n" + chatML,
+		},
+		{
+			name:   "earlier duplicates are removed",
+			source: "{% set tool_start = 'a' %}\n{% set tool_start = 'b' %}",
+			md:     Metadata{ToolStart: "c", ToolStartToken: &token},
+			want:   "{%- set tool_start_token = 12 -%}\n{% set tool_start = \"c\" %}",
+		},
+		{
+			name:   "sets inside blocks are left alone",
+			source: "{% if x %}{% set tool_start = 'a' %}{% endif %}",
+			md:     Metadata{},
+			want:   "{% if x %}{% set tool_start = 'a' %}{% endif %}",
+		},
+		{
+			name:   "other variables are left alone",
+			source: "{% set system = 'hi' %}",
+			md:     Metadata{},
+			want:   "{% set system = 'hi' %}",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SetMetadata(tt.source, tt.md)
+			if got != tt.want {
+				t.Errorf("got\n%s\nwant\n%s", got, tt.want)
+			}
+
+			// What was written reads back the same
+			read, err := ExtractMetadata(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.md.IsZero() && !reflect.DeepEqual(read, tt.md) {
+				t.Errorf("read back %+v, want %+v", read, tt.md)
+			}
+		})
+	}
+}
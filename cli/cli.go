@@ -0,0 +1,297 @@
+// Package cli implements TabLoad's headless command line interface, for use
+// from SSH sessions, cron jobs and scripts. It shares the API client, config
+// and presets with the GUI but never initialises a window.
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
+)
+
+// Exit codes returned by Run.
+const (
+	ExitOK      = 0
+	ExitFailure = 1
+	ExitUsage   = 2
+)
+
+// errUsage marks errors caused by bad command line usage.
+var errUsage = errors.New("usage error")
+
+const usage = `Usage: tabload [global flags] <command> [flags]
+
+Commands:
+  status [--json]                      Show the loaded model and LoRAs
+  models list [--draft] [--json]       List models (or draft models) on the server
+  load --preset NAME | --model NAME    Load a model, or apply a saved preset (model,
+                                       LoRAs, template and sampler override)
+  unload                               Unload the current model
+  loras list [--json]                  List LoRAs on the server
+  loras load NAME[:SCALING],...        Load LoRAs, e.g. a:0.8,b:1.0
+  loras unload                         Unload all LoRAs
+  presets list [--json]                List saved presets
+  presets export [--format yaml] [--output FILE] [NAME...]
+                                       Export presets (all by default) as JSON or YAML
+  presets import [--on-conflict skip|overwrite|rename] FILE
+                                       Import presets from a JSON or YAML file
+  presets from-config [--name NAME] [--overwrite] CONFIG_YML
+                                       Save a TabbyAPI config.yml's model sections as a preset
+  presets to-config NAME               Print a preset as TabbyAPI config.yml sections
+
+Global flags:
+`
+
+// env holds what every command needs: the API client, storage and where to
+// write.
+type env struct {
+	client *api.Client
+	store  store.Store
+	stdout io.Writer
+	stderr io.Writer
+}
+
+// Run executes the command in args (without the program name) and returns the
+// process exit code.
+func Run(args []string) int {
+	return run(args, os.Stdout, os.Stderr)
+}
+
+func run(args []string, stdout, stderr io.Writer) int {
+	// Keep stdout for command output so it can be piped
+	logging.SetConsoleOutput(stderr)
+	logging.SetLevel(zerolog.WarnLevel)
+
+	flags := flag.NewFlagSet("tabload", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		flags.PrintDefaults()
+	}
+	configDir := flags.String("config-dir", store.DefaultDir(), "directory holding TabLoad's config and presets")
+	profile := flags.String("profile", "", "server profile to connect with (default the profile the GUI last used)")
+	url := flags.String("url", "", "TabbyAPI endpoint URL (default the server the GUI last connected to)")
+	adminKey := flags.String("admin-key", os.Getenv("TABLOAD_ADMIN_KEY"), "TabbyAPI admin key (default $TABLOAD_ADMIN_KEY)")
+	timeout := flags.Duration("timeout", api.DefaultTimeout, "timeout for regular API calls")
+	verbose := flags.Bool("verbose", false, "log debug output to stderr")
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		return ExitUsage
+	}
+
+	if *verbose {
+		logging.SetLevel(zerolog.DebugLevel)
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return ExitUsage
+	}
+
+	st := store.NewFileStore(*configDir)
+	cfg, err := st.Config()
+	if err != nil {
+		fmt.Fprintln(stderr, "tabload:", err)
+		return ExitFailure
+	}
+
+	client := api.NewClient(*url, *adminKey)
+	client.Timeout = *timeout
+
+	profileName := *profile
+	if profileName == "" && *url == "" {
+		profileName = cfg.LastProfile
+	}
+	if err := configureClient(client, cfg, secrets.Open(*configDir), profileName); err != nil {
+		fmt.Fprintln(stderr, "tabload:", err)
+		return ExitFailure
+	}
+
+	e := &env{client: client, store: st, stdout: stdout, stderr: stderr}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	err = e.dispatch(ctx, flags.Args())
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		// A command's --help has already printed its flags
+		return ExitOK
+	case errors.Is(err, errUsage):
+		fmt.Fprintln(stderr, "tabload:", err)
+		fmt.Fprintln(stderr, "Run 'tabload --help' for usage.")
+		return ExitUsage
+	default:
+		fmt.Fprintln(stderr, "tabload:", err)
+		return ExitFailure
+	}
+}
+
+// configureClient fills in whatever wasn't given on the command line from the
+// named server profile, if any, then from the last server the GUI used.
+// Profile keys are read from the secrets store.
+func configureClient(client *api.Client, cfg store.Config, secretStore secrets.Store, profileName string) error {
+	if profileName != "" {
+		profile, ok := cfg.Profile(profileName)
+		if !ok {
+			return fmt.Errorf("server profile not found: %s", profileName)
+		}
+		if client.BaseURL == "" {
+			client.BaseURL = profile.URL
+		}
+		if client.AdminKey == "" {
+			adminKey, err := profileSecret(secretStore, profile.AdminKeyRef, profile.AdminKey)
+			if err != nil {
+				return err
+			}
+			client.AdminKey = adminKey
+		}
+		apiKey, err := profileSecret(secretStore, profile.APIKeyRef, profile.APIKey)
+		if err != nil {
+			return err
+		}
+		client.APIKey = apiKey
+
+		tlsOptions := api.TLSOptions{
+			InsecureSkipVerify: profile.TLS.InsecureSkipVerify,
+			CACertFile:         profile.TLS.CACertFile,
+		}
+		if err := client.ConfigureTLS(tlsOptions); err != nil {
+			return err
+		}
+	}
+
+	if client.BaseURL == "" {
+		client.BaseURL = cfg.LastConnectedServer
+	}
+	if client.BaseURL == "" {
+		client.BaseURL = cfg.APIURL
+	}
+	if client.BaseURL == "" {
+		client.BaseURL = "http://localhost:5000"
+	}
+
+	return nil
+}
+
+// profileSecret returns the secret saved under ref, or the legacy plaintext key
+// of a profile the GUI hasn't migrated yet.
+func profileSecret(secretStore secrets.Store, ref, legacy string) (string, error) {
+	if ref == "" {
+		return legacy, nil
+	}
+	secret, err := secrets.Lookup(secretStore, ref)
+	if errors.Is(err, secrets.ErrLocked) {
+		return "", fmt.Errorf("%w: set %s to read profile keys", err, secrets.PassphraseEnv)
+	}
+	return secret, err
+}
+
+func (e *env) dispatch(ctx context.Context, args []string) error {
+	command, rest := args[0], args[1:]
+
+	switch command {
+	case "status":
+		return e.status(ctx, rest)
+	case "models":
+		return e.subcommand(ctx, "models", rest, map[string]func(context.Context, []string) error{
+			"list": e.modelsList,
+		})
+	case "load":
+		return e.load(ctx, rest)
+	case "unload":
+		return e.unload(ctx, rest)
+	case "loras":
+		return e.subcommand(ctx, "loras", rest, map[string]func(context.Context, []string) error{
+			"list":   e.lorasList,
+			"load":   e.lorasLoad,
+			"unload": e.lorasUnload,
+		})
+	case "presets":
+		return e.subcommand(ctx, "presets", rest, map[string]func(context.Context, []string) error{
+			"list":        e.presetsList,
+			"export":      e.presetsExport,
+			"import":      e.presetsImport,
+			"from-config": e.presetsFromConfig,
+			"to-config":   e.presetsToConfig,
+		})
+	case "help":
+		fmt.Fprint(e.stdout, usage)
+		return nil
+	default:
+		return fmt.Errorf("%w: unknown command %q", errUsage, command)
+	}
+}
+
+func (e *env) subcommand(ctx context.Context, command string, args []string, subcommands map[string]func(context.Context, []string) error) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%w: %s needs a subcommand", errUsage, command)
+	}
+	switch args[0] {
+	case "-h", "-help", "--help":
+		fmt.Fprint(e.stdout, usage)
+		return nil
+	}
+
+	handler, ok := subcommands[args[0]]
+	if !ok {
+		return fmt.Errorf("%w: unknown subcommand %q for %s", errUsage, args[0], command)
+	}
+
+	return handler(ctx, args[1:])
+}
+
+// newFlagSet returns a flag set for a command that reports parse errors as
+// usage errors instead of exiting.
+func (e *env) newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(e.stderr)
+	return flags
+}
+
+func parseFlags(flags *flag.FlagSet, args []string) error {
+	err := flags.Parse(args)
+	switch {
+	case err == nil, errors.Is(err, flag.ErrHelp):
+		return err
+	default:
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+}
+
+// flagSet reports whether the named flag was given on the command line.
+func flagSet(flags *flag.FlagSet, name string) bool {
+	set := false
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == name {
+			set = true
+		}
+	})
+	return set
+}
+
+func (e *env) printJSON(v interface{}) error {
+	encoder := json.NewEncoder(e.stdout)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// elapsed formats the time since start for progress output.
+func elapsed(start time.Time) string {
+	return time.Since(start).Round(100 * time.Millisecond).String()
+}
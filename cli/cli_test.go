@@ -0,0 +1,183 @@
+package cli
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
+)
+
+func TestParseLoraSpec(t *testing.T) {
+	tests := []struct {
+		name         string
+		spec         string
+		wantNames    []string
+		wantScalings []float64
+		wantErr      string
+	}{
+		{name: "scaling defaults to 1", spec: "a:0.8,b", wantNames: []string{"a", "b"}, wantScalings: []float64{0.8, 1}},
+		{name: "spaces", spec: " a : 0.5 , b ", wantNames: []string{"a", "b"}, wantScalings: []float64{0.5, 1}},
+		{name: "empty entries", spec: "a,,b,", wantNames: []string{"a", "b"}, wantScalings: []float64{1, 1}},
+		{name: "nothing but commas", spec: ", ,", wantErr: "no LoRAs given"},
+		{name: "empty", spec: "", wantErr: "no LoRAs given"},
+		{name: "bad scaling", spec: "a:x", wantErr: `invalid scaling for LoRA "a"`},
+		{name: "name with no scaling", spec: "a:", wantErr: `invalid scaling for LoRA "a"`},
+		{name: "scaling with no name", spec: ":0.5", wantErr: "has no name"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, scalings, err := parseLoraSpec(tt.spec)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(names, tt.wantNames) || !reflect.DeepEqual(scalings, tt.wantScalings) {
+				t.Errorf("got %v %v, want %v %v", names, scalings, tt.wantNames, tt.wantScalings)
+			}
+		})
+	}
+}
+
+func TestConfigureClient(t *testing.T) {
+	secretStore := secrets.NewMemoryStore()
+	if err := secretStore.Set(secrets.ProfileKeyRef("lab", "admin_key"), "saved-admin"); err != nil {
+		t.Fatal(err)
+	}
+	cfg := store.Config{
+		LastConnectedServer: "http://last:5000",
+		APIURL:              "http://config:5000",
+		Profiles: []store.ServerProfile{
+			{Name: "lab", URL: "http://lab:5000", AdminKeyRef: secrets.ProfileKeyRef("lab", "admin_key")},
+			{Name: "old", URL: "http://old:5000", AdminKey: "plain-admin"},
+		},
+	}
+
+	tests := []struct {
+		name         string
+		cfg          store.Config
+		url          string
+		adminKey     string
+		profile      string
+		wantURL      string
+		wantAdminKey string
+		wantErr      bool
+	}{
+		{name: "flag URL beats the profile", cfg: cfg, url: "http://flag:5000", profile: "lab", wantURL: "http://flag:5000", wantAdminKey: "saved-admin"},
+		{name: "profile URL beats the last server", cfg: cfg, profile: "lab", wantURL: "http://lab:5000", wantAdminKey: "saved-admin"},
+		{name: "last server", cfg: cfg, wantURL: "http://last:5000"},
+		{name: "config URL", cfg: store.Config{APIURL: "http://config:5000"}, wantURL: "http://config:5000"},
+		{name: "default URL", wantURL: "http://localhost:5000"},
+		{name: "given key beats the profile", cfg: cfg, adminKey: "given", profile: "lab", wantURL: "http://lab:5000", wantAdminKey: "given"},
+		{name: "legacy plaintext key", cfg: cfg, profile: "old", wantURL: "http://old:5000", wantAdminKey: "plain-admin"},
+		{name: "unknown profile", cfg: cfg, profile: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := api.NewClient(tt.url, tt.adminKey)
+			err := configureClient(client, tt.cfg, secretStore, tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, want an error: %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if client.BaseURL != tt.wantURL || client.AdminKey != tt.wantAdminKey {
+				t.Errorf("got %q with key %q, want %q with key %q", client.BaseURL, client.AdminKey, tt.wantURL, tt.wantAdminKey)
+			}
+		})
+	}
+}
+
+// keyServer is a TabbyAPI with nothing loaded that records the admin key it
+// was last sent.
+func keyServer(t *testing.T) (*httptest.Server, func() string) {
+	t.Helper()
+	var (
+		mu  sync.Mutex
+		key string
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		key = r.Header.Get("X-admin-key")
+		mu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		_, _ = w.Write([]byte(`{"detail": "No models are currently loaded."}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, func() string {
+		mu.Lock()
+		defer mu.Unlock()
+		return key
+	}
+}
+
+func TestRunAdminKey(t *testing.T) {
+	server, sentKey := keyServer(t)
+	configDir := t.TempDir()
+	cfg := store.Config{Profiles: []store.ServerProfile{{Name: "old", URL: server.URL, AdminKey: "profile-key"}}}
+	if err := store.NewFileStore(configDir).SaveConfig(cfg); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		env  string
+		args []string
+		want string
+	}{
+		{name: "environment", env: "env-key", args: []string{"--url", server.URL}, want: "env-key"},
+		{name: "flag beats environment", env: "env-key", args: []string{"--url", server.URL, "--admin-key", "flag-key"}, want: "flag-key"},
+		{name: "environment beats profile", env: "env-key", args: []string{"--profile", "old"}, want: "env-key"},
+		{name: "profile", args: []string{"--profile", "old"}, want: "profile-key"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TABLOAD_ADMIN_KEY", tt.env)
+			args := append([]string{"--config-dir", configDir}, tt.args...)
+			if code := run(append(args, "status"), io.Discard, io.Discard); code != ExitOK {
+				t.Fatalf("exit code = %d, want %d", code, ExitOK)
+			}
+			if got := sentKey(); got != tt.want {
+				t.Errorf("admin key = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunExitCodes(t *testing.T) {
+	configDir := t.TempDir()
+	tests := []struct {
+		args []string
+		want int
+	}{
+		{args: []string{"--help"}, want: ExitOK},
+		{args: []string{"status", "--help"}, want: ExitOK},
+		{args: []string{"loras", "--help"}, want: ExitOK},
+		{args: []string{"loras", "load", "-h"}, want: ExitOK},
+		{args: []string{}, want: ExitUsage},
+		{args: []string{"bogus"}, want: ExitUsage},
+		{args: []string{"status", "--bogus"}, want: ExitUsage},
+		{args: []string{"loras"}, want: ExitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.args, " "), func(t *testing.T) {
+			args := append([]string{"--config-dir", configDir}, tt.args...)
+			if code := run(args, io.Discard, io.Discard); code != tt.want {
+				t.Errorf("exit code = %d, want %d", code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,396 @@
+package cli
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/apply"
+	"github.com/sammcj/tabload/store"
+)
+
+type statusOutput struct {
+	Server string         `json:"server"`
+	Model  *api.ModelCard `json:"model"`
+	Loras  string         `json:"loras,omitempty"`
+}
+
+func (e *env) status(ctx context.Context, args []string) error {
+	flags := e.newFlagSet("status")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	output := statusOutput{Server: e.client.BaseURL}
+
+	model, err := e.client.FetchCurrentModelCtx(ctx)
+	switch {
+	case err == nil:
+		output.Model = model
+	case api.IsNoModelLoaded(err):
+		// Nothing loaded isn't a failure
+	default:
+		return err
+	}
+
+	if output.Model != nil {
+		loras, err := e.client.FetchCurrentLorasCtx(ctx)
+		if err != nil {
+			return err
+		}
+		output.Loras = loras
+	}
+
+	if *asJSON {
+		return e.printJSON(output)
+	}
+
+	fmt.Fprintf(e.stdout, "Server: %s\n", output.Server)
+	if output.Model == nil {
+		fmt.Fprintln(e.stdout, "Model:  (none loaded)")
+		return nil
+	}
+	fmt.Fprintf(e.stdout, "Model:  %s\n", output.Model.Summary())
+	if output.Loras != "" {
+		fmt.Fprintf(e.stdout, "LoRAs:  %s\n", output.Loras)
+	}
+	return nil
+}
+
+func (e *env) modelsList(ctx context.Context, args []string) error {
+	flags := e.newFlagSet("models list")
+	draft := flags.Bool("draft", false, "list draft models")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	var models []api.ModelCard
+	var err error
+	if *draft {
+		models, err = e.client.FetchDraftModelsCtx(ctx)
+	} else {
+		models, err = e.client.FetchModelsCtx(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	return e.printCards(models, *asJSON)
+}
+
+func (e *env) lorasList(ctx context.Context, args []string) error {
+	flags := e.newFlagSet("loras list")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	loras, err := e.client.FetchLorasCtx(ctx)
+	if err != nil {
+		return err
+	}
+
+	return e.printCards(loras, *asJSON)
+}
+
+func (e *env) printCards(cards []api.ModelCard, asJSON bool) error {
+	if asJSON {
+		return e.printJSON(cards)
+	}
+	for _, id := range api.ModelIDs(cards) {
+		fmt.Fprintln(e.stdout, id)
+	}
+	return nil
+}
+
+func (e *env) load(ctx context.Context, args []string) error {
+	flags := e.newFlagSet("load")
+	presetName := flags.String("preset", "", "name of a saved preset to load")
+	modelName := flags.String("model", "", "model to load (overrides the preset's model)")
+	quiet := flags.Bool("quiet", false, "don't print load progress")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	preset := &store.Preset{}
+	if *presetName != "" {
+		var err error
+		preset, err = e.store.Preset(*presetName)
+		if err != nil {
+			return err
+		}
+	}
+	if *modelName != "" {
+		preset.ModelID = *modelName
+	}
+	if preset.ModelID == "" {
+		if *presetName != "" {
+			return fmt.Errorf("%w: preset %q has no model, pass --model", errUsage, *presetName)
+		}
+		return fmt.Errorf("%w: load needs --preset or --model", errUsage)
+	}
+
+	start := time.Now()
+	applier := apply.New(e.client, e.store)
+	applier.OnLoadProgress = func(progress api.LoadProgress) {
+		if *quiet {
+			return
+		}
+		fmt.Fprintf(e.stderr, "[%s] loading %s: module %d/%d\n",
+			elapsed(start), progress.ModelType, progress.Module, progress.Modules)
+	}
+	applier.OnStep = func(step apply.Step) {
+		if *quiet || step.Status == apply.Pending {
+			return
+		}
+		fmt.Fprintf(e.stderr, "[%s] %s: %s\n", elapsed(start), step.Name, step.Status)
+	}
+
+	if _, err := applier.Apply(ctx, *preset); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(e.stdout, "Loaded %s in %s\n", preset.ModelID, elapsed(start))
+	return nil
+}
+
+func (e *env) unload(ctx context.Context, args []string) error {
+	if err := parseFlags(e.newFlagSet("unload"), args); err != nil {
+		return err
+	}
+	if err := e.client.UnloadModelCtx(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, "Model unloaded")
+	return nil
+}
+
+func (e *env) lorasLoad(ctx context.Context, args []string) error {
+	flags := e.newFlagSet("loras load")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: loras load takes one NAME[:SCALING],... argument", errUsage)
+	}
+
+	names, scalings, err := parseLoraSpec(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	if err := e.client.LoadLorasCtx(ctx, names, scalings); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Loaded LoRAs: %s\n", strings.Join(names, ", "))
+	return nil
+}
+
+func (e *env) lorasUnload(ctx context.Context, args []string) error {
+	if err := parseFlags(e.newFlagSet("loras unload"), args); err != nil {
+		return err
+	}
+	if err := e.client.UnloadLorasCtx(ctx); err != nil {
+		return err
+	}
+	fmt.Fprintln(e.stdout, "LoRAs unloaded")
+	return nil
+}
+
+func (e *env) presetsList(_ context.Context, args []string) error {
+	flags := e.newFlagSet("presets list")
+	asJSON := flags.Bool("json", false, "print JSON")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+
+	presets, err := e.store.Presets()
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		return e.printJSON(presets)
+	}
+	for _, preset := range presets {
+		fmt.Fprintln(e.stdout, preset.Name)
+	}
+	return nil
+}
+
+func (e *env) presetsExport(_ context.Context, args []string) error {
+	flags := e.newFlagSet("presets export")
+	format := flags.String("format", store.FormatJSON, "json or yaml")
+	output := flags.String("output", "", "file to write instead of stdout; its extension sets the format")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if *output != "" && !flagSet(flags, "format") {
+		*format = store.FormatForPath(*output)
+	}
+
+	presets, err := e.store.Presets()
+	if err != nil {
+		return err
+	}
+	if flags.NArg() > 0 {
+		byName := make(map[string]store.Preset, len(presets))
+		for _, preset := range presets {
+			byName[preset.Name] = preset
+		}
+		selected := make([]store.Preset, 0, flags.NArg())
+		for _, name := range flags.Args() {
+			preset, ok := byName[name]
+			if !ok {
+				return fmt.Errorf("preset not found: %s", name)
+			}
+			selected = append(selected, preset)
+		}
+		presets = selected
+	}
+
+	data, err := store.ExportBundle(presets, *format)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+	if *output == "" {
+		_, err = e.stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(*output, data, 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", *output, err)
+	}
+	fmt.Fprintf(e.stdout, "Exported %d presets to %s\n", len(presets), *output)
+	return nil
+}
+
+func (e *env) presetsImport(_ context.Context, args []string) error {
+	flags := e.newFlagSet("presets import")
+	onConflict := flags.String("on-conflict", "skip", "what to do with presets whose name is taken: skip, overwrite or rename")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: presets import takes one FILE argument", errUsage)
+	}
+	action, err := store.ParseConflictAction(*onConflict)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUsage, err)
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", flags.Arg(0), err)
+	}
+	bundle, err := store.ParseBundle(data)
+	if err != nil {
+		return err
+	}
+
+	result, err := store.ImportPresets(e.store, bundle.Presets, func(store.Preset) store.ConflictAction {
+		return action
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Imported presets: %s\n", result)
+	return nil
+}
+
+func (e *env) presetsFromConfig(_ context.Context, args []string) error {
+	flags := e.newFlagSet("presets from-config")
+	name := flags.String("name", "", "preset name (defaults to the model name)")
+	overwrite := flags.Bool("overwrite", false, "replace a preset of the same name")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: presets from-config takes one config.yml argument", errUsage)
+	}
+
+	data, err := os.ReadFile(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("reading %s: %w", flags.Arg(0), err)
+	}
+	preset, err := store.ParseTabbyConfig(data)
+	if err != nil {
+		return err
+	}
+	if *name != "" {
+		preset.Name = *name
+	}
+
+	if _, err := e.store.Preset(preset.Name); err == nil && !*overwrite {
+		return fmt.Errorf("preset %q already exists, pass --overwrite or --name", preset.Name)
+	}
+	if err := e.store.SavePreset(preset); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stdout, "Saved preset %s\n", preset.Name)
+	return nil
+}
+
+func (e *env) presetsToConfig(_ context.Context, args []string) error {
+	flags := e.newFlagSet("presets to-config")
+	if err := parseFlags(flags, args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return fmt.Errorf("%w: presets to-config takes one preset NAME argument", errUsage)
+	}
+
+	preset, err := e.store.Preset(flags.Arg(0))
+	if err != nil {
+		return err
+	}
+	data, err := preset.TabbyConfig()
+	if err != nil {
+		return err
+	}
+	_, err = e.stdout.Write(data)
+	return err
+}
+
+// parseLoraSpec parses "name[:scaling],..." into LoRA names and scalings.
+// Scaling defaults to 1.0.
+func parseLoraSpec(spec string) ([]string, []float64, error) {
+	var names []string
+	var scalings []float64
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, scalingText, hasScaling := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if name == "" {
+			return nil, nil, fmt.Errorf("LoRA %q has no name", entry)
+		}
+		scaling := 1.0
+		if hasScaling {
+			var err error
+			scaling, err = strconv.ParseFloat(strings.TrimSpace(scalingText), 64)
+			if err != nil {
+				return nil, nil, fmt.Errorf("invalid scaling for LoRA %q: %q", name, scalingText)
+			}
+		}
+
+		names = append(names, name)
+		scalings = append(scalings, scaling)
+	}
+
+	if len(names) == 0 {
+		return nil, nil, errors.New("no LoRAs given")
+	}
+
+	return names, scalings, nil
+}
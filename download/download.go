@@ -0,0 +1,355 @@
+// Package download runs Hugging Face downloads on a TabbyAPI server in the
+// background. Jobs wait in a queue and run one at a time, since the server
+// can only cancel the download it is currently running.
+package download
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+)
+
+// Status is the state of a download job.
+type Status int
+
+const (
+	// Queued means the job is waiting for earlier downloads to finish.
+	Queued Status = iota
+	// Running means the server is downloading the job's files.
+	Running
+	// Done means the download finished.
+	Done
+	// Failed means the server reported an error.
+	Failed
+	// Cancelled means the job was cancelled before it finished.
+	Cancelled
+)
+
+func (s Status) String() string {
+	switch s {
+	case Queued:
+		return "queued"
+	case Running:
+		return "running"
+	case Done:
+		return "done"
+	case Failed:
+		return "failed"
+	case Cancelled:
+		return "cancelled"
+	default:
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+}
+
+// Actions are what to do once a job's download finishes. The queue only
+// carries them; whoever watches it carries them out, as they need the rest
+// of the app.
+type Actions struct {
+	// Refresh reloads the server's model and LoRA lists.
+	Refresh bool
+	// CreatePreset saves a preset for a downloaded model, from the default
+	// load params.
+	CreatePreset bool
+	// Load loads the download straight away: a model with its preset, or a
+	// LoRA onto the loaded model.
+	Load bool
+}
+
+func (a Actions) String() string {
+	var actions []string
+	if a.Refresh {
+		actions = append(actions, "refresh lists")
+	}
+	if a.CreatePreset {
+		actions = append(actions, "create preset")
+	}
+	if a.Load {
+		actions = append(actions, "load")
+	}
+	if len(actions) == 0 {
+		return "nothing"
+	}
+	return strings.Join(actions, ", ")
+}
+
+// Job is a download in the queue. The queue hands out copies, so a Job is a
+// snapshot of the download at the time it was taken.
+type Job struct {
+	ID      int
+	Request Request
+	Actions Actions
+	Status  Status
+	// Path is where the server saved the files, once done.
+	Path string
+	// Err is why the download failed.
+	Err error
+
+	Added    time.Time
+	Started  time.Time
+	Finished time.Time
+
+	// client is a copy of the queue's client taken when the job was queued,
+	// so connecting elsewhere afterwards doesn't change where it runs.
+	client *api.Client
+}
+
+// Name returns the name the server lists the download under: the folder it
+// was saved to, or the one it will be saved to.
+func (j Job) Name() string {
+	folder := j.Path
+	if folder == "" {
+		folder = j.Request.FolderName
+	}
+	if folder == "" {
+		folder = j.Request.RepoID
+	}
+	folder = strings.TrimRight(folder, `/\`)
+	if i := strings.LastIndexAny(folder, `/\`); i >= 0 {
+		folder = folder[i+1:]
+	}
+	return folder
+}
+
+// IsFinished reports whether the job has stopped, successfully or not.
+func (j Job) IsFinished() bool {
+	return j.Status == Done || j.Status == Failed || j.Status == Cancelled
+}
+
+// Elapsed is how long the job has been running, or ran for once finished.
+func (j Job) Elapsed() time.Duration {
+	switch {
+	case j.Started.IsZero():
+		return 0
+	case j.Finished.IsZero():
+		return time.Since(j.Started)
+	default:
+		return j.Finished.Sub(j.Started)
+	}
+}
+
+// Queue runs download jobs one after another. Create one with NewQueue.
+type Queue struct {
+	// client is read when a job is queued; the caller may change it between
+	// calls to Add, but not during one.
+	client *api.Client
+
+	// OnChange, if set, is called whenever a job is added or changes status,
+	// from the goroutine that changed it.
+	OnChange func(Job)
+
+	ctx  context.Context
+	stop context.CancelFunc
+
+	mu     sync.Mutex
+	jobs   []*Job
+	nextID int
+	// working is set while the worker goroutine is running.
+	working bool
+	// cancelRunning cancels the running job's request, and cancelling is set
+	// once the user has asked for it to be cancelled.
+	cancelRunning context.CancelFunc
+	cancelling    bool
+}
+
+// NewQueue returns an empty queue downloading through client. Each job runs
+// on the server client pointed at when it was added.
+func NewQueue(client *api.Client) *Queue {
+	ctx, stop := context.WithCancel(context.Background())
+	return &Queue{client: client, ctx: ctx, stop: stop, nextID: 1}
+}
+
+// Add queues a download, starting it straight away if nothing else is
+// running. actions are passed back with the job once it's done.
+func (q *Queue) Add(request Request, actions Actions) (Job, error) {
+	if strings.TrimSpace(request.RepoID) == "" {
+		return Job{}, errors.New("repo ID cannot be empty")
+	}
+	if q.ctx.Err() != nil {
+		return Job{}, errors.New("download queue is closed")
+	}
+
+	client := *q.client
+	q.mu.Lock()
+	job := &Job{ID: q.nextID, Request: request, Actions: actions, Status: Queued, Added: time.Now(), client: &client}
+	q.nextID++
+	q.jobs = append(q.jobs, job)
+	snapshot := *job
+	start := !q.working
+	q.working = true
+	q.mu.Unlock()
+
+	logging.Info(fmt.Sprintf("Queued download %d: %s", snapshot.ID, request.RepoID))
+	q.report(snapshot)
+	if start {
+		go q.work()
+	}
+	return snapshot, nil
+}
+
+// Cancel cancels the job with the given ID. A queued job is dropped; a
+// running one is cancelled on the server, and only stops once the server
+// has agreed to.
+func (q *Queue) Cancel(id int) error {
+	q.mu.Lock()
+	job := q.find(id)
+	if job == nil {
+		q.mu.Unlock()
+		return fmt.Errorf("no download %d", id)
+	}
+
+	switch job.Status {
+	case Queued:
+		job.Status = Cancelled
+		job.Finished = time.Now()
+		snapshot := *job
+		q.mu.Unlock()
+		logging.Info(fmt.Sprintf("Cancelled queued download %d: %s", id, job.Request.RepoID))
+		q.report(snapshot)
+		return nil
+	case Running:
+		// Set first, as the server fails the download as soon as it's
+		// cancelled, possibly before answering the cancel itself
+		q.cancelling = true
+		client := job.client
+		q.mu.Unlock()
+
+		logging.Info(fmt.Sprintf("Cancelling download %d: %s", id, job.Request.RepoID))
+		// Stop the server first, then stop waiting for its answer. If the
+		// server can't be stopped the job carries on, so the next one isn't
+		// started alongside it.
+		err := client.CancelDownloadCtx(q.ctx)
+
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if job.Status != Running {
+			return err
+		}
+		if err != nil {
+			q.cancelling = false
+			return err
+		}
+		q.cancelRunning()
+		return nil
+	default:
+		q.mu.Unlock()
+		return fmt.Errorf("download %d is already %s", id, job.Status)
+	}
+}
+
+// Jobs returns a snapshot of every job, oldest first.
+func (q *Queue) Jobs() []Job {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := make([]Job, len(q.jobs))
+	for i, job := range q.jobs {
+		jobs[i] = *job
+	}
+	return jobs
+}
+
+// ClearFinished removes finished jobs from the queue.
+func (q *Queue) ClearFinished() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	jobs := q.jobs[:0]
+	for _, job := range q.jobs {
+		if !job.IsFinished() {
+			jobs = append(jobs, job)
+		}
+	}
+	q.jobs = jobs
+}
+
+// Close stops waiting on the running download and starts no more. The server
+// carries on with a download it has already started.
+func (q *Queue) Close() {
+	q.stop()
+}
+
+func (q *Queue) find(id int) *Job {
+	for _, job := range q.jobs {
+		if job.ID == id {
+			return job
+		}
+	}
+	return nil
+}
+
+// next returns the oldest queued job, or nil if there are none.
+func (q *Queue) next() *Job {
+	for _, job := range q.jobs {
+		if job.Status == Queued {
+			return job
+		}
+	}
+	return nil
+}
+
+func (q *Queue) report(job Job) {
+	if q.OnChange != nil {
+		q.OnChange(job)
+	}
+}
+
+// work runs queued jobs until there are none left.
+func (q *Queue) work() {
+	for {
+		q.mu.Lock()
+		job := q.next()
+		if job == nil || q.ctx.Err() != nil {
+			q.working = false
+			q.mu.Unlock()
+			return
+		}
+		ctx, cancel := context.WithCancel(q.ctx)
+		job.Status = Running
+		job.Started = time.Now()
+		q.cancelRunning = cancel
+		q.cancelling = false
+		request := job.Request
+		client := job.client
+		snapshot := *job
+		q.mu.Unlock()
+
+		logging.Info(fmt.Sprintf("Starting download %d: %s", snapshot.ID, request.RepoID))
+		q.report(snapshot)
+
+		path, err := client.DownloadCtx(ctx, request.Params())
+
+		q.mu.Lock()
+		cancelled := q.cancelling || ctx.Err() != nil
+		cancel()
+		q.cancelRunning = nil
+		job.Finished = time.Now()
+		switch {
+		case err == nil:
+			// Finished before a cancel could stop it
+			job.Status = Done
+			job.Path = path
+		case cancelled:
+			job.Status = Cancelled
+		default:
+			job.Status = Failed
+			job.Err = err
+		}
+		snapshot = *job
+		q.mu.Unlock()
+
+		switch snapshot.Status {
+		case Done:
+			logging.Info(fmt.Sprintf("Download %d finished in %s: %s", snapshot.ID, snapshot.Elapsed().Round(time.Second), path))
+		case Failed:
+			logging.Error(fmt.Sprintf("Download %d of %s failed", snapshot.ID, request.RepoID), err)
+		default:
+			logging.Info(fmt.Sprintf("Download %d of %s cancelled", snapshot.ID, request.RepoID))
+		}
+		q.report(snapshot)
+	}
+}
@@ -0,0 +1,205 @@
+package download
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sammcj/tabload/api"
+)
+
+// stubServer is a TabbyAPI download endpoint whose downloads run until they
+// are released or cancelled.
+type stubServer struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// cancelStatus is what /v1/download/cancel answers with.
+	cancelStatus int
+	// finishOnCancel makes the download finish as the cancel arrives, which
+	// then finds nothing to cancel.
+	finishOnCancel bool
+	release        chan string
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	t.Helper()
+	s := &stubServer{cancelStatus: http.StatusOK, release: make(chan string, 1)}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/download":
+			select {
+			case path := <-s.release:
+				if path == "" {
+					http.Error(w, `{"detail": "Download cancelled"}`, http.StatusInternalServerError)
+					return
+				}
+				_, _ = w.Write([]byte(`{"download_path": "` + path + `"}`))
+			case <-r.Context().Done():
+			}
+		case "/v1/download/cancel":
+			s.mu.Lock()
+			status, finish := s.cancelStatus, s.finishOnCancel
+			s.mu.Unlock()
+			switch {
+			case finish:
+				s.release <- "models/done"
+				http.Error(w, `{"detail": "No download is running"}`, http.StatusBadRequest)
+			case status != http.StatusOK:
+				w.WriteHeader(status)
+			default:
+				s.release <- ""
+			}
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *stubServer) setCancelStatus(status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cancelStatus = status
+}
+
+// watch returns a channel of the queue's job changes.
+func watch(q *Queue) <-chan Job {
+	changes := make(chan Job, 100)
+	q.OnChange = func(job Job) { changes <- job }
+	return changes
+}
+
+// waitFor waits for the job to reach status.
+func waitFor(t *testing.T, changes <-chan Job, id int, status Status) Job {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		select {
+		case job := <-changes:
+			if job.ID == id && job.Status == status {
+				return job
+			}
+		case <-timeout:
+			t.Fatalf("download %d never became %s", id, status)
+		}
+	}
+}
+
+func TestQueueRunsJobsInTurn(t *testing.T) {
+	server := newStubServer(t)
+	q := NewQueue(api.NewClient(server.URL, ""))
+	defer q.Close()
+	changes := watch(q)
+
+	first, err := q.Add(Request{RepoID: "a/first"}, Actions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := q.Add(Request{RepoID: "a/second"}, Actions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changes, first.ID, Running)
+	if jobs := q.Jobs(); jobs[1].Status != Queued {
+		t.Errorf("second download is %s while the first runs", jobs[1].Status)
+	}
+
+	server.release <- "models/first"
+	if job := waitFor(t, changes, first.ID, Done); job.Path != "models/first" {
+		t.Errorf("path = %q, want models/first", job.Path)
+	}
+	waitFor(t, changes, second.ID, Running)
+	server.release <- "models/second"
+	waitFor(t, changes, second.ID, Done)
+}
+
+func TestQueueJobKeepsItsClient(t *testing.T) {
+	server := newStubServer(t)
+	client := api.NewClient(server.URL, "")
+	q := NewQueue(client)
+	defer q.Close()
+	changes := watch(q)
+
+	job, err := q.Add(Request{RepoID: "a/b"}, Actions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Connecting elsewhere doesn't move the job
+	client.BaseURL = "http://127.0.0.1:1"
+
+	waitFor(t, changes, job.ID, Running)
+	if err := q.Cancel(job.ID); err != nil {
+		t.Fatalf("cancel went to the wrong server: %v", err)
+	}
+	waitFor(t, changes, job.ID, Cancelled)
+}
+
+func TestQueueCancelNeedsTheServer(t *testing.T) {
+	server := newStubServer(t)
+	q := NewQueue(api.NewClient(server.URL, ""))
+	defer q.Close()
+	changes := watch(q)
+
+	first, _ := q.Add(Request{RepoID: "a/first"}, Actions{})
+	second, _ := q.Add(Request{RepoID: "a/second"}, Actions{})
+	waitFor(t, changes, first.ID, Running)
+
+	server.setCancelStatus(http.StatusInternalServerError)
+	if err := q.Cancel(first.ID); err == nil {
+		t.Fatal("cancel succeeded though the server refused it")
+	}
+	jobs := q.Jobs()
+	if jobs[0].Status != Running || jobs[1].Status != Queued {
+		t.Fatalf("after a refused cancel the downloads are %s and %s, want running and queued", jobs[0].Status, jobs[1].Status)
+	}
+
+	server.setCancelStatus(http.StatusOK)
+	if err := q.Cancel(first.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changes, first.ID, Cancelled)
+	waitFor(t, changes, second.ID, Running)
+}
+
+func TestQueueCancelTooLateKeepsPath(t *testing.T) {
+	server := newStubServer(t)
+	server.finishOnCancel = true
+	q := NewQueue(api.NewClient(server.URL, ""))
+	defer q.Close()
+	changes := watch(q)
+
+	job, _ := q.Add(Request{RepoID: "a/b"}, Actions{})
+	waitFor(t, changes, job.ID, Running)
+	if err := q.Cancel(job.ID); err == nil {
+		t.Error("cancel succeeded though there was nothing left to cancel")
+	}
+	if done := waitFor(t, changes, job.ID, Done); done.Path != "models/done" {
+		t.Errorf("path = %q, want models/done", done.Path)
+	}
+}
+
+func TestQueueCancelQueued(t *testing.T) {
+	server := newStubServer(t)
+	q := NewQueue(api.NewClient(server.URL, ""))
+	defer q.Close()
+	changes := watch(q)
+
+	first, _ := q.Add(Request{RepoID: "a/first"}, Actions{})
+	second, _ := q.Add(Request{RepoID: "a/second"}, Actions{})
+	waitFor(t, changes, first.ID, Running)
+
+	if err := q.Cancel(second.ID); err != nil {
+		t.Fatal(err)
+	}
+	waitFor(t, changes, second.ID, Cancelled)
+	if err := q.Cancel(second.ID); err == nil {
+		t.Error("cancelling a cancelled download succeeded")
+	}
+
+	server.release <- "models/first"
+	waitFor(t, changes, first.ID, Done)
+}
@@ -0,0 +1,251 @@
+package download
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/sammcj/tabload/utils"
+)
+
+// RepoType is where TabbyAPI saves a download: its model or LoRA directory.
+type RepoType string
+
+const (
+	RepoModel RepoType = "model"
+	RepoLora  RepoType = "lora"
+)
+
+// ParseRepoType maps a repo type as shown in the UI, e.g. "Model" or "LoRA",
+// to the value TabbyAPI expects. Empty means a model.
+func ParseRepoType(value string) (RepoType, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "", "model", "models":
+		return RepoModel, nil
+	case "lora", "loras":
+		return RepoLora, nil
+	default:
+		return "", fmt.Errorf("unknown repo type %q, expected model or LoRA", value)
+	}
+}
+
+// Request is what to download, as sent to /v1/download.
+type Request struct {
+	RepoID     string
+	RepoType   RepoType
+	Revision   string
+	FolderName string
+	Token      string
+	// Include and Exclude are glob patterns selecting files in the repo.
+	// No include patterns means every file.
+	Include []string
+	Exclude []string
+	// ChunkLimit caps the size of each downloaded chunk, in the server's
+	// units; 0 uses its default.
+	ChunkLimit int
+	// Timeout bounds the download on the server; 0 uses its default.
+	Timeout time.Duration
+}
+
+// Params returns the request body, leaving out anything not set so the
+// server uses its defaults. In particular an empty include list is left out
+// rather than sent, as TabbyAPI would then match no files at all.
+func (r Request) Params() map[string]interface{} {
+	params := map[string]interface{}{
+		"repo_id": r.RepoID,
+	}
+	if r.RepoType != "" {
+		params["repo_type"] = string(r.RepoType)
+	}
+	optional := map[string]string{
+		"revision":    r.Revision,
+		"folder_name": r.FolderName,
+		"token":       r.Token,
+	}
+	for key, value := range optional {
+		if value != "" {
+			params[key] = value
+		}
+	}
+	if len(r.Include) > 0 {
+		params["include"] = r.Include
+	}
+	if len(r.Exclude) > 0 {
+		params["exclude"] = r.Exclude
+	}
+	if r.ChunkLimit > 0 {
+		params["chunk_limit"] = r.ChunkLimit
+	}
+	if r.Timeout > 0 {
+		params["timeout"] = int(r.Timeout.Seconds())
+	}
+	return params
+}
+
+// Form is the downloader form as typed, turned into a Request by Build.
+type Form struct {
+	RepoID     string
+	RepoType   string
+	Revision   string
+	FolderName string
+	Token      string
+	// Include and Exclude are comma-separated glob patterns.
+	Include string
+	Exclude string
+	// ChunkLimit is a whole number; Timeout is seconds or a duration like
+	// "30m".
+	ChunkLimit string
+	Timeout    string
+}
+
+// repoIDPattern matches a Hugging Face repo ID: a name, optionally under a
+// user or organisation.
+var repoIDPattern = regexp.MustCompile(`^([\w.-]+/)?[\w.-]+$`)
+
+// Build checks the form and returns the request it describes: values are
+// trimmed, empty patterns dropped and the repo type mapped for TabbyAPI. The
+// error joins every problem found.
+func (f Form) Build() (Request, error) {
+	var errs []error
+	request := Request{
+		RepoID:     strings.TrimSpace(f.RepoID),
+		Revision:   strings.TrimSpace(f.Revision),
+		FolderName: strings.TrimSpace(f.FolderName),
+		Token:      strings.TrimSpace(f.Token),
+		Include:    SplitPatterns(f.Include),
+		Exclude:    SplitPatterns(f.Exclude),
+	}
+
+	switch {
+	case request.RepoID == "":
+		errs = append(errs, errors.New("repo ID cannot be empty"))
+	case !repoIDPattern.MatchString(request.RepoID):
+		errs = append(errs, fmt.Errorf("repo ID %q should look like org/name", request.RepoID))
+	}
+
+	repoType, err := ParseRepoType(f.RepoType)
+	if err != nil {
+		errs = append(errs, err)
+	}
+	request.RepoType = repoType
+
+	for _, pattern := range append(append([]string(nil), request.Include...), request.Exclude...) {
+		if _, err := globRegexp(pattern); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if text := strings.TrimSpace(f.ChunkLimit); text != "" {
+		limit, err := utils.Int("chunk_limit", text)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case limit <= 0:
+			errs = append(errs, fmt.Errorf("chunk_limit: must be a positive number, got %d", limit))
+		default:
+			request.ChunkLimit = limit
+		}
+	}
+	if text := strings.TrimSpace(f.Timeout); text != "" {
+		seconds, err := utils.Seconds("timeout", text)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			request.Timeout = time.Duration(seconds) * time.Second
+		}
+	}
+
+	return request, errors.Join(errs...)
+}
+
+// SplitPatterns splits a comma-separated list of glob patterns, trimming
+// each and dropping empty entries.
+func SplitPatterns(value string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(value, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// Selects reports whether the request downloads the repo file at path: it
+// matches an include pattern, or there are none, and no exclude pattern.
+func (r Request) Selects(path string) bool {
+	included := len(r.Include) == 0
+	for _, pattern := range r.Include {
+		if MatchPattern(pattern, path) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, pattern := range r.Exclude {
+		if MatchPattern(pattern, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchPattern reports whether path matches a glob pattern the way the
+// server matches them, with Python's fnmatch: * and ? match any character
+// including /, and [...] is a character class, negated by a leading !. A
+// pattern ending in / matches everything under that directory.
+func MatchPattern(pattern, path string) bool {
+	re, err := globRegexp(pattern)
+	return err == nil && re.MatchString(path)
+}
+
+// globRegexp translates an fnmatch-style glob into a regexp.
+func globRegexp(pattern string) (*regexp.Regexp, error) {
+	if strings.HasSuffix(pattern, "/") {
+		pattern += "*"
+	}
+
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(pattern); i++ {
+		c := pattern[i]
+		switch c {
+		case '*':
+			sb.WriteString(".*")
+		case '?':
+			sb.WriteString(".")
+		case '[':
+			end := strings.IndexByte(pattern[i+1:], ']')
+			// A ] straight after [ or [! is part of the class
+			if end == 0 || (end == 1 && pattern[i+1] == '!') {
+				if next := strings.IndexByte(pattern[i+end+2:], ']'); next >= 0 {
+					end += next + 1
+				} else {
+					end = -1
+				}
+			}
+			if end < 0 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := pattern[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	sb.WriteString("$")
+
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return re, nil
+}
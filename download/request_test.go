@@ -0,0 +1,175 @@
+package download
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMatchPattern(t *testing.T) {
+	tests := []struct {
+		pattern string
+		path    string
+		want    bool
+	}{
+		{"*.safetensors", "model.safetensors", true},
+		{"*.safetensors", "model.safetensors.index.json", false},
+		// * crosses directories, as in fnmatch
+		{"*.json", "sub/dir/config.json", true},
+		{"config.json", "sub/config.json", false},
+		{"model-0000?-of-00002.safetensors", "model-00001-of-00002.safetensors", true},
+		{"model-0000?-of-00002.safetensors", "model-00010-of-00002.safetensors", false},
+		{"[mt]*.json", "tokenizer.json", true},
+		{"[mt]*.json", "config.json", false},
+		{"[!c]*.json", "tokenizer.json", true},
+		{"[!c]*.json", "config.json", false},
+		{"[a-c]*", "bar", true},
+		{"[a-c]*", "dog", false},
+		// A ] right after [ or [! is part of the class
+		{"[]]x", "]x", true},
+		{"[!]]x", "ax", true},
+		{"[!]]x", "]x", false},
+		// An unclosed [ is literal
+		{"[abc", "[abc", true},
+		{"[abc", "a", false},
+		// A trailing / matches everything under the directory
+		{"measurement/", "measurement/layer1.json", true},
+		{"measurement/", "other/layer1.json", false},
+		// Regexp metacharacters are literal
+		{"a+b.(1).txt", "a+b.(1).txt", true},
+		{"a+b.(1).txt", "aab.(1).txt", false},
+		{`back\slash`, `back\slash`, true},
+	}
+	for _, tt := range tests {
+		if got := MatchPattern(tt.pattern, tt.path); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %v, want %v", tt.pattern, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestSelects(t *testing.T) {
+	files := []string{"config.json", "model.safetensors", "README.md", "measurement/a.json"}
+	tests := []struct {
+		name    string
+		include []string
+		exclude []string
+		want    []string
+	}{
+		{"everything", nil, nil, files},
+		{"include", []string{"*.json"}, nil, []string{"config.json", "measurement/a.json"}},
+		{"exclude", nil, []string{"*.md", "measurement/"}, []string{"config.json", "model.safetensors"}},
+		{"exclude wins", []string{"*.json"}, []string{"measurement/"}, []string{"config.json"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := Request{Include: tt.include, Exclude: tt.exclude}
+			var got []string
+			for _, file := range files {
+				if request.Selects(file) {
+					got = append(got, file)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selected %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormBuild(t *testing.T) {
+	tests := []struct {
+		name    string
+		form    Form
+		want    Request
+		wantErr []string
+	}{
+		{
+			name: "trimmed",
+			form: Form{
+				RepoID: "  turboderp/Llama-3-8B-exl2 ", RepoType: "Model", Revision: " 4.0bpw ",
+				FolderName: " llama ", Include: " *.json , , *.safetensors ", Exclude: " ",
+				ChunkLimit: " 4 ", Timeout: " 30m ",
+			},
+			want: Request{
+				RepoID: "turboderp/Llama-3-8B-exl2", RepoType: RepoModel, Revision: "4.0bpw",
+				FolderName: "llama", Include: []string{"*.json", "*.safetensors"},
+				ChunkLimit: 4, Timeout: 30 * time.Minute,
+			},
+		},
+		{
+			name: "lora, bare seconds",
+			form: Form{RepoID: "gpt2", RepoType: "LoRA", Timeout: "90"},
+			want: Request{RepoID: "gpt2", RepoType: RepoLora, Timeout: 90 * time.Second},
+		},
+		{
+			name:    "empty repo",
+			form:    Form{RepoID: "  "},
+			want:    Request{RepoType: RepoModel},
+			wantErr: []string{"repo ID cannot be empty"},
+		},
+		{
+			name: "every problem is reported",
+			form: Form{
+				RepoID: "not a/repo/id", RepoType: "dataset",
+				ChunkLimit: "0", Timeout: "soon",
+			},
+			want:    Request{RepoID: "not a/repo/id"},
+			wantErr: []string{"should look like org/name", "unknown repo type", "chunk_limit", "timeout"},
+		},
+		{
+			name:    "bad chunk limit",
+			form:    Form{RepoID: "a/b", ChunkLimit: "4MB"},
+			want:    Request{RepoID: "a/b", RepoType: RepoModel},
+			wantErr: []string{"chunk_limit"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.form.Build()
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("got error %v, want one containing %q", err, want)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParams(t *testing.T) {
+	tests := []struct {
+		name    string
+		request Request
+		want    map[string]interface{}
+	}{
+		{
+			name:    "only what is set",
+			request: Request{RepoID: "a/b"},
+			want:    map[string]interface{}{"repo_id": "a/b"},
+		},
+		{
+			name: "everything",
+			request: Request{
+				RepoID: "a/b", RepoType: RepoLora, Revision: "main", FolderName: "b", Token: "hf_x",
+				Include: []string{"*.json"}, Exclude: []string{"*.md"}, ChunkLimit: 8, Timeout: 90 * time.Second,
+			},
+			want: map[string]interface{}{
+				"repo_id": "a/b", "repo_type": "lora", "revision": "main", "folder_name": "b", "token": "hf_x",
+				"include": []string{"*.json"}, "exclude": []string{"*.md"}, "chunk_limit": 8, "timeout": 90,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.request.Params(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,248 @@
+// Package hf reads model repository metadata from the Hugging Face Hub API:
+// a repo's branches and the files on each, so downloads can be picked rather
+// than typed blind. The endpoint is configurable so a mirror or a local
+// stand-in server can be used.
+package hf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultEndpoint is the public Hugging Face Hub.
+const DefaultEndpoint = "https://huggingface.co"
+
+// EndpointEnv is the environment variable huggingface_hub reads its endpoint
+// from; it is honoured here too when no endpoint is configured.
+const EndpointEnv = "HF_ENDPOINT"
+
+const (
+	requestTimeout = 30 * time.Second
+	// maxErrorBodySize caps how much of an error response body is read.
+	maxErrorBodySize = 64 * 1024
+)
+
+// Client talks to a Hugging Face Hub API.
+type Client struct {
+	Endpoint string
+	// Token is a Hugging Face access token, needed for gated and private
+	// repos.
+	Token string
+
+	httpClient *http.Client
+}
+
+// NewClient returns a client for the Hub at endpoint. An empty endpoint
+// falls back to $HF_ENDPOINT, then to the public Hub.
+func NewClient(endpoint, token string) *Client {
+	return &Client{
+		Endpoint:   ResolveEndpoint(endpoint),
+		Token:      token,
+		httpClient: &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// ResolveEndpoint returns the endpoint to use for a configured one, which may
+// be empty.
+func ResolveEndpoint(endpoint string) string {
+	if endpoint == "" {
+		endpoint = os.Getenv(EndpointEnv)
+	}
+	if endpoint == "" {
+		endpoint = DefaultEndpoint
+	}
+	return strings.TrimRight(endpoint, "/")
+}
+
+// Error is returned when the Hub answers with a non-200 status.
+type Error struct {
+	StatusCode int
+	URL        string
+	// Message is the Hub's "error" message, or the raw body.
+	Message string
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("GET %s: %d %s", e.URL, e.StatusCode, http.StatusText(e.StatusCode))
+	if e.Message != "" {
+		msg += ": " + e.Message
+	}
+	return msg
+}
+
+// Branch is a branch or tag of a repo.
+type Branch struct {
+	Name         string `json:"name"`
+	Ref          string `json:"ref"`
+	TargetCommit string `json:"targetCommit"`
+}
+
+// bpwPattern matches the branch names EXL2 repos use for each quant, e.g.
+// "4.0bpw" or "6_5bpw".
+var bpwPattern = regexp.MustCompile(`^(\d+)(?:[._](\d+))?bpw$`)
+
+// BPW returns the bits per weight of an EXL2 quant branch.
+func (b Branch) BPW() (float64, bool) {
+	match := bpwPattern.FindStringSubmatch(strings.ToLower(b.Name))
+	if match == nil {
+		return 0, false
+	}
+	value := match[1]
+	if match[2] != "" {
+		value += "." + match[2]
+	}
+	bpw, err := strconv.ParseFloat(value, 64)
+	return bpw, err == nil
+}
+
+// Refs lists a repo's branches and tags.
+type Refs struct {
+	Branches []Branch `json:"branches"`
+	Tags     []Branch `json:"tags"`
+}
+
+// File is a file in a repo.
+type File struct {
+	Path string `json:"path"`
+	Type string `json:"type"`
+	Size int64  `json:"size"`
+	LFS  *struct {
+		Size int64 `json:"size"`
+	} `json:"lfs,omitempty"`
+}
+
+// Bytes returns the size of the file's contents. For LFS files Size is that
+// of the pointer, so the LFS size is used instead.
+func (f File) Bytes() int64 {
+	if f.LFS != nil && f.LFS.Size > 0 {
+		return f.LFS.Size
+	}
+	return f.Size
+}
+
+// TotalBytes returns the combined size of files.
+func TotalBytes(files []File) int64 {
+	var total int64
+	for _, file := range files {
+		total += file.Bytes()
+	}
+	return total
+}
+
+// Refs fetches a model repo's branches and tags. EXL2 repos keep each quant
+// on its own branch; branches are sorted with main first, then other plain
+// branches by name, then the quants by bits per weight.
+func (c *Client) Refs(ctx context.Context, repoID string) (*Refs, error) {
+	var refs Refs
+	if _, err := c.get(ctx, c.repoURL(repoID, "refs"), &refs); err != nil {
+		return nil, fmt.Errorf("fetching branches of %s: %w", repoID, err)
+	}
+	sort.SliceStable(refs.Branches, func(i, j int) bool {
+		a, b := refs.Branches[i], refs.Branches[j]
+		abpw, aok := a.BPW()
+		bbpw, bok := b.BPW()
+		switch {
+		case aok && bok:
+			return abpw < bbpw
+		case aok != bok:
+			return !aok // Plain branches before the quants
+		case (a.Name == "main") != (b.Name == "main"):
+			return a.Name == "main"
+		default:
+			return a.Name < b.Name
+		}
+	})
+	return &refs, nil
+}
+
+// Files lists every file in a model repo at revision, following the Hub's
+// pagination. An empty revision means the default branch.
+func (c *Client) Files(ctx context.Context, repoID, revision string) ([]File, error) {
+	if revision == "" {
+		revision = "main"
+	}
+	next := c.repoURL(repoID, "tree", revision) + "?recursive=true"
+
+	var files []File
+	for next != "" {
+		var page []File
+		link, err := c.get(ctx, next, &page)
+		if err != nil {
+			return nil, fmt.Errorf("listing files of %s@%s: %w", repoID, revision, err)
+		}
+		for _, file := range page {
+			if file.Type == "file" {
+				files = append(files, file)
+			}
+		}
+		next = nextLink(link)
+	}
+
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	return files, nil
+}
+
+// repoURL builds an API URL for a model repo. The repo ID's slash is kept,
+// while each further part is escaped, as revisions may contain slashes.
+func (c *Client) repoURL(repoID string, parts ...string) string {
+	u := c.Endpoint + "/api/models/" + strings.Trim(strings.TrimSpace(repoID), "/")
+	for _, part := range parts {
+		u += "/" + url.PathEscape(part)
+	}
+	return u
+}
+
+// get fetches rawURL and decodes its JSON into v, returning the Link header.
+func (c *Client) get(ctx context.Context, rawURL string, v interface{}) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("creating request: %w", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBodySize))
+		hubErr := &Error{StatusCode: resp.StatusCode, URL: rawURL, Message: strings.TrimSpace(string(body))}
+		var payload struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(body, &payload) == nil && payload.Error != "" {
+			hubErr.Message = payload.Error
+		}
+		return "", hubErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+		return "", fmt.Errorf("decoding response: %w", err)
+	}
+	return resp.Header.Get("Link"), nil
+}
+
+// linkPattern matches the next page in a Link header.
+var linkPattern = regexp.MustCompile(`<([^>]+)>\s*;\s*rel="next"`)
+
+func nextLink(header string) string {
+	if match := linkPattern.FindStringSubmatch(header); match != nil {
+		return match[1]
+	}
+	return ""
+}
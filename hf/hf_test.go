@@ -0,0 +1,193 @@
+package hf
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestBranchBPW(t *testing.T) {
+	tests := []struct {
+		name   string
+		want   float64
+		wantOK bool
+	}{
+		{name: "4.0bpw", want: 4, wantOK: true},
+		{name: "6_5bpw", want: 6.5, wantOK: true},
+		{name: "8bpw", want: 8, wantOK: true},
+		{name: "3.75BPW", want: 3.75, wantOK: true},
+		{name: "main"},
+		{name: "4.0bpw-old"},
+		{name: "bpw"},
+		{name: "4.5.1bpw"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Branch{Name: tt.name}.BPW()
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("BPW() = %v, %v, want %v, %v", got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFileBytes(t *testing.T) {
+	lfs := func(size int64) *struct {
+		Size int64 `json:"size"`
+	} {
+		return &struct {
+			Size int64 `json:"size"`
+		}{size}
+	}
+	tests := []struct {
+		name string
+		file File
+		want int64
+	}{
+		{name: "plain file", file: File{Size: 1200}, want: 1200},
+		{name: "LFS file", file: File{Size: 134, LFS: lfs(8_000_000_000)}, want: 8_000_000_000},
+		{name: "LFS size missing", file: File{Size: 134, LFS: lfs(0)}, want: 134},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.file.Bytes(); got != tt.want {
+				t.Errorf("Bytes() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNextLink(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   string
+	}{
+		{name: "none"},
+		{name: "next", header: `<https://hub/api/models/a/b/tree/main?cursor=abc>; rel="next"`, want: "https://hub/api/models/a/b/tree/main?cursor=abc"},
+		{name: "among others", header: `<https://hub/prev>; rel="prev", <https://hub/next>; rel="next"`, want: "https://hub/next"},
+		{name: "no next", header: `<https://hub/prev>; rel="prev"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextLink(tt.header); got != tt.want {
+				t.Errorf("nextLink() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRepoURL(t *testing.T) {
+	c := NewClient("https://hub.example/", "")
+	tests := []struct {
+		name   string
+		repoID string
+		parts  []string
+		want   string
+	}{
+		{name: "refs", repoID: "turboderp/Llama-3-8B-exl2", parts: []string{"refs"}, want: "https://hub.example/api/models/turboderp/Llama-3-8B-exl2/refs"},
+		{name: "stray slashes and spaces", repoID: " /a/b/ ", parts: []string{"refs"}, want: "https://hub.example/api/models/a/b/refs"},
+		{name: "revision with a slash", repoID: "a/b", parts: []string{"tree", "refs/pr/1"}, want: "https://hub.example/api/models/a/b/tree/refs%2Fpr%2F1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := c.repoURL(tt.repoID, tt.parts...); got != tt.want {
+				t.Errorf("repoURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRefsOrder(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(`{"branches": [
+			{"name": "8_0bpw"}, {"name": "dev"}, {"name": "4.0bpw"}, {"name": "main"}, {"name": "6.5bpw"}, {"name": "alpha"}
+		], "tags": []}`))
+	}))
+	defer server.Close()
+
+	refs, err := NewClient(server.URL, "").Refs(context.Background(), "a/b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, branch := range refs.Branches {
+		got = append(got, branch.Name)
+	}
+	want := []string{"main", "alpha", "dev", "4.0bpw", "6.5bpw", "8_0bpw"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("branches = %v, want %v", got, want)
+	}
+}
+
+func TestFilesPaginates(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/models/a/b/tree/4.0bpw" {
+			http.NotFound(w, r)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			t.Errorf("Authorization = %q", r.Header.Get("Authorization"))
+		}
+		switch r.URL.Query().Get("cursor") {
+		case "":
+			w.Header().Set("Link", "<"+server.URL+r.URL.Path+`?recursive=true&cursor=2>; rel="next"`)
+			_, _ = w.Write([]byte(`[{"path": "model.safetensors", "type": "file", "size": 134, "lfs": {"size": 5000}}, {"path": "sub", "type": "directory"}]`))
+		case "2":
+			_, _ = w.Write([]byte(`[{"path": "config.json", "type": "file", "size": 700}]`))
+		default:
+			t.Errorf("unexpected cursor in %s", r.URL)
+		}
+	}))
+	defer server.Close()
+
+	files, err := NewClient(server.URL, "secret").Files(context.Background(), "a/b", "4.0bpw")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"config.json", "model.safetensors"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if total := TotalBytes(files); total != 5700 {
+		t.Errorf("TotalBytes() = %d, want 5700", total)
+	}
+}
+
+func TestErrorBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		status      int
+		body        string
+		wantMessage string
+	}{
+		{name: "hub error", status: http.StatusUnauthorized, body: `{"error": "Access to model a/b is restricted."}`, wantMessage: "Access to model a/b is restricted."},
+		{name: "plain body", status: http.StatusBadGateway, body: "upstream down\n", wantMessage: "upstream down"},
+		{name: "JSON without an error", status: http.StatusNotFound, body: `{"detail": "nope"}`, wantMessage: `{"detail": "nope"}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+
+			_, err := NewClient(server.URL, "").Refs(context.Background(), "a/b")
+			var hubErr *Error
+			if !errors.As(err, &hubErr) {
+				t.Fatalf("err = %v, want an *Error", err)
+			}
+			if hubErr.StatusCode != tt.status || hubErr.Message != tt.wantMessage {
+				t.Errorf("got %d %q, want %d %q", hubErr.StatusCode, hubErr.Message, tt.status, tt.wantMessage)
+			}
+		})
+	}
+}
@@ -2,6 +2,7 @@ package logging
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
@@ -11,21 +12,35 @@ import (
 	"github.com/rs/zerolog/log"
 )
 
-var Logger zerolog.Logger
+var (
+	Logger  zerolog.Logger
+	logFile *os.File
+)
 
 func init() {
-	logFile := setupLogFile()
-	writer := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: os.Stdout}, logFile)
+	logFile = setupLogFile()
 
 	zerolog.TimeFieldFormat = time.RFC3339
 	// zerolog.SetGlobalLevel(zerolog.InfoLevel)
 	zerolog.SetGlobalLevel(zerolog.DebugLevel)
 
+	SetConsoleOutput(os.Stdout)
+}
+
+// SetConsoleOutput sends console log output to w. Logs are still written to
+// the log file as well.
+func SetConsoleOutput(w io.Writer) {
+	writer := zerolog.MultiLevelWriter(zerolog.ConsoleWriter{Out: w}, logFile)
 	Logger = zerolog.New(writer).With().Timestamp().Caller().Logger()
 
 	log.Logger = Logger
 }
 
+// SetLevel sets the minimum level that is logged.
+func SetLevel(level zerolog.Level) {
+	zerolog.SetGlobalLevel(level)
+}
+
 func setupLogFile() *os.File {
 	logDir := filepath.Join(os.TempDir(), "tabload_logs")
 
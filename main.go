@@ -1,14 +1,24 @@
 package main
 
 import (
+	"os"
+
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/driver/desktop"
+	"github.com/sammcj/tabload/cli"
 	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
 	"github.com/sammcj/tabload/ui"
 )
 
 func main() {
+	// Any arguments select the headless CLI instead of the GUI
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	logging.Info("TabLoad started")
 
 	a := app.NewWithID("com.sammcj.tabload")
@@ -17,16 +27,13 @@ func main() {
 	// Set initial window size
 	w.Resize(fyne.Size{Width: 900, Height: 800})
 
-	tabload := ui.NewTabLoad(w)
+	st := store.NewFileStore(store.DefaultDir())
+	tabload := ui.NewTabLoad(w, st, st, st, secrets.Open(store.DefaultDir()))
+	w.SetOnClosed(tabload.Close)
 
-	// Build UI first
+	// Build UI first; it auto-connects once saved keys are unlocked
 	tabload.BuildUI()
 
-	// Perform auto-connect immediately after building the UI
-	if tabload.ShouldAutoConnect() {
-		tabload.AutoConnect()
-	}
-
 	// Set system tray menu
 	if desk, ok := a.(desktop.App); ok {
 		m := fyne.NewMenu("TabLoad",
@@ -0,0 +1,234 @@
+// Package monitor polls a TabbyAPI server in the background and tracks the
+// connection through an explicit state machine, so the UI can tell when the
+// server goes away and comes back.
+package monitor
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+)
+
+// State is the state of the connection to the server.
+type State int
+
+const (
+	// Disconnected means the monitor isn't running.
+	Disconnected State = iota
+	// Connecting means the first poll hasn't finished yet.
+	Connecting
+	// Connected means the server is up and healthy.
+	Connected
+	// Degraded means the server answers but reports itself unhealthy or fails
+	// to report its model.
+	Degraded
+	// Reconnecting means the server stopped answering and is being retried
+	// with backoff.
+	Reconnecting
+)
+
+func (s State) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Degraded:
+		return "degraded"
+	case Reconnecting:
+		return "reconnecting"
+	default:
+		return fmt.Sprintf("State(%d)", int(s))
+	}
+}
+
+// transitions lists the states each state can move to.
+var transitions = map[State][]State{
+	Disconnected: {Connecting},
+	Connecting:   {Connected, Degraded, Reconnecting, Disconnected},
+	Connected:    {Degraded, Reconnecting, Disconnected},
+	Degraded:     {Connected, Reconnecting, Disconnected},
+	Reconnecting: {Connected, Degraded, Disconnected},
+}
+
+// canTransition reports whether the state machine allows moving from one state
+// to another.
+func canTransition(from, to State) bool {
+	for _, allowed := range transitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Status is a snapshot of the connection, passed to the change callback.
+type Status struct {
+	State State
+	// Previous is the state before this change.
+	Previous State
+	// Err is the error of the last failed poll, if any.
+	Err error
+	// Health is the last health report, if the server answered.
+	Health *api.Health
+	// Model is the loaded model, or nil if none is loaded or it's unknown.
+	Model *api.ModelCard
+	// Recovered is set when the server answers again after having been
+	// unreachable or degraded.
+	Recovered bool
+	// ModelChanged is set when the loaded model differs from the last poll.
+	ModelChanged bool
+	// NextRetry is how long until the next attempt while reconnecting.
+	NextRetry time.Duration
+}
+
+// Default polling intervals.
+const (
+	DefaultInterval   = 10 * time.Second
+	DefaultMinBackoff = time.Second
+	DefaultMaxBackoff = 30 * time.Second
+)
+
+// Monitor polls /health and /v1/model on a server. Create one with New and
+// run it with Run.
+type Monitor struct {
+	client *api.Client
+
+	// Interval is the time between polls while connected or degraded.
+	Interval time.Duration
+	// MinBackoff and MaxBackoff bound the exponential backoff between polls
+	// while reconnecting.
+	MinBackoff time.Duration
+	MaxBackoff time.Duration
+
+	// OnChange is called from the monitor's goroutine whenever the state
+	// changes, or the loaded model changes while connected.
+	OnChange func(Status)
+
+	mu      sync.Mutex
+	state   State
+	modelID string
+}
+
+// New returns a monitor for client with the default intervals.
+func New(client *api.Client, onChange func(Status)) *Monitor {
+	return &Monitor{
+		client:     client,
+		Interval:   DefaultInterval,
+		MinBackoff: DefaultMinBackoff,
+		MaxBackoff: DefaultMaxBackoff,
+		OnChange:   onChange,
+	}
+}
+
+// State returns the current state.
+func (m *Monitor) State() State {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.state
+}
+
+// Run polls the server until ctx is cancelled, then moves to Disconnected.
+func (m *Monitor) Run(ctx context.Context) {
+	m.setState(Status{State: Connecting})
+
+	backoff := m.MinBackoff
+	for {
+		status := m.poll(ctx)
+		if ctx.Err() != nil {
+			break
+		}
+
+		wait := m.Interval
+		if status.State == Reconnecting {
+			wait = backoff
+			status.NextRetry = wait
+			backoff = min(backoff*2, m.MaxBackoff)
+		} else {
+			backoff = m.MinBackoff
+		}
+		m.setState(status)
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+		case <-timer.C:
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+
+	m.setState(Status{State: Disconnected})
+}
+
+// poll checks the server once and returns the state it's in.
+func (m *Monitor) poll(ctx context.Context) Status {
+	health, err := m.client.FetchHealthCtx(ctx)
+	if err != nil {
+		return Status{State: Reconnecting, Err: err}
+	}
+
+	status := Status{State: Connected, Health: health}
+	if !health.Healthy() {
+		status.State = Degraded
+	}
+
+	model, err := m.client.FetchCurrentModelCtx(ctx)
+	switch {
+	case err == nil:
+		status.Model = model
+	case api.IsNoModelLoaded(err):
+		// Nothing loaded, but the server is fine. Other errors, such as a key
+		// the server rejects, leave it degraded.
+	default:
+		status.State = Degraded
+		status.Err = err
+	}
+
+	return status
+}
+
+// setState records a poll's result and calls OnChange if the state or the
+// loaded model changed.
+func (m *Monitor) setState(status Status) {
+	m.mu.Lock()
+	status.Previous = m.state
+	if status.State != m.state && !canTransition(m.state, status.State) {
+		logging.Warn(fmt.Sprintf("Ignoring invalid connection state change %s -> %s", m.state, status.State))
+		m.mu.Unlock()
+		return
+	}
+	if status.State == Connecting {
+		m.modelID = ""
+	}
+
+	modelKnown := status.State == Connected || status.State == Degraded
+	modelID := ""
+	if status.Model != nil {
+		modelID = status.Model.ID
+	}
+	if modelKnown && status.Err == nil {
+		status.ModelChanged = modelID != m.modelID && status.Previous != Connecting
+		m.modelID = modelID
+	}
+	status.Recovered = status.State == Connected && (status.Previous == Reconnecting || status.Previous == Degraded)
+
+	changed := status.State != status.Previous || status.ModelChanged || status.State == Reconnecting
+	m.state = status.State
+	m.mu.Unlock()
+
+	if status.State != status.Previous {
+		logging.Info(fmt.Sprintf("Connection state: %s -> %s", status.Previous, status.State))
+	}
+	if changed && m.OnChange != nil {
+		m.OnChange(status)
+	}
+}
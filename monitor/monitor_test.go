@@ -0,0 +1,311 @@
+package monitor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sammcj/tabload/api"
+)
+
+// reply is a canned response from the stub server.
+type reply struct {
+	status int
+	body   string
+}
+
+func newStub(t *testing.T, health, model reply) *api.Client {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var rep reply
+		switch r.URL.Path {
+		case "/health":
+			rep = health
+		case "/v1/model":
+			rep = model
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(rep.status)
+		_, _ = w.Write([]byte(rep.body))
+	}))
+	t.Cleanup(server.Close)
+	return api.NewClient(server.URL, "")
+}
+
+func TestPoll(t *testing.T) {
+	healthy := reply{http.StatusOK, `{"status": "healthy"}`}
+	model := reply{http.StatusOK, `{"id": "llama-8b"}`}
+	tests := []struct {
+		name      string
+		health    reply
+		model     reply
+		want      State
+		wantModel string
+		wantErr   bool
+	}{
+		{name: "model loaded", health: healthy, model: model, want: Connected, wantModel: "llama-8b"},
+		{name: "older server with empty health", health: reply{http.StatusOK, ""}, model: model, want: Connected, wantModel: "llama-8b"},
+		{
+			name:   "no model loaded",
+			health: healthy,
+			model:  reply{http.StatusBadRequest, `{"detail": "No models are currently loaded."}`},
+			want:   Connected,
+		},
+		{
+			name:    "key rejected",
+			health:  healthy,
+			model:   reply{http.StatusUnauthorized, `{"detail": "Invalid API key"}`},
+			want:    Degraded,
+			wantErr: true,
+		},
+		{
+			name:    "forbidden",
+			health:  healthy,
+			model:   reply{http.StatusForbidden, `{"detail": "Forbidden"}`},
+			want:    Degraded,
+			wantErr: true,
+		},
+		{
+			name:    "other bad request",
+			health:  healthy,
+			model:   reply{http.StatusBadRequest, `{"detail": "Something else"}`},
+			want:    Degraded,
+			wantErr: true,
+		},
+		{
+			name:    "model endpoint fails",
+			health:  healthy,
+			model:   reply{http.StatusInternalServerError, `{"detail": "boom"}`},
+			want:    Degraded,
+			wantErr: true,
+		},
+		{
+			name:      "unhealthy",
+			health:    reply{http.StatusServiceUnavailable, `{"status": "unhealthy", "issues": [{"description": "CUDA error"}]}`},
+			model:     model,
+			want:      Degraded,
+			wantModel: "llama-8b",
+		},
+		{
+			name:    "health fails",
+			health:  reply{http.StatusInternalServerError, ""},
+			model:   model,
+			want:    Reconnecting,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := New(newStub(t, tt.health, tt.model), nil)
+			status := m.poll(context.Background())
+			if status.State != tt.want {
+				t.Errorf("state = %s, want %s (err %v)", status.State, tt.want, status.Err)
+			}
+			if (status.Err != nil) != tt.wantErr {
+				t.Errorf("err = %v, want an error: %v", status.Err, tt.wantErr)
+			}
+			modelID := ""
+			if status.Model != nil {
+				modelID = status.Model.ID
+			}
+			if modelID != tt.wantModel {
+				t.Errorf("model = %q, want %q", modelID, tt.wantModel)
+			}
+		})
+	}
+}
+
+func TestPollUnreachable(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	status := New(api.NewClient(server.URL, ""), nil).poll(context.Background())
+	if status.State != Reconnecting || status.Err == nil {
+		t.Errorf("got %s (err %v), want reconnecting with an error", status.State, status.Err)
+	}
+}
+
+func TestCanTransition(t *testing.T) {
+	tests := []struct {
+		from, to State
+		want     bool
+	}{
+		{Disconnected, Connecting, true},
+		{Disconnected, Connected, false},
+		{Disconnected, Reconnecting, false},
+		{Connecting, Connected, true},
+		{Connecting, Degraded, true},
+		{Connecting, Reconnecting, true},
+		{Connecting, Disconnected, true},
+		{Connected, Degraded, true},
+		{Connected, Connecting, false},
+		{Degraded, Connected, true},
+		{Reconnecting, Connected, true},
+		{Reconnecting, Connecting, false},
+	}
+	for _, tt := range tests {
+		if got := canTransition(tt.from, tt.to); got != tt.want {
+			t.Errorf("canTransition(%s, %s) = %v, want %v", tt.from, tt.to, got, tt.want)
+		}
+	}
+}
+
+func TestSetState(t *testing.T) {
+	llama := &api.ModelCard{ID: "llama-8b"}
+	mistral := &api.ModelCard{ID: "mistral-7b"}
+	tests := []struct {
+		name   string
+		status Status
+		want   *Status // nil means OnChange isn't called
+		state  State
+	}{
+		{
+			name:   "start",
+			status: Status{State: Connecting},
+			want:   &Status{State: Connecting, Previous: Disconnected},
+			state:  Connecting,
+		},
+		{
+			name:   "first poll isn't a model change",
+			status: Status{State: Connected, Model: llama},
+			want:   &Status{State: Connected, Previous: Connecting, Model: llama},
+			state:  Connected,
+		},
+		{
+			name:   "same model is quiet",
+			status: Status{State: Connected, Model: llama},
+			state:  Connected,
+		},
+		{
+			name:   "model changed",
+			status: Status{State: Connected, Model: mistral},
+			want:   &Status{State: Connected, Previous: Connected, Model: mistral, ModelChanged: true},
+			state:  Connected,
+		},
+		{
+			name:   "invalid change is ignored",
+			status: Status{State: Connecting},
+			state:  Connected,
+		},
+		{
+			name:   "lost",
+			status: Status{State: Reconnecting, NextRetry: time.Second},
+			want:   &Status{State: Reconnecting, Previous: Connected, NextRetry: time.Second},
+			state:  Reconnecting,
+		},
+		{
+			name:   "every retry is reported",
+			status: Status{State: Reconnecting, NextRetry: 2 * time.Second},
+			want:   &Status{State: Reconnecting, Previous: Reconnecting, NextRetry: 2 * time.Second},
+			state:  Reconnecting,
+		},
+		{
+			name:   "recovered",
+			status: Status{State: Connected, Model: mistral},
+			want:   &Status{State: Connected, Previous: Reconnecting, Model: mistral, Recovered: true},
+			state:  Connected,
+		},
+		{
+			name:   "model unloaded",
+			status: Status{State: Connected},
+			want:   &Status{State: Connected, Previous: Connected, ModelChanged: true},
+			state:  Connected,
+		},
+		{
+			name:   "stopped",
+			status: Status{State: Disconnected},
+			want:   &Status{State: Disconnected, Previous: Connected},
+			state:  Disconnected,
+		},
+	}
+
+	var got *Status
+	m := New(nil, func(status Status) { got = &status })
+	for _, tt := range tests {
+		got = nil
+		m.setState(tt.status)
+		switch {
+		case tt.want == nil && got != nil:
+			t.Errorf("%s: OnChange called with %+v", tt.name, *got)
+		case tt.want != nil && got == nil:
+			t.Errorf("%s: OnChange not called, want %+v", tt.name, *tt.want)
+		case tt.want != nil && *got != *tt.want:
+			t.Errorf("%s: OnChange called with %+v, want %+v", tt.name, *got, *tt.want)
+		}
+		if state := m.State(); state != tt.state {
+			t.Errorf("%s: state = %s, want %s", tt.name, state, tt.state)
+		}
+	}
+}
+
+func TestRunBacksOff(t *testing.T) {
+	// The server is down for four polls, up for one, then down again
+	var (
+		mu    sync.Mutex
+		polls int
+	)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/health" {
+			mu.Lock()
+			polls++
+			up := polls == 5
+			mu.Unlock()
+			if !up {
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+		}
+		_, _ = w.Write([]byte(`{"id": "llama-8b"}`))
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var (
+		retries []time.Duration
+		states  []State
+	)
+	m := New(api.NewClient(server.URL, ""), func(status Status) {
+		states = append(states, status.State)
+		if status.State == Reconnecting {
+			retries = append(retries, status.NextRetry)
+		}
+		if len(retries) == 5 {
+			cancel()
+		}
+	})
+	m.Interval = time.Millisecond
+	m.MinBackoff = time.Millisecond
+	m.MaxBackoff = 4 * time.Millisecond
+
+	done := make(chan struct{})
+	go func() {
+		m.Run(ctx)
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run didn't stop")
+	}
+
+	want := []time.Duration{time.Millisecond, 2 * time.Millisecond, 4 * time.Millisecond, 4 * time.Millisecond, time.Millisecond}
+	if len(retries) != len(want) {
+		t.Fatalf("retries = %v, want %v", retries, want)
+	}
+	for i := range want {
+		if retries[i] != want[i] {
+			t.Errorf("retries = %v, want %v", retries, want)
+			break
+		}
+	}
+	if last := states[len(states)-1]; last != Disconnected {
+		t.Errorf("last state = %s, want disconnected", last)
+	}
+}
@@ -0,0 +1,215 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// scrypt parameters for deriving the file key from the passphrase.
+const (
+	scryptN      = 1 << 15
+	scryptR      = 8
+	scryptP      = 1
+	keyLength    = 32
+	saltLength   = 16
+	fileVersion  = 1
+	secretsFile  = "secrets.enc"
+	secretsPerms = 0600
+)
+
+// encryptedFile is the on-disk format of a FileStore.
+type encryptedFile struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// FileStore keeps secrets in a file encrypted with AES-256-GCM, using a key
+// derived from a passphrase with scrypt. It must be unlocked before use.
+type FileStore struct {
+	path string
+
+	mu         sync.Mutex
+	passphrase string
+	secrets    map[string]string // nil while locked
+}
+
+// NewFileStore returns a locked store backed by secrets.enc in dir.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{path: filepath.Join(dir, secretsFile)}
+}
+
+// Exists reports whether the secrets file has been created yet.
+func (s *FileStore) Exists() bool {
+	_, err := os.Stat(s.path)
+	return err == nil
+}
+
+// Locked reports whether the store still needs its passphrase.
+func (s *FileStore) Locked() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.secrets == nil
+}
+
+// Unlock decrypts the secrets file with passphrase. If the file doesn't exist
+// yet, passphrase becomes the passphrase it is created with.
+func (s *FileStore) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return errors.New("passphrase cannot be empty")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.read(passphrase)
+	if err != nil {
+		return err
+	}
+
+	s.passphrase = passphrase
+	s.secrets = secrets
+	return nil
+}
+
+func (s *FileStore) Get(ref string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		return "", ErrLocked
+	}
+	secret, ok := s.secrets[ref]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s *FileStore) Set(ref, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		return ErrLocked
+	}
+	s.secrets[ref] = secret
+	return s.write()
+}
+
+func (s *FileStore) Delete(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.secrets == nil {
+		return ErrLocked
+	}
+	if _, ok := s.secrets[ref]; !ok {
+		return nil
+	}
+	delete(s.secrets, ref)
+	return s.write()
+}
+
+func (s *FileStore) Backend() string {
+	return "encrypted file " + s.path
+}
+
+func (s *FileStore) read(passphrase string) (map[string]string, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return make(map[string]string), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets file: %w", err)
+	}
+
+	var file encryptedFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("unmarshalling secrets file: %w", err)
+	}
+	if file.Version != fileVersion {
+		return nil, fmt.Errorf("unsupported secrets file version %d", file.Version)
+	}
+
+	gcm, err := newGCM(passphrase, file.Salt)
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return nil, errors.New("wrong passphrase or corrupted secrets file")
+	}
+
+	secrets := make(map[string]string)
+	if err := json.Unmarshal(plaintext, &secrets); err != nil {
+		return nil, fmt.Errorf("unmarshalling secrets: %w", err)
+	}
+	return secrets, nil
+}
+
+// write encrypts and saves the secrets with a fresh salt and nonce. The caller
+// must hold s.mu.
+func (s *FileStore) write() error {
+	plaintext, err := json.Marshal(s.secrets)
+	if err != nil {
+		return fmt.Errorf("marshalling secrets: %w", err)
+	}
+
+	salt := make([]byte, saltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return fmt.Errorf("generating salt: %w", err)
+	}
+	gcm, err := newGCM(s.passphrase, salt)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return fmt.Errorf("generating nonce: %w", err)
+	}
+
+	data, err := json.MarshalIndent(encryptedFile{
+		Version:    fileVersion,
+		Salt:       salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plaintext, nil),
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling secrets file: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0700); err != nil {
+		return fmt.Errorf("creating secrets directory: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, secretsPerms); err != nil {
+		return fmt.Errorf("writing secrets file: %w", err)
+	}
+	// WriteFile only sets the mode of a new file
+	if err := os.Chmod(s.path, secretsPerms); err != nil {
+		return fmt.Errorf("restricting secrets file: %w", err)
+	}
+	return nil
+}
+
+func newGCM(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("deriving key: %w", err)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("creating cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,99 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileStoreRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	if _, err := store.Get("a"); !errors.Is(err, ErrLocked) {
+		t.Fatalf("Get on a locked store: err = %v, want ErrLocked", err)
+	}
+	if err := store.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(ProfileKeyRef("lab", "admin_key"), "admin-secret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set(HFTokenRef, "hf_token"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(HFTokenRef); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewFileStore(dir)
+	if !reopened.Exists() || !reopened.Locked() {
+		t.Fatal("reopened store should exist and be locked")
+	}
+	if err := reopened.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := reopened.Get(ProfileKeyRef("lab", "admin_key")); err != nil || got != "admin-secret" {
+		t.Errorf("Get() = %q, %v, want admin-secret", got, err)
+	}
+	if _, err := reopened.Get(HFTokenRef); !errors.Is(err, ErrNotFound) {
+		t.Errorf("deleted secret: err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestFileStoreWrongPassphrase(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFileStore(dir)
+	if err := store.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("a", "secret"); err != nil {
+		t.Fatal(err)
+	}
+
+	reopened := NewFileStore(dir)
+	err := reopened.Unlock("battery staple")
+	if err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Fatalf("err = %v, want a wrong passphrase error", err)
+	}
+	if !reopened.Locked() {
+		t.Error("store unlocked with the wrong passphrase")
+	}
+	if err := reopened.Unlock(""); err == nil {
+		t.Error("empty passphrase accepted")
+	}
+}
+
+func TestFileStorePermissions(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, secretsFile)
+	store := NewFileStore(dir)
+	if err := store.Unlock("correct horse"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("a", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	checkMode(t, path)
+
+	// A file that was left readable is tightened on the next write
+	if err := os.Chmod(path, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Set("b", "secret"); err != nil {
+		t.Fatal(err)
+	}
+	checkMode(t, path)
+}
+
+func checkMode(t *testing.T, path string) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := info.Mode().Perm(); mode != 0600 {
+		t.Errorf("secrets file mode = %o, want 600", mode)
+	}
+}
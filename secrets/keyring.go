@@ -0,0 +1,53 @@
+package secrets
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zalando/go-keyring"
+)
+
+// KeyringStore keeps secrets in the OS keyring.
+type KeyringStore struct{}
+
+func NewKeyringStore() *KeyringStore {
+	return &KeyringStore{}
+}
+
+func (s *KeyringStore) Get(ref string) (string, error) {
+	secret, err := keyring.Get(service, ref)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("reading %s from keyring: %w", ref, err)
+	}
+	return secret, nil
+}
+
+func (s *KeyringStore) Set(ref, secret string) error {
+	if err := keyring.Set(service, ref, secret); err != nil {
+		return fmt.Errorf("saving %s to keyring: %w", ref, err)
+	}
+	return nil
+}
+
+func (s *KeyringStore) Delete(ref string) error {
+	err := keyring.Delete(service, ref)
+	if err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return fmt.Errorf("deleting %s from keyring: %w", ref, err)
+	}
+	return nil
+}
+
+func (s *KeyringStore) Backend() string {
+	return "OS keyring"
+}
+
+// keyringAvailable probes the keyring with a lookup. Anything other than a
+// clean "not found" means there is no usable keyring, e.g. no Secret Service
+// on a headless Linux box.
+func keyringAvailable() bool {
+	_, err := keyring.Get(service, "probe")
+	return err == nil || errors.Is(err, keyring.ErrNotFound)
+}
@@ -0,0 +1,41 @@
+package secrets
+
+import "sync"
+
+// MemoryStore keeps secrets in memory only, for tests.
+type MemoryStore struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{secrets: make(map[string]string)}
+}
+
+func (s *MemoryStore) Get(ref string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	secret, ok := s.secrets[ref]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return secret, nil
+}
+
+func (s *MemoryStore) Set(ref, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.secrets[ref] = secret
+	return nil
+}
+
+func (s *MemoryStore) Delete(ref string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.secrets, ref)
+	return nil
+}
+
+func (s *MemoryStore) Backend() string {
+	return "memory"
+}
@@ -0,0 +1,81 @@
+// Package secrets keeps admin keys and access tokens out of TabLoad's config
+// file. Secrets are stored in the OS keyring (the Secret Service on Linux)
+// when one is available, and otherwise in a passphrase-encrypted file.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/sammcj/tabload/logging"
+)
+
+// Store saves secrets under reference names. The config only ever holds the
+// references.
+type Store interface {
+	// Get returns the secret saved under ref, or ErrNotFound.
+	Get(ref string) (string, error)
+	// Set saves secret under ref, replacing any existing secret.
+	Set(ref, secret string) error
+	// Delete removes the secret saved under ref. Deleting a missing secret is
+	// not an error.
+	Delete(ref string) error
+	// Backend describes where secrets are kept, for display.
+	Backend() string
+}
+
+var (
+	// ErrNotFound is returned by Get when no secret is saved under a ref.
+	ErrNotFound = errors.New("secret not found")
+	// ErrLocked is returned by an encrypted file store that hasn't been
+	// unlocked with its passphrase yet.
+	ErrLocked = errors.New("secrets file is locked")
+)
+
+// service is the keyring service name secrets are saved under.
+const service = "tabload"
+
+// ProfileKeyRef returns the reference for one of a server profile's keys, such
+// as "admin_key" or "api_key".
+func ProfileKeyRef(profile, key string) string {
+	return fmt.Sprintf("profile/%s/%s", profile, key)
+}
+
+// HFTokenRef is the reference of the Hugging Face access token.
+const HFTokenRef = "huggingface/token"
+
+// PassphraseEnv is the environment variable the encrypted file store's
+// passphrase is read from, for headless use.
+const PassphraseEnv = "TABLOAD_SECRETS_PASSPHRASE"
+
+// Open returns the keyring store if the OS keyring is usable, and otherwise an
+// encrypted file store in dir. The file store is unlocked with $PassphraseEnv
+// when it's set, and otherwise must be unlocked before use.
+func Open(dir string) Store {
+	if keyringAvailable() {
+		return NewKeyringStore()
+	}
+
+	logging.Info("OS keyring unavailable, falling back to encrypted secrets file")
+	store := NewFileStore(dir)
+	if passphrase := os.Getenv(PassphraseEnv); passphrase != "" {
+		if err := store.Unlock(passphrase); err != nil {
+			logging.Error("Failed to unlock secrets file with "+PassphraseEnv, err)
+		}
+	}
+	return store
+}
+
+// Lookup returns the secret saved under ref, treating a missing ref or secret
+// as empty.
+func Lookup(store Store, ref string) (string, error) {
+	if ref == "" {
+		return "", nil
+	}
+	secret, err := store.Get(ref)
+	if errors.Is(err, ErrNotFound) {
+		return "", nil
+	}
+	return secret, err
+}
@@ -0,0 +1,230 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Bundle formats for sharing presets.
+const (
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+// Bundle is a portable set of presets, in the same shape as presets.json so
+// either can be imported.
+type Bundle struct {
+	Version int      `json:"version"`
+	Presets []Preset `json:"presets"`
+}
+
+// ExportBundle encodes presets as a bundle in format, FormatJSON or
+// FormatYAML.
+func ExportBundle(presets []Preset, format string) ([]byte, error) {
+	data, err := json.MarshalIndent(Bundle{Version: PresetsVersion, Presets: presets}, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling presets: %w", err)
+	}
+
+	switch format {
+	case FormatJSON:
+		return data, nil
+	case FormatYAML:
+		// Go through JSON so the YAML uses the same field names in the same
+		// order. JSON is valid YAML, so it decodes straight into a node tree.
+		var node yaml.Node
+		if err := yaml.Unmarshal(data, &node); err != nil {
+			return nil, fmt.Errorf("converting presets to YAML: %w", err)
+		}
+		blockStyle(&node)
+		var buf bytes.Buffer
+		encoder := yaml.NewEncoder(&buf)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(&node); err != nil {
+			return nil, fmt.Errorf("marshalling presets: %w", err)
+		}
+		if err := encoder.Close(); err != nil {
+			return nil, fmt.Errorf("marshalling presets: %w", err)
+		}
+		return buf.Bytes(), nil
+	default:
+		return nil, fmt.Errorf("unknown preset format %q", format)
+	}
+}
+
+// blockStyle clears the flow and quoting styles decoding JSON leaves on a
+// node tree, so it encodes as ordinary block YAML.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// FormatForPath returns the bundle format to use for a file name, by its
+// extension.
+func FormatForPath(path string) string {
+	lower := strings.ToLower(path)
+	if strings.HasSuffix(lower, ".yaml") || strings.HasSuffix(lower, ".yml") {
+		return FormatYAML
+	}
+	return FormatJSON
+}
+
+// ParseBundle decodes a JSON or YAML bundle, migrating presets from older
+// versions and checking each against the preset schema. Unknown fields are
+// rejected so typos aren't silently dropped.
+func ParseBundle(data []byte) (*Bundle, error) {
+	// YAML is a superset of JSON, so one decoder reads both. Re-encoding as
+	// JSON lets the json tags and strict field checks apply to either.
+	var generic interface{}
+	if err := yaml.Unmarshal(data, &generic); err != nil {
+		return nil, fmt.Errorf("parsing presets: %w", err)
+	}
+	jsonData, err := json.Marshal(generic)
+	if err != nil {
+		return nil, fmt.Errorf("parsing presets: %w", err)
+	}
+
+	var bundle Bundle
+	decoder := json.NewDecoder(bytes.NewReader(jsonData))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&bundle); err != nil {
+		return nil, fmt.Errorf("presets don't match the preset schema: %w", err)
+	}
+
+	if bundle.Version == 0 {
+		bundle.Version = 1
+	}
+	if bundle.Version > PresetsVersion {
+		return nil, fmt.Errorf("presets are version %d, this TabLoad only understands up to %d", bundle.Version, PresetsVersion)
+	}
+	if len(bundle.Presets) == 0 {
+		return nil, fmt.Errorf("no presets found")
+	}
+	bundle.Presets = migratePresets(bundle.Version, bundle.Presets)
+	bundle.Version = PresetsVersion
+
+	seen := make(map[string]bool)
+	for i, preset := range bundle.Presets {
+		if err := preset.Validate(); err != nil {
+			return nil, fmt.Errorf("preset %d (%s): %w", i+1, preset.Name, err)
+		}
+		if seen[preset.Name] {
+			return nil, fmt.Errorf("preset %q appears more than once", preset.Name)
+		}
+		seen[preset.Name] = true
+	}
+
+	return &bundle, nil
+}
+
+// ConflictAction is what to do when an imported preset has the same name as
+// a stored one.
+type ConflictAction int
+
+const (
+	// Skip keeps the stored preset and drops the imported one.
+	Skip ConflictAction = iota
+	// Overwrite replaces the stored preset.
+	Overwrite
+	// Rename saves the imported preset under a new, unused name.
+	Rename
+)
+
+// ParseConflictAction parses "skip", "overwrite" or "rename".
+func ParseConflictAction(s string) (ConflictAction, error) {
+	switch strings.ToLower(s) {
+	case "skip":
+		return Skip, nil
+	case "overwrite":
+		return Overwrite, nil
+	case "rename":
+		return Rename, nil
+	default:
+		return Skip, fmt.Errorf("unknown conflict action %q, expected skip, overwrite or rename", s)
+	}
+}
+
+// ImportResult lists what an import did, by preset name as saved.
+type ImportResult struct {
+	Added       []string
+	Overwritten []string
+	// Renamed maps imported names to the names they were saved under.
+	Renamed map[string]string
+	Skipped []string
+}
+
+func (r ImportResult) String() string {
+	parts := []string{fmt.Sprintf("%d added", len(r.Added)+len(r.Renamed))}
+	if len(r.Overwritten) > 0 {
+		parts = append(parts, fmt.Sprintf("%d overwritten", len(r.Overwritten)))
+	}
+	if len(r.Renamed) > 0 {
+		parts = append(parts, fmt.Sprintf("%d renamed", len(r.Renamed)))
+	}
+	if len(r.Skipped) > 0 {
+		parts = append(parts, fmt.Sprintf("%d skipped", len(r.Skipped)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ImportPresets saves presets into st. resolve is asked what to do with each
+// preset whose name is already taken.
+func ImportPresets(st PresetStore, presets []Preset, resolve func(Preset) ConflictAction) (ImportResult, error) {
+	result := ImportResult{Renamed: make(map[string]string)}
+
+	existing, err := st.Presets()
+	if err != nil {
+		return result, fmt.Errorf("loading presets: %w", err)
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, preset := range existing {
+		taken[preset.Name] = true
+	}
+
+	for _, preset := range presets {
+		if !taken[preset.Name] {
+			if err := st.SavePreset(preset); err != nil {
+				return result, fmt.Errorf("saving preset %s: %w", preset.Name, err)
+			}
+			taken[preset.Name] = true
+			result.Added = append(result.Added, preset.Name)
+			continue
+		}
+
+		switch resolve(preset) {
+		case Overwrite:
+			if err := st.SavePreset(preset); err != nil {
+				return result, fmt.Errorf("saving preset %s: %w", preset.Name, err)
+			}
+			result.Overwritten = append(result.Overwritten, preset.Name)
+		case Rename:
+			original := preset.Name
+			preset.Name = uniqueName(original, taken)
+			if err := st.SavePreset(preset); err != nil {
+				return result, fmt.Errorf("saving preset %s: %w", preset.Name, err)
+			}
+			taken[preset.Name] = true
+			result.Renamed[original] = preset.Name
+		default:
+			result.Skipped = append(result.Skipped, preset.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// uniqueName returns name with the lowest " (n)" suffix that isn't taken.
+func uniqueName(name string, taken map[string]bool) string {
+	for n := 2; ; n++ {
+		candidate := fmt.Sprintf("%s (%d)", name, n)
+		if !taken[candidate] {
+			return candidate
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseBundle(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Preset
+		wantErr string
+	}{
+		{
+			name: "json",
+			data: `{"version": 2, "presets": [{"name": "Fast", "model_id": "llama-8b", "cache_mode": "Q4"}]}`,
+			want: []Preset{{Name: "Fast", ModelID: "llama-8b", CacheMode: "Q4"}},
+		},
+		{
+			name: "yaml",
+			data: "version: 2\npresets:\n  - name: Fast\n    model_id: llama-8b\n    loras:\n      - name: style\n        scaling: 0.8\n",
+			want: []Preset{{Name: "Fast", ModelID: "llama-8b", Loras: []LoraSpec{{Name: "style", Scaling: 0.8}}}},
+		},
+		{
+			name: "unversioned presets are migrated",
+			data: `{"presets": [{"name": "llama-70b"}]}`,
+			want: []Preset{{Name: "llama-70b", ModelID: "llama-70b"}},
+		},
+		{
+			name:    "unknown field",
+			data:    `{"presets": [{"name": "Fast", "max_seq_length": 8192}]}`,
+			wantErr: "schema",
+		},
+		{
+			name:    "future version",
+			data:    `{"version": 99, "presets": [{"name": "Fast"}]}`,
+			wantErr: "version 99",
+		},
+		{
+			name:    "no presets",
+			data:    `{"version": 2, "presets": []}`,
+			wantErr: "no presets",
+		},
+		{
+			name:    "duplicate names",
+			data:    `{"version": 2, "presets": [{"name": "Fast"}, {"name": "Fast"}]}`,
+			wantErr: "more than once",
+		},
+		{
+			name:    "invalid preset",
+			data:    `{"version": 2, "presets": [{"name": "Fast", "max_seq_len": -1}]}`,
+			wantErr: "preset 1 (Fast)",
+		},
+		{
+			name:    "not yaml",
+			data:    "presets: [",
+			wantErr: "parsing presets",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bundle, err := ParseBundle([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if bundle.Version != PresetsVersion {
+				t.Errorf("version = %d, want %d", bundle.Version, PresetsVersion)
+			}
+			if !reflect.DeepEqual(bundle.Presets, tt.want) {
+				t.Errorf("presets = %+v, want %+v", bundle.Presets, tt.want)
+			}
+		})
+	}
+}
+
+func TestExportBundleRoundTrip(t *testing.T) {
+	maxSeqLen := 8192
+	presets := []Preset{{
+		Name:      "Fast",
+		ModelID:   "llama-8b",
+		MaxSeqLen: &maxSeqLen,
+		GPUSplit:  "20,24",
+		Loras:     []LoraSpec{{Name: "style", Scaling: 0.5}},
+	}}
+	for _, format := range []string{FormatJSON, FormatYAML} {
+		t.Run(format, func(t *testing.T) {
+			data, err := ExportBundle(presets, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			bundle, err := ParseBundle(data)
+			if err != nil {
+				t.Fatalf("parsing exported bundle: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(bundle.Presets, presets) {
+				t.Errorf("round trip = %+v, want %+v", bundle.Presets, presets)
+			}
+		})
+	}
+}
+
+func TestImportPresets(t *testing.T) {
+	tests := []struct {
+		name    string
+		action  ConflictAction
+		want    ImportResult
+		wantIDs map[string]string // Preset name to model ID afterwards
+	}{
+		{
+			name:    "skip",
+			action:  Skip,
+			want:    ImportResult{Added: []string{"New"}, Renamed: map[string]string{}, Skipped: []string{"Fast"}},
+			wantIDs: map[string]string{"Fast": "old", "New": "new"},
+		},
+		{
+			name:    "overwrite",
+			action:  Overwrite,
+			want:    ImportResult{Added: []string{"New"}, Renamed: map[string]string{}, Overwritten: []string{"Fast"}},
+			wantIDs: map[string]string{"Fast": "imported", "New": "new"},
+		},
+		{
+			name:    "rename",
+			action:  Rename,
+			want:    ImportResult{Added: []string{"New"}, Renamed: map[string]string{"Fast": "Fast (3)"}},
+			wantIDs: map[string]string{"Fast": "old", "Fast (2)": "taken", "Fast (3)": "imported", "New": "new"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := NewMemoryStore()
+			for _, preset := range []Preset{{Name: "Fast", ModelID: "old"}, {Name: "Fast (2)", ModelID: "taken"}} {
+				if err := st.SavePreset(preset); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			imported := []Preset{{Name: "Fast", ModelID: "imported"}, {Name: "New", ModelID: "new"}}
+			result, err := ImportPresets(st, imported, func(Preset) ConflictAction { return tt.action })
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result, tt.want) {
+				t.Errorf("result = %+v, want %+v", result, tt.want)
+			}
+			for name, modelID := range tt.wantIDs {
+				preset, err := st.Preset(name)
+				if err != nil {
+					t.Errorf("preset %s: %v", name, err)
+					continue
+				}
+				if preset.ModelID != modelID {
+					t.Errorf("preset %s has model %q, want %q", name, preset.ModelID, modelID)
+				}
+			}
+		})
+	}
+}
+
+func TestUniqueName(t *testing.T) {
+	tests := []struct {
+		name  string
+		taken []string
+		want  string
+	}{
+		{"Fast", []string{"Fast"}, "Fast (2)"},
+		{"Fast", []string{"Fast", "Fast (2)", "Fast (3)"}, "Fast (4)"},
+		{"Fast", []string{"Fast", "Fast (3)"}, "Fast (2)"},
+		{"Fast (2)", []string{"Fast (2)"}, "Fast (2) (2)"},
+	}
+	for _, tt := range tests {
+		taken := make(map[string]bool)
+		for _, name := range tt.taken {
+			taken[name] = true
+		}
+		if got := uniqueName(tt.name, taken); got != tt.want {
+			t.Errorf("uniqueName(%q, %v) = %q, want %q", tt.name, tt.taken, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,305 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/sammcj/tabload/logging"
+)
+
+// FileStore keeps presets, config and sampler profiles as JSON files in a
+// directory.
+type FileStore struct {
+	dir string
+
+	mu      sync.Mutex
+	presets []Preset // cached after the first read
+}
+
+// NewFileStore returns a store rooted at dir. The directory is created on the
+// first write.
+func NewFileStore(dir string) *FileStore {
+	return &FileStore{dir: dir}
+}
+
+// Dir returns the directory the store is rooted at.
+func (s *FileStore) Dir() string {
+	return s.dir
+}
+
+func (s *FileStore) configPath() string {
+	return filepath.Join(s.dir, "config.json")
+}
+
+func (s *FileStore) presetsPath() string {
+	return filepath.Join(s.dir, "presets.json")
+}
+
+func (s *FileStore) samplersPath() string {
+	return filepath.Join(s.dir, "samplers.json")
+}
+
+func (s *FileStore) Config() (Config, error) {
+	path := s.configPath()
+	logging.Info(fmt.Sprintf("Attempting to read config from: %s", path))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logging.Info(fmt.Sprintf("Config file not found at %s. Using defaults.", path))
+			return DefaultConfig(), nil
+		}
+		return DefaultConfig(), fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	config := DefaultConfig()
+	if err := json.Unmarshal(data, &config); err != nil {
+		return DefaultConfig(), fmt.Errorf("failed to unmarshal config: %w", err)
+	}
+
+	logging.Info("Successfully read config file")
+	return config, nil
+}
+
+func (s *FileStore) SaveConfig(config Config) error {
+	data, err := json.MarshalIndent(config, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal config: %w", err)
+	}
+
+	return s.writeFile(s.configPath(), data)
+}
+
+func (s *FileStore) Presets() ([]Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.loadPresets()
+	if err != nil {
+		return nil, err
+	}
+	return append([]Preset(nil), presets...), nil
+}
+
+func (s *FileStore) Preset(name string) (*Preset, error) {
+	presets, err := s.Presets()
+	if err != nil {
+		return nil, fmt.Errorf("error loading presets: %w", err)
+	}
+
+	for _, preset := range presets {
+		if preset.Name == name {
+			return &preset, nil
+		}
+	}
+
+	return nil, fmt.Errorf("preset not found: %s", name)
+}
+
+func (s *FileStore) SavePreset(preset Preset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.loadPresets()
+	if err != nil {
+		return fmt.Errorf("error loading existing presets: %w", err)
+	}
+
+	return s.savePresets(mergePresets(presets, []Preset{preset}))
+}
+
+func (s *FileStore) DeletePreset(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	presets, err := s.loadPresets()
+	if err != nil {
+		return fmt.Errorf("error loading existing presets: %w", err)
+	}
+
+	newPresets := make([]Preset, 0, len(presets))
+	found := false
+	for _, p := range presets {
+		if p.Name != name {
+			newPresets = append(newPresets, p)
+		} else {
+			found = true
+		}
+	}
+
+	if !found {
+		return fmt.Errorf("preset not found: %s", name)
+	}
+
+	// take a backup of the presets file before deleting
+	path := s.presetsPath()
+	if err := os.Rename(path, path+".bak"); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error taking backup of presets file: %w", err)
+	}
+
+	return s.savePresets(newPresets)
+}
+
+// loadPresets returns the cached presets, reading them from disk on first use.
+// The caller must hold s.mu.
+func (s *FileStore) loadPresets() ([]Preset, error) {
+	if s.presets != nil {
+		return s.presets, nil
+	}
+
+	path := s.presetsPath()
+	logging.Info(fmt.Sprintf("Loading presets from: %s", path))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			logging.Info("Presets file not found. Using default presets.")
+			s.presets = defaultPresets()
+			return s.presets, nil
+		}
+		return nil, fmt.Errorf("error reading presets file: %w", err)
+	}
+
+	if len(data) == 0 {
+		logging.Info("Presets file is empty. Using default presets.")
+		s.presets = defaultPresets()
+		return s.presets, nil
+	}
+
+	var presetsWrapper presetsFile
+	if err := json.Unmarshal(data, &presetsWrapper); err != nil {
+		return nil, fmt.Errorf("error unmarshalling presets: %w", err)
+	}
+	if presetsWrapper.Version == 0 {
+		// Files written before presets were versioned
+		presetsWrapper.Version = 1
+	}
+	if presetsWrapper.Version > PresetsVersion {
+		return nil, fmt.Errorf("presets file is version %d, this TabLoad only understands up to %d", presetsWrapper.Version, PresetsVersion)
+	}
+
+	presets := mergePresets(defaultPresets(), presetsWrapper.Presets)
+	if presetsWrapper.Version < PresetsVersion {
+		logging.Info(fmt.Sprintf("Migrating presets file from version %d to %d", presetsWrapper.Version, PresetsVersion))
+		if err := s.writeFile(path+fmt.Sprintf(".v%d.bak", presetsWrapper.Version), data); err != nil {
+			return nil, fmt.Errorf("error taking backup of presets file: %w", err)
+		}
+		presets = migratePresets(presetsWrapper.Version, presets)
+		if err := s.savePresets(presets); err != nil {
+			return nil, fmt.Errorf("error saving migrated presets: %w", err)
+		}
+	}
+
+	s.presets = presets
+	logging.Info(fmt.Sprintf("Loaded %d presets", len(s.presets)))
+
+	return s.presets, nil
+}
+
+// savePresets writes presets to disk and updates the cache. The caller must
+// hold s.mu.
+func (s *FileStore) savePresets(presets []Preset) error {
+	presetsWrapper := presetsFile{
+		Version: PresetsVersion,
+		Presets: presets,
+	}
+
+	data, err := json.MarshalIndent(presetsWrapper, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling presets: %w", err)
+	}
+
+	if err := s.writeFile(s.presetsPath(), data); err != nil {
+		return err
+	}
+
+	s.presets = presets
+	return nil
+}
+
+func (s *FileStore) SamplerProfiles() ([]SamplerProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.loadSamplerProfiles()
+}
+
+func (s *FileStore) SaveSamplerProfile(profile SamplerProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles, err := s.loadSamplerProfiles()
+	if err != nil {
+		return fmt.Errorf("error loading existing sampler profiles: %w", err)
+	}
+
+	return s.saveSamplerProfiles(mergeSamplerProfiles(profiles, profile))
+}
+
+func (s *FileStore) DeleteSamplerProfile(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profiles, err := s.loadSamplerProfiles()
+	if err != nil {
+		return fmt.Errorf("error loading existing sampler profiles: %w", err)
+	}
+
+	for i, profile := range profiles {
+		if profile.Name == name {
+			return s.saveSamplerProfiles(append(profiles[:i:i], profiles[i+1:]...))
+		}
+	}
+	return fmt.Errorf("sampler profile not found: %s", name)
+}
+
+// loadSamplerProfiles reads the sampler profiles file. The caller must hold
+// s.mu.
+func (s *FileStore) loadSamplerProfiles() ([]SamplerProfile, error) {
+	data, err := os.ReadFile(s.samplersPath())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("error reading sampler profiles file: %w", err)
+	}
+
+	var wrapper struct {
+		Profiles []SamplerProfile `json:"profiles"`
+	}
+	if err := json.Unmarshal(data, &wrapper); err != nil {
+		return nil, fmt.Errorf("error unmarshalling sampler profiles: %w", err)
+	}
+	return wrapper.Profiles, nil
+}
+
+// saveSamplerProfiles writes the sampler profiles file. The caller must hold
+// s.mu.
+func (s *FileStore) saveSamplerProfiles(profiles []SamplerProfile) error {
+	wrapper := struct {
+		Profiles []SamplerProfile `json:"profiles"`
+	}{
+		Profiles: profiles,
+	}
+
+	data, err := json.MarshalIndent(wrapper, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error marshalling sampler profiles: %w", err)
+	}
+
+	return s.writeFile(s.samplersPath(), data)
+}
+
+func (s *FileStore) writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("error creating directory: %w", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("error writing %s: %w", filepath.Base(path), err)
+	}
+
+	return nil
+}
@@ -0,0 +1,130 @@
+package store
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writePresetsFile writes a presets.json into a new store directory.
+func writePresetsFile(t *testing.T, data string) (*FileStore, string) {
+	t.Helper()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "presets.json")
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return NewFileStore(dir), path
+}
+
+const v1Presets = `{
+  "presets": [
+    {"name": "Default Preset", "cache_mode": "Q8"},
+    {"name": "llama-70b", "max_seq_len": 8192},
+    {"name": "qwen", "model_id": "Qwen2-7B"}
+  ]
+}`
+
+func TestMigratePresetsFromV1(t *testing.T) {
+	st, _ := writePresetsFile(t, v1Presets)
+
+	presets, err := st.Presets()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		modelID string
+	}{
+		// The default preset isn't a model, so it gets no model ID
+		{"Default Preset", ""},
+		// Unversioned presets were named after their model
+		{"llama-70b", "llama-70b"},
+		// A model ID already set is kept
+		{"qwen", "Qwen2-7B"},
+	}
+	if len(presets) != len(tests) {
+		t.Fatalf("got %d presets, want %d", len(presets), len(tests))
+	}
+	for i, tt := range tests {
+		if presets[i].Name != tt.name || presets[i].ModelID != tt.modelID {
+			t.Errorf("preset %d = %q with model %q, want %q with model %q", i, presets[i].Name, presets[i].ModelID, tt.name, tt.modelID)
+		}
+	}
+	if presets[0].CacheMode != "Q8" {
+		t.Errorf("default preset's cache mode = %q, want the saved Q8", presets[0].CacheMode)
+	}
+}
+
+func TestMigratePresetsWritesBackupAndNewVersion(t *testing.T) {
+	st, path := writePresetsFile(t, v1Presets)
+	if _, err := st.Presets(); err != nil {
+		t.Fatal(err)
+	}
+
+	backup, err := os.ReadFile(path + ".v1.bak")
+	if err != nil {
+		t.Fatalf("reading backup: %v", err)
+	}
+	if string(backup) != v1Presets {
+		t.Errorf("backup = %s, want the original file", backup)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file presetsFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatal(err)
+	}
+	if file.Version != PresetsVersion {
+		t.Errorf("migrated file is version %d, want %d", file.Version, PresetsVersion)
+	}
+
+	// Loading the migrated file again changes nothing
+	presets, err := NewFileStore(filepath.Dir(path)).Presets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if presets[1].ModelID != "llama-70b" {
+		t.Errorf("reloaded preset has model %q, want llama-70b", presets[1].ModelID)
+	}
+}
+
+func TestCurrentPresetsAreNotMigrated(t *testing.T) {
+	st, path := writePresetsFile(t, `{"version": 2, "presets": [{"name": "Fast", "model_id": "llama-8b"}]}`)
+
+	presets, err := st.Presets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(presets) != 2 || presets[1].ModelID != "llama-8b" {
+		t.Errorf("got %+v, want the default preset and Fast", presets)
+	}
+	if _, err := os.Stat(path + ".v2.bak"); !os.IsNotExist(err) {
+		t.Errorf("a current file shouldn't be backed up, stat: %v", err)
+	}
+}
+
+func TestFuturePresetsVersionIsRejected(t *testing.T) {
+	future := `{"version": 99, "presets": [{"name": "x"}]}`
+	st, path := writePresetsFile(t, future)
+
+	_, err := st.Presets()
+	if err == nil || !strings.Contains(err.Error(), "version 99") {
+		t.Fatalf("got error %v, want one naming version 99", err)
+	}
+
+	// The file is left alone
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != future {
+		t.Errorf("presets file was rewritten: %s", data)
+	}
+}
@@ -0,0 +1,100 @@
+package store
+
+import (
+	"fmt"
+	"sync"
+)
+
+// MemoryStore keeps presets, config and sampler profiles in memory. It is
+// meant for tests and for running without touching the user's files.
+type MemoryStore struct {
+	mu       sync.Mutex
+	config   Config
+	presets  []Preset
+	samplers []SamplerProfile
+}
+
+// NewMemoryStore returns a store holding the default config and presets.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		config:  DefaultConfig(),
+		presets: defaultPresets(),
+	}
+}
+
+func (s *MemoryStore) Config() (Config, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.config, nil
+}
+
+func (s *MemoryStore) SaveConfig(config Config) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.config = config
+	return nil
+}
+
+func (s *MemoryStore) Presets() ([]Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Preset(nil), s.presets...), nil
+}
+
+func (s *MemoryStore) Preset(name string) (*Preset, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, preset := range s.presets {
+		if preset.Name == name {
+			return &preset, nil
+		}
+	}
+	return nil, fmt.Errorf("preset not found: %s", name)
+}
+
+func (s *MemoryStore) SavePreset(preset Preset) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.presets = mergePresets(s.presets, []Preset{preset})
+	return nil
+}
+
+func (s *MemoryStore) DeletePreset(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, preset := range s.presets {
+		if preset.Name == name {
+			s.presets = append(s.presets[:i:i], s.presets[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("preset not found: %s", name)
+}
+
+func (s *MemoryStore) SamplerProfiles() ([]SamplerProfile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SamplerProfile(nil), s.samplers...), nil
+}
+
+func (s *MemoryStore) SaveSamplerProfile(profile SamplerProfile) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.samplers = mergeSamplerProfiles(s.samplers, profile)
+	return nil
+}
+
+func (s *MemoryStore) DeleteSamplerProfile(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, profile := range s.samplers {
+		if profile.Name == name {
+			s.samplers = append(s.samplers[:i:i], s.samplers[i+1:]...)
+			return nil
+		}
+	}
+	return fmt.Errorf("sampler profile not found: %s", name)
+}
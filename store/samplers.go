@@ -0,0 +1,79 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+
+	"gopkg.in/yaml.v3"
+)
+
+// SamplerOverride is a single sampler override in TabbyAPI's override schema.
+// Force makes the override win over values sent with a request; Additive
+// appends list values such as stop strings to the request's instead.
+type SamplerOverride struct {
+	Override interface{} `json:"override" yaml:"override"`
+	Force    bool        `json:"force" yaml:"force"`
+	Additive bool        `json:"additive,omitempty" yaml:"additive,omitempty"`
+}
+
+// SamplerProfile is a named set of sampler overrides, keyed by sampler
+// parameter, e.g. "temperature".
+type SamplerProfile struct {
+	Name      string                     `json:"name"`
+	Overrides map[string]SamplerOverride `json:"overrides"`
+}
+
+// OverrideParams builds the "overrides" body of TabbyAPI's override switch
+// request from the profile.
+func (p SamplerProfile) OverrideParams() map[string]interface{} {
+	params := make(map[string]interface{}, len(p.Overrides))
+	for key, override := range p.Overrides {
+		params[key] = override
+	}
+	return params
+}
+
+// YAML returns the profile as a TabbyAPI sampler override preset, as kept in
+// the server's sampler_overrides directory.
+func (p SamplerProfile) YAML() ([]byte, error) {
+	keys := make([]string, 0, len(p.Overrides))
+	for key := range p.Overrides {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	// Build the document by hand so keys are written in a stable order
+	doc := &yaml.Node{Kind: yaml.MappingNode}
+	for _, key := range keys {
+		var value yaml.Node
+		if err := value.Encode(p.Overrides[key]); err != nil {
+			return nil, fmt.Errorf("encoding override %s: %w", key, err)
+		}
+		doc.Content = append(doc.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: key}, &value)
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(doc); err != nil {
+		return nil, fmt.Errorf("marshalling sampler profile %s: %w", p.Name, err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshalling sampler profile %s: %w", p.Name, err)
+	}
+	return buf.Bytes(), nil
+}
+
+// mergeSamplerProfiles returns existing with profile added, replacing any
+// profile of the same name.
+func mergeSamplerProfiles(existing []SamplerProfile, profile SamplerProfile) []SamplerProfile {
+	merged := append([]SamplerProfile(nil), existing...)
+	for i := range merged {
+		if merged[i].Name == profile.Name {
+			merged[i] = profile
+			return merged
+		}
+	}
+	return append(merged, profile)
+}
@@ -0,0 +1,122 @@
+// Package store persists TabLoad's presets and configuration. It has no GUI
+// dependencies so it can be shared by the GUI, the CLI and tests.
+package store
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// PresetStore loads and saves model presets.
+type PresetStore interface {
+	// Presets returns all stored presets.
+	Presets() ([]Preset, error)
+	// Preset returns the preset with the given name.
+	Preset(name string) (*Preset, error)
+	// SavePreset adds a preset, replacing any existing preset of the same name.
+	SavePreset(preset Preset) error
+	// DeletePreset removes the preset with the given name.
+	DeletePreset(name string) error
+}
+
+// ConfigStore loads and saves the application config.
+type ConfigStore interface {
+	// Config returns the stored config, or the defaults if none is stored.
+	Config() (Config, error)
+	// SaveConfig replaces the stored config.
+	SaveConfig(config Config) error
+}
+
+// SamplerStore loads and saves named sampler override profiles.
+type SamplerStore interface {
+	// SamplerProfiles returns all stored sampler profiles.
+	SamplerProfiles() ([]SamplerProfile, error)
+	// SaveSamplerProfile adds a profile, replacing any existing profile of the
+	// same name.
+	SaveSamplerProfile(profile SamplerProfile) error
+	// DeleteSamplerProfile removes the profile with the given name.
+	DeleteSamplerProfile(name string) error
+}
+
+// Store is a PresetStore, ConfigStore and SamplerStore in one.
+type Store interface {
+	PresetStore
+	ConfigStore
+	SamplerStore
+}
+
+// DefaultDir returns the directory TabLoad keeps its files in,
+// $HOME/.config/tabload.
+func DefaultDir() string {
+	homeDir, _ := os.UserHomeDir()
+	return filepath.Join(homeDir, ".config", "tabload")
+}
+
+// DefaultConfig returns the config used when none has been saved.
+func DefaultConfig() Config {
+	return Config{
+		AutoConnect:         false,
+		LastConnectedServer: "",
+		APIURL:              "http://localhost:5000",
+		DefaultParams:       ModelParams{},
+	}
+}
+
+// PresetsVersion is the version of the presets file format this package
+// writes. Older files are migrated when they're loaded.
+//
+//   - 1 (unversioned): Preset.Name is both the preset name and the model.
+//   - 2: the model is in Preset.ModelID.
+const PresetsVersion = 2
+
+// presetsFile is the on-disk form of presets.json.
+type presetsFile struct {
+	Version int      `json:"version"`
+	Presets []Preset `json:"presets"`
+}
+
+// migratePresets upgrades presets read from a file of the given version to
+// PresetsVersion.
+func migratePresets(version int, presets []Preset) []Preset {
+	migrated := append([]Preset(nil), presets...)
+	if version < 2 {
+		defaults := make(map[string]bool)
+		for _, preset := range defaultPresets() {
+			defaults[preset.Name] = true
+		}
+		for i := range migrated {
+			if migrated[i].ModelID == "" && !defaults[migrated[i].Name] {
+				migrated[i].ModelID = migrated[i].Name
+			}
+		}
+	}
+	return migrated
+}
+
+// defaultPresets are always offered, even before the user saves any.
+func defaultPresets() []Preset {
+	return []Preset{
+		{
+			Name:           "Default Preset",
+			CacheMode:      "Q4",
+			DraftCacheMode: "Q4",
+		},
+	}
+}
+
+// mergePresets overlays loaded presets onto the defaults, keeping the order
+// they were given in and letting later presets replace earlier ones of the
+// same name.
+func mergePresets(defaults, loaded []Preset) []Preset {
+	merged := make([]Preset, 0, len(defaults)+len(loaded))
+	index := make(map[string]int)
+	for _, p := range append(append([]Preset{}, defaults...), loaded...) {
+		if i, ok := index[p.Name]; ok {
+			merged[i] = p
+			continue
+		}
+		index[p.Name] = len(merged)
+		merged = append(merged, p)
+	}
+	return merged
+}
@@ -0,0 +1,193 @@
+package store
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/sammcj/tabload/utils"
+	"gopkg.in/yaml.v3"
+)
+
+// tabbyConfig is the part of TabbyAPI's config.yml that describes the model
+// to load. Everything else in the file is ignored.
+type tabbyConfig struct {
+	Model      *tabbyModel      `yaml:"model,omitempty"`
+	DraftModel *tabbyDraftModel `yaml:"draft_model,omitempty"`
+	Lora       *tabbyLora       `yaml:"lora,omitempty"`
+}
+
+type tabbyModel struct {
+	ModelName          string    `yaml:"model_name,omitempty"`
+	MaxSeqLen          *int      `yaml:"max_seq_len,omitempty"`
+	OverrideBaseSeqLen *int      `yaml:"override_base_seq_len,omitempty"`
+	CacheSize          *int      `yaml:"cache_size,omitempty"`
+	GPUSplitAuto       *bool     `yaml:"gpu_split_auto,omitempty"`
+	AutosplitReserve   []int     `yaml:"autosplit_reserve,omitempty,flow"`
+	GPUSplit           []float64 `yaml:"gpu_split,omitempty,flow"`
+	RopeScale          *float64  `yaml:"rope_scale,omitempty"`
+	RopeAlpha          *float64  `yaml:"rope_alpha,omitempty"`
+	CacheMode          string    `yaml:"cache_mode,omitempty"`
+	ChunkSize          *int      `yaml:"chunk_size,omitempty"`
+	PromptTemplate     *string   `yaml:"prompt_template,omitempty"`
+	NumExpertsPerToken *int      `yaml:"num_experts_per_token,omitempty"`
+	Fasttensors        bool      `yaml:"fasttensors,omitempty"`
+
+	// Older TabbyAPI versions nest the draft model and LoRAs in the model
+	// section
+	Draft *tabbyDraftModel `yaml:"draft,omitempty"`
+	Lora  *tabbyLora       `yaml:"lora,omitempty"`
+}
+
+type tabbyDraftModel struct {
+	DraftModelName string   `yaml:"draft_model_name,omitempty"`
+	DraftRopeScale *float64 `yaml:"draft_rope_scale,omitempty"`
+	DraftRopeAlpha *float64 `yaml:"draft_rope_alpha,omitempty"`
+	DraftCacheMode string   `yaml:"draft_cache_mode,omitempty"`
+}
+
+type tabbyLora struct {
+	Loras []LoraSpec `yaml:"loras,omitempty"`
+}
+
+// ParseTabbyConfig reads the model, draft_model and lora sections of a
+// TabbyAPI config.yml into a preset named after the model.
+func ParseTabbyConfig(data []byte) (Preset, error) {
+	var config tabbyConfig
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return Preset{}, fmt.Errorf("parsing config.yml: %w", err)
+	}
+	if config.Model == nil {
+		return Preset{}, fmt.Errorf("config.yml has no model section")
+	}
+
+	m := config.Model
+	preset := Preset{
+		Name:               m.ModelName,
+		ModelID:            m.ModelName,
+		MaxSeqLen:          m.MaxSeqLen,
+		OverrideBaseSeqLen: m.OverrideBaseSeqLen,
+		CacheSize:          m.CacheSize,
+		RopeScale:          m.RopeScale,
+		RopeAlpha:          m.RopeAlpha,
+		CacheMode:          m.CacheMode,
+		ChunkSize:          m.ChunkSize,
+		PromptTemplate:     m.PromptTemplate,
+		NumExpertsPerToken: m.NumExpertsPerToken,
+		Fasttensors:        m.Fasttensors,
+	}
+	// TabbyAPI defaults gpu_split_auto to true
+	preset.GPUSplitAuto = m.GPUSplitAuto == nil || *m.GPUSplitAuto
+	if len(m.GPUSplit) > 0 {
+		preset.GPUSplit = joinNumbers(m.GPUSplit)
+		if m.GPUSplitAuto == nil {
+			preset.GPUSplitAuto = false
+		}
+	}
+	if len(m.AutosplitReserve) > 0 {
+		preset.AutosplitReserve = joinNumbers(m.AutosplitReserve)
+	}
+
+	draft := config.DraftModel
+	if draft == nil {
+		draft = m.Draft
+	}
+	if draft != nil && draft.DraftModelName != "" {
+		preset.DraftModelName = &draft.DraftModelName
+		preset.DraftRopeScale = draft.DraftRopeScale
+		preset.DraftRopeAlpha = draft.DraftRopeAlpha
+		preset.DraftCacheMode = draft.DraftCacheMode
+	}
+
+	lora := config.Lora
+	if lora == nil {
+		lora = m.Lora
+	}
+	if lora != nil {
+		preset.Loras = lora.Loras
+	}
+
+	if err := preset.Validate(); err != nil {
+		return Preset{}, fmt.Errorf("config.yml: %w", err)
+	}
+	return preset, nil
+}
+
+// IsTabbyConfig reports whether data looks like a TabbyAPI config.yml rather
+// than a preset bundle.
+func IsTabbyConfig(data []byte) bool {
+	var sections map[string]interface{}
+	if err := yaml.Unmarshal(data, &sections); err != nil {
+		return false
+	}
+	_, hasModel := sections["model"]
+	_, hasPresets := sections["presets"]
+	return hasModel && !hasPresets
+}
+
+// TabbyConfig returns the preset as the model, draft_model and lora sections
+// of a TabbyAPI config.yml, to paste into the server's config.
+func (p Preset) TabbyConfig() ([]byte, error) {
+	model := &tabbyModel{
+		ModelName:          p.ModelID,
+		MaxSeqLen:          p.MaxSeqLen,
+		OverrideBaseSeqLen: p.OverrideBaseSeqLen,
+		CacheSize:          p.CacheSize,
+		RopeScale:          p.RopeScale,
+		RopeAlpha:          p.RopeAlpha,
+		CacheMode:          p.CacheMode,
+		ChunkSize:          p.ChunkSize,
+		PromptTemplate:     p.PromptTemplate,
+		NumExpertsPerToken: p.NumExpertsPerToken,
+		Fasttensors:        p.Fasttensors,
+	}
+	// Spell out gpu_split_auto as TabbyAPI assumes it's on when it's missing
+	gpuSplitAuto := p.GPUSplitAuto
+	model.GPUSplitAuto = &gpuSplitAuto
+	if !p.GPUSplitAuto && p.GPUSplit != "" {
+		split, err := utils.FloatList("gpu_split", p.GPUSplit)
+		if err != nil {
+			return nil, err
+		}
+		model.GPUSplit = split
+	}
+	if p.AutosplitReserve != "" {
+		reserve, err := utils.MegabytesList("autosplit_reserve", p.AutosplitReserve)
+		if err != nil {
+			return nil, err
+		}
+		model.AutosplitReserve = reserve
+	}
+
+	config := tabbyConfig{Model: model}
+	if p.DraftModelName != nil && *p.DraftModelName != "" {
+		config.DraftModel = &tabbyDraftModel{
+			DraftModelName: *p.DraftModelName,
+			DraftRopeScale: p.DraftRopeScale,
+			DraftRopeAlpha: p.DraftRopeAlpha,
+			DraftCacheMode: p.DraftCacheMode,
+		}
+	}
+	if len(p.Loras) > 0 {
+		config.Lora = &tabbyLora{Loras: p.Loras}
+	}
+
+	var buf bytes.Buffer
+	encoder := yaml.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(config); err != nil {
+		return nil, fmt.Errorf("marshalling config.yml: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("marshalling config.yml: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func joinNumbers[T int | float64](values []T) string {
+	parts := make([]string, len(values))
+	for i, v := range values {
+		parts[i] = fmt.Sprint(v)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTabbyConfig(t *testing.T) {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+	stringPtr := func(v string) *string { return &v }
+
+	tests := []struct {
+		name    string
+		data    string
+		want    Preset
+		wantErr string
+	}{
+		{
+			name: "model only, gpu_split_auto defaults on",
+			data: "network:\n  host: 0.0.0.0\nmodel:\n  model_name: llama-8b\n  max_seq_len: 8192\n  cache_mode: Q4\n",
+			want: Preset{Name: "llama-8b", ModelID: "llama-8b", MaxSeqLen: intPtr(8192), CacheMode: "Q4", GPUSplitAuto: true},
+		},
+		{
+			name: "manual gpu split and reserve",
+			data: "model:\n  model_name: llama-70b\n  gpu_split: [20, 24.5]\n  autosplit_reserve: [96, 128]\n",
+			want: Preset{Name: "llama-70b", ModelID: "llama-70b", GPUSplit: "20,24.5", AutosplitReserve: "96,128"},
+		},
+		{
+			name: "draft_model and lora sections",
+			data: "model:\n  model_name: llama-70b\n  prompt_template: chatml\ndraft_model:\n  draft_model_name: tiny\n  draft_rope_alpha: 2.5\nlora:\n  loras:\n    - name: style\n      scaling: 0.8\n",
+			want: Preset{
+				Name: "llama-70b", ModelID: "llama-70b", GPUSplitAuto: true, PromptTemplate: stringPtr("chatml"),
+				DraftModelName: stringPtr("tiny"), DraftRopeAlpha: floatPtr(2.5),
+				Loras: []LoraSpec{{Name: "style", Scaling: 0.8}},
+			},
+		},
+		{
+			name: "older nested draft and lora",
+			data: "model:\n  model_name: llama-70b\n  gpu_split_auto: false\n  draft:\n    draft_model_name: tiny\n  lora:\n    loras:\n      - name: style\n        scaling: 1\n",
+			want: Preset{
+				Name: "llama-70b", ModelID: "llama-70b", DraftModelName: stringPtr("tiny"),
+				Loras: []LoraSpec{{Name: "style", Scaling: 1}},
+			},
+		},
+		{
+			name:    "no model section",
+			data:    "network:\n  host: 0.0.0.0\n",
+			wantErr: "no model section",
+		},
+		{
+			name:    "no model name",
+			data:    "model:\n  max_seq_len: 8192\n",
+			wantErr: "preset name cannot be empty",
+		},
+		{
+			name:    "invalid value",
+			data:    "model:\n  model_name: llama-8b\n  max_seq_len: -5\n",
+			wantErr: "config.yml",
+		},
+		{
+			name:    "not yaml",
+			data:    "model: [",
+			wantErr: "parsing config.yml",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseTabbyConfig([]byte(tt.data))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got error %v, want one containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTabbyConfigRoundTrip(t *testing.T) {
+	maxSeqLen := 32768
+	ropeAlpha := 2.5
+	draft := "tiny"
+	tests := []struct {
+		name   string
+		preset Preset
+	}{
+		{
+			name:   "auto split",
+			preset: Preset{Name: "llama-8b", ModelID: "llama-8b", MaxSeqLen: &maxSeqLen, GPUSplitAuto: true, AutosplitReserve: "96,128"},
+		},
+		{
+			name:   "manual split",
+			preset: Preset{Name: "llama-70b", ModelID: "llama-70b", GPUSplit: "20,24.5", CacheMode: "Q6"},
+		},
+		{
+			name: "draft and loras",
+			preset: Preset{
+				Name: "llama-70b", ModelID: "llama-70b", GPUSplitAuto: true,
+				DraftModelName: &draft, DraftRopeAlpha: &ropeAlpha, DraftCacheMode: "Q4",
+				Loras: []LoraSpec{{Name: "a", Scaling: 0.5}, {Name: "b", Scaling: 1}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.preset.TabbyConfig()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !IsTabbyConfig(data) {
+				t.Errorf("exported config isn't recognised as one:\n%s", data)
+			}
+			got, err := ParseTabbyConfig(data)
+			if err != nil {
+				t.Fatalf("parsing exported config: %v\n%s", err, data)
+			}
+			if !reflect.DeepEqual(got, tt.preset) {
+				t.Errorf("round trip = %+v, want %+v\n%s", got, tt.preset, data)
+			}
+		})
+	}
+}
+
+func TestTabbyConfigSpellsOutGPUSplitAuto(t *testing.T) {
+	data, err := Preset{Name: "x", ModelID: "x", GPUSplit: "20,24"}.TabbyConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"gpu_split_auto: false", "gpu_split: [20, 24]"} {
+		if !strings.Contains(string(data), want) {
+			t.Errorf("config doesn't contain %q:\n%s", want, data)
+		}
+	}
+}
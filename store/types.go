@@ -0,0 +1,283 @@
+package store
+
+import (
+	"fmt"
+
+	"github.com/sammcj/tabload/utils"
+)
+
+type Config struct {
+	AutoConnect         bool            `json:"auto_connect"`
+	LastConnectedServer string          `json:"last_connected_server"`
+	APIURL              string          `json:"api_url"`
+	DefaultParams       ModelParams     `json:"default_params"`
+	Profiles            []ServerProfile `json:"profiles,omitempty"`
+	LastProfile         string          `json:"last_profile,omitempty"`
+	// HFTokenRef names the secret holding the Hugging Face access token.
+	HFTokenRef string `json:"hf_token_ref,omitempty"`
+	// HFEndpoint is the Hugging Face Hub API used to browse repos, for a
+	// mirror or stand-in server. Empty means $HF_ENDPOINT or the public Hub.
+	HFEndpoint string `json:"hf_endpoint,omitempty"`
+}
+
+// ServerProfile is a named TabbyAPI server and the credentials to use with it.
+// The keys themselves are kept in the secrets store; the profile only holds
+// references to them.
+type ServerProfile struct {
+	Name        string `json:"name"`
+	URL         string `json:"url"`
+	AdminKeyRef string `json:"admin_key_ref,omitempty"`
+	APIKeyRef   string `json:"api_key_ref,omitempty"`
+	// AdminKey and APIKey are plaintext keys written by older versions. They
+	// are only read so MigrateSecrets can move them into the secrets store.
+	AdminKey      string     `json:"admin_key,omitempty"`
+	APIKey        string     `json:"api_key,omitempty"`
+	DefaultPreset string     `json:"default_preset,omitempty"`
+	TLS           TLSOptions `json:"tls,omitempty"`
+}
+
+// TLSOptions controls certificate verification for a server profile.
+type TLSOptions struct {
+	InsecureSkipVerify bool   `json:"insecure_skip_verify,omitempty"`
+	CACertFile         string `json:"ca_cert_file,omitempty"`
+}
+
+// Profile returns the server profile with the given name.
+func (c *Config) Profile(name string) (*ServerProfile, bool) {
+	for i := range c.Profiles {
+		if c.Profiles[i].Name == name {
+			return &c.Profiles[i], true
+		}
+	}
+	return nil, false
+}
+
+// SetProfile adds a server profile, replacing any existing one of the same
+// name.
+func (c *Config) SetProfile(profile ServerProfile) {
+	if existing, ok := c.Profile(profile.Name); ok {
+		*existing = profile
+		return
+	}
+	c.Profiles = append(c.Profiles, profile)
+}
+
+// DeleteProfile removes the server profile with the given name and reports
+// whether it existed.
+func (c *Config) DeleteProfile(name string) bool {
+	for i, profile := range c.Profiles {
+		if profile.Name == name {
+			c.Profiles = append(c.Profiles[:i:i], c.Profiles[i+1:]...)
+			if c.LastProfile == name {
+				c.LastProfile = ""
+			}
+			return true
+		}
+	}
+	return false
+}
+
+// MigrateSecrets moves plaintext profile keys into the secrets store through
+// save, which is given a reference name and the secret, and replaces them with
+// references. It reports whether anything was migrated, in which case the
+// config should be saved.
+func (c *Config) MigrateSecrets(save func(ref, secret string) error, ref func(profile, key string) string) (bool, error) {
+	migrated := false
+	for i := range c.Profiles {
+		profile := &c.Profiles[i]
+		if profile.AdminKey != "" {
+			adminRef := ref(profile.Name, "admin_key")
+			if err := save(adminRef, profile.AdminKey); err != nil {
+				return migrated, fmt.Errorf("migrating admin key of profile %s: %w", profile.Name, err)
+			}
+			profile.AdminKeyRef = adminRef
+			profile.AdminKey = ""
+			migrated = true
+		}
+		if profile.APIKey != "" {
+			apiRef := ref(profile.Name, "api_key")
+			if err := save(apiRef, profile.APIKey); err != nil {
+				return migrated, fmt.Errorf("migrating API key of profile %s: %w", profile.Name, err)
+			}
+			profile.APIKeyRef = apiRef
+			profile.APIKey = ""
+			migrated = true
+		}
+	}
+	return migrated, nil
+}
+
+// ProfileNames returns the names of all server profiles, in order.
+func (c *Config) ProfileNames() []string {
+	names := make([]string, len(c.Profiles))
+	for i, profile := range c.Profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+type ModelParams struct {
+	MaxSeqLen          *int     `json:"max_seq_len,omitempty"`
+	OverrideBaseSeqLen *int     `json:"override_base_seq_len,omitempty"`
+	CacheSize          *int     `json:"cache_size,omitempty"`
+	GPUSplitAuto       bool     `json:"gpu_split_auto,omitempty"`
+	GPUSplit           string   `json:"gpu_split,omitempty"`
+	RopeScale          *float64 `json:"rope_scale,omitempty"`
+	RopeAlpha          *float64 `json:"rope_alpha,omitempty"`
+	CacheMode          string   `json:"cache_mode,omitempty"`
+	PromptTemplate     *string  `json:"prompt_template,omitempty"`
+	NumExpertsPerToken *int     `json:"num_experts_per_token,omitempty"`
+	DraftModelName     *string  `json:"draft_model_name,omitempty"`
+	DraftRopeScale     *float64 `json:"draft_rope_scale,omitempty"`
+	DraftRopeAlpha     *float64 `json:"draft_rope_alpha,omitempty"`
+	DraftCacheMode     string   `json:"draft_cache_mode,omitempty"`
+	Fasttensors        bool     `json:"fasttensors,omitempty"`
+	AutosplitReserve   string   `json:"autosplit_reserve,omitempty"`
+	ChunkSize          *int     `json:"chunk_size,omitempty"`
+}
+
+// Preset returns a preset loading modelID with these params.
+func (m ModelParams) Preset(name, modelID string) Preset {
+	return Preset{
+		Name:               name,
+		ModelID:            modelID,
+		MaxSeqLen:          m.MaxSeqLen,
+		OverrideBaseSeqLen: m.OverrideBaseSeqLen,
+		CacheSize:          m.CacheSize,
+		GPUSplitAuto:       m.GPUSplitAuto,
+		GPUSplit:           m.GPUSplit,
+		RopeScale:          m.RopeScale,
+		RopeAlpha:          m.RopeAlpha,
+		CacheMode:          m.CacheMode,
+		PromptTemplate:     m.PromptTemplate,
+		NumExpertsPerToken: m.NumExpertsPerToken,
+		DraftModelName:     m.DraftModelName,
+		DraftRopeScale:     m.DraftRopeScale,
+		DraftRopeAlpha:     m.DraftRopeAlpha,
+		DraftCacheMode:     m.DraftCacheMode,
+		Fasttensors:        m.Fasttensors,
+		AutosplitReserve:   m.AutosplitReserve,
+		ChunkSize:          m.ChunkSize,
+	}
+}
+
+// Preset is a saved model load configuration: the model and its load params,
+// the LoRAs to stack on it and the sampler override to switch to.
+type Preset struct {
+	// Name identifies the preset. Before version 2 of the presets file it
+	// was also the model to load.
+	Name               string     `json:"name"`
+	ModelID            string     `json:"model_id,omitempty"`
+	MaxSeqLen          *int       `json:"max_seq_len,omitempty"`
+	OverrideBaseSeqLen *int       `json:"override_base_seq_len,omitempty"`
+	CacheSize          *int       `json:"cache_size,omitempty"`
+	GPUSplitAuto       bool       `json:"gpu_split_auto,omitempty"`
+	GPUSplit           string     `json:"gpu_split,omitempty"`
+	RopeScale          *float64   `json:"rope_scale,omitempty"`
+	RopeAlpha          *float64   `json:"rope_alpha,omitempty"`
+	CacheMode          string     `json:"cache_mode,omitempty"`
+	PromptTemplate     *string    `json:"prompt_template,omitempty"`
+	NumExpertsPerToken *int       `json:"num_experts_per_token,omitempty"`
+	DraftModelName     *string    `json:"draft_model_name,omitempty"`
+	DraftRopeScale     *float64   `json:"draft_rope_scale,omitempty"`
+	DraftRopeAlpha     *float64   `json:"draft_rope_alpha,omitempty"`
+	DraftCacheMode     string     `json:"draft_cache_mode,omitempty"`
+	Fasttensors        bool       `json:"fasttensors,omitempty"`
+	AutosplitReserve   string     `json:"autosplit_reserve,omitempty"`
+	ChunkSize          *int       `json:"chunk_size,omitempty"`
+	Loras              []LoraSpec `json:"loras,omitempty"`
+	// SamplerOverride names a TabLoad sampler profile, or failing that a
+	// sampler override preset on the server.
+	SamplerOverride string `json:"sampler_override,omitempty"`
+	Notes           string `json:"notes,omitempty"`
+}
+
+// LoraSpec is a LoRA in a preset's stack and the scaling to load it with.
+type LoraSpec struct {
+	Name    string  `json:"name"`
+	Scaling float64 `json:"scaling"`
+}
+
+// LoraLists splits the preset's LoRA stack into the names and scalings
+// /v1/lora/load takes.
+func (p Preset) LoraLists() ([]string, []float64) {
+	names := make([]string, len(p.Loras))
+	scalings := make([]float64, len(p.Loras))
+	for i, lora := range p.Loras {
+		names[i] = lora.Name
+		scalings[i] = lora.Scaling
+	}
+	return names, scalings
+}
+
+// LoadParams converts the preset into a /v1/model/load request body.
+func (p Preset) LoadParams() (map[string]interface{}, error) {
+	params := map[string]interface{}{
+		"name": p.ModelID,
+	}
+
+	if p.MaxSeqLen != nil {
+		params["max_seq_len"] = *p.MaxSeqLen
+	}
+	if p.OverrideBaseSeqLen != nil {
+		params["override_base_seq_len"] = *p.OverrideBaseSeqLen
+	}
+	if p.CacheSize != nil {
+		params["cache_size"] = *p.CacheSize
+	}
+	if p.GPUSplitAuto {
+		params["gpu_split_auto"] = true
+	} else if p.GPUSplit != "" {
+		split, err := utils.FloatList("gpu_split", p.GPUSplit)
+		if err != nil {
+			return nil, err
+		}
+		params["gpu_split"] = split
+	}
+	if p.RopeScale != nil {
+		params["rope_scale"] = *p.RopeScale
+	}
+	if p.RopeAlpha != nil {
+		params["rope_alpha"] = *p.RopeAlpha
+	}
+	if p.CacheMode != "" {
+		params["cache_mode"] = p.CacheMode
+	}
+	if p.PromptTemplate != nil && *p.PromptTemplate != "" {
+		params["prompt_template"] = *p.PromptTemplate
+	}
+	if p.NumExpertsPerToken != nil {
+		params["num_experts_per_token"] = *p.NumExpertsPerToken
+	}
+	if p.Fasttensors {
+		params["fasttensors"] = true
+	}
+	if p.AutosplitReserve != "" {
+		reserve, err := utils.MegabytesList("autosplit_reserve", p.AutosplitReserve)
+		if err != nil {
+			return nil, err
+		}
+		params["autosplit_reserve"] = reserve
+	}
+	if p.ChunkSize != nil {
+		params["chunk_size"] = *p.ChunkSize
+	}
+
+	if p.DraftModelName != nil && *p.DraftModelName != "" {
+		draftParams := map[string]interface{}{
+			"draft_model_name": *p.DraftModelName,
+		}
+		if p.DraftRopeScale != nil {
+			draftParams["draft_rope_scale"] = *p.DraftRopeScale
+		}
+		if p.DraftRopeAlpha != nil {
+			draftParams["draft_rope_alpha"] = *p.DraftRopeAlpha
+		}
+		if p.DraftCacheMode != "" {
+			draftParams["draft_cache_mode"] = p.DraftCacheMode
+		}
+		params["draft"] = draftParams
+	}
+
+	return params, nil
+}
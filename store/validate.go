@@ -0,0 +1,169 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sammcj/tabload/utils"
+)
+
+// Limits on load parameters. TabbyAPI allocates the cache and processes
+// prompts in pages of 256 tokens.
+const (
+	cachePageSize      = 256
+	maxRopeScale       = 32
+	maxRopeAlpha       = 100
+	maxExpertsPerToken = 64
+)
+
+// cacheModes are the cache modes TabbyAPI accepts.
+var cacheModes = []string{"Q4", "Q6", "Q8", "FP16"}
+
+// FieldError is a problem with one load parameter. Field is the parameter's
+// path in the load request, e.g. "max_seq_len" or "draft.draft_rope_scale".
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+// ValidationErrors lists every problem found with a preset's load
+// parameters, so they can all be shown at once.
+type ValidationErrors []FieldError
+
+func (e ValidationErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fmt.Sprintf("%s: %s", fieldErr.Field, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// FieldErrors maps field paths to their messages, like api.APIError's.
+func (e ValidationErrors) FieldErrors() map[string]string {
+	fields := make(map[string]string, len(e))
+	for _, fieldErr := range e {
+		if _, ok := fields[fieldErr.Field]; !ok {
+			fields[fieldErr.Field] = fieldErr.Message
+		}
+	}
+	return fields
+}
+
+// Add records a problem with field.
+func (e *ValidationErrors) Add(field, format string, args ...interface{}) {
+	*e = append(*e, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// AddParseError records a value that didn't parse against its field, taken
+// from err if it's a *utils.ParseError.
+func (e *ValidationErrors) AddParseError(err error) {
+	var parseErr *utils.ParseError
+	if errors.As(err, &parseErr) {
+		e.Add(parseErr.Field, "%s", parseErr.Problem())
+		return
+	}
+	e.Add("", "%v", err)
+}
+
+// Validate checks the preset is complete and its load parameters are ones
+// TabbyAPI will accept. The error is a ValidationErrors.
+func (p Preset) Validate() error {
+	var errs ValidationErrors
+	if strings.TrimSpace(p.Name) == "" {
+		errs.Add("preset_name", "preset name cannot be empty")
+	}
+	errs = append(errs, p.ValidateLoadParams()...)
+	for _, lora := range p.Loras {
+		if lora.Name == "" {
+			errs.Add("loras", "LoRA with no name")
+		} else if lora.Scaling < 0 {
+			errs.Add("loras", "LoRA %s has negative scaling %g", lora.Name, lora.Scaling)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// ValidateLoadParams checks the values of the preset's load parameters.
+func (p Preset) ValidateLoadParams() ValidationErrors {
+	var errs ValidationErrors
+
+	positive := func(field string, value *int) bool {
+		if value != nil && *value <= 0 {
+			errs.Add(field, "must be a positive number, got %d", *value)
+			return false
+		}
+		return value != nil
+	}
+	multipleOfPage := func(field string, value *int) {
+		if positive(field, value) && *value%cachePageSize != 0 {
+			errs.Add(field, "must be a multiple of %d, e.g. %d", cachePageSize, roundUp(*value, cachePageSize))
+		}
+	}
+	inRange := func(field string, value *float64, max float64) {
+		if value != nil && (*value <= 0 || *value > max) {
+			errs.Add(field, "must be above 0 and at most %g, got %g", max, *value)
+		}
+	}
+	cacheMode := func(field, mode string) {
+		if mode == "" {
+			return
+		}
+		for _, allowed := range cacheModes {
+			if mode == allowed {
+				return
+			}
+		}
+		errs.Add(field, "must be one of %s, got %q", strings.Join(cacheModes, ", "), mode)
+	}
+
+	positive("max_seq_len", p.MaxSeqLen)
+	positive("override_base_seq_len", p.OverrideBaseSeqLen)
+	multipleOfPage("cache_size", p.CacheSize)
+	if p.CacheSize != nil && p.MaxSeqLen != nil && *p.CacheSize > 0 && *p.CacheSize < *p.MaxSeqLen {
+		errs.Add("cache_size", "must be at least the max sequence length (%d)", *p.MaxSeqLen)
+	}
+	multipleOfPage("chunk_size", p.ChunkSize)
+
+	inRange("rope_scale", p.RopeScale, maxRopeScale)
+	inRange("rope_alpha", p.RopeAlpha, maxRopeAlpha)
+	cacheMode("cache_mode", p.CacheMode)
+
+	if !p.GPUSplitAuto && p.GPUSplit != "" {
+		split, err := utils.FloatList("gpu_split", p.GPUSplit)
+		if err != nil {
+			errs.AddParseError(err)
+		}
+		for i, gb := range split {
+			if gb <= 0 {
+				errs.Add("gpu_split", "GPU %d must get more than 0 GB", i)
+				break
+			}
+		}
+	}
+	if p.AutosplitReserve != "" {
+		if _, err := utils.MegabytesList("autosplit_reserve", p.AutosplitReserve); err != nil {
+			errs.AddParseError(err)
+		}
+	}
+
+	if n := p.NumExpertsPerToken; n != nil && (*n < 1 || *n > maxExpertsPerToken) {
+		errs.Add("num_experts_per_token", "must be between 1 and %d, got %d", maxExpertsPerToken, *n)
+	}
+
+	if p.DraftModelName != nil && *p.DraftModelName != "" {
+		inRange("draft.draft_rope_scale", p.DraftRopeScale, maxRopeScale)
+		inRange("draft.draft_rope_alpha", p.DraftRopeAlpha, maxRopeAlpha)
+		cacheMode("draft.draft_cache_mode", p.DraftCacheMode)
+	}
+
+	return errs
+}
+
+func roundUp(value, multiple int) int {
+	return (value + multiple - 1) / multiple * multiple
+}
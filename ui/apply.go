@@ -0,0 +1,116 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/apply"
+	"github.com/sammcj/tabload/logging"
+)
+
+// handleApplyPreset puts the selected preset into effect on the server in
+// one go, showing each step and rolling back if one fails.
+func (t *TabLoad) handleApplyPreset() {
+	selected := t.presetDropdown.Selected
+	if selected == "" || selected == "(Select one)" {
+		dialog.ShowInformation("Apply Preset", "Please select a preset to apply", t.window)
+		return
+	}
+
+	preset, err := t.loadPresetFromStorage(selected)
+	if err != nil {
+		logging.Error("Failed to load preset", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(t.requestContext())
+
+	stepsBox := container.NewVBox()
+	stepLabels := make(map[int]*widget.Label)
+	progressBar := widget.NewProgressBar()
+	closeButton := widget.NewButton("Close", nil)
+	closeButton.Hide()
+	cancelButton := widget.NewButton("Cancel", func() {
+		logging.Info("Cancelling preset apply")
+		cancel()
+	})
+
+	content := container.NewVBox(
+		widget.NewLabelWithStyle(fmt.Sprintf("Applying %s", preset.Name), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		stepsBox,
+		progressBar,
+		container.NewHBox(cancelButton, closeButton),
+	)
+	progressDialog := dialog.NewCustomWithoutButtons("Apply Preset", content, t.window)
+	closeButton.OnTapped = progressDialog.Hide
+	progressDialog.Resize(fyne.NewSize(500, 250))
+	progressDialog.Show()
+
+	applier := apply.New(t.client, t.samplerStore)
+	applier.OnStep = func(step apply.Step) {
+		label, ok := stepLabels[step.Index]
+		if !ok {
+			label = widget.NewLabel("")
+			stepLabels[step.Index] = label
+			stepsBox.Add(label)
+		}
+		text := fmt.Sprintf("%s %s", stepIcon(step.Status), step.Name)
+		if step.Err != nil {
+			text += ": " + step.Err.Error()
+		}
+		label.SetText(text)
+	}
+	applier.OnLoadProgress = func(progress api.LoadProgress) {
+		progressBar.SetValue(progress.Fraction())
+	}
+
+	t.loadModelButton.Disable()
+	go func() {
+		defer cancel()
+
+		_, err := applier.Apply(ctx, *preset)
+
+		t.loadModelButton.Enable()
+		cancelButton.Hide()
+		closeButton.Show()
+
+		switch {
+		case err == nil:
+			progressBar.SetValue(1)
+			logging.Info(fmt.Sprintf("Preset '%s' applied", preset.Name))
+		case errors.Is(err, context.Canceled):
+			logging.Info("Preset apply cancelled and rolled back")
+		default:
+			logging.Error("Failed to apply preset", err)
+			dialog.ShowError(err, t.window)
+		}
+
+		t.refreshCurrentModel()
+		t.refreshCurrentLoras()
+		t.refreshOverrides()
+	}()
+}
+
+func stepIcon(status apply.Status) string {
+	switch status {
+	case apply.Running:
+		return "…"
+	case apply.Done:
+		return "✓"
+	case apply.Skipped:
+		return "–"
+	case apply.Failed, apply.RollbackFailed:
+		return "✗"
+	case apply.RolledBack:
+		return "↺"
+	default:
+		return "○"
+	}
+}
@@ -0,0 +1,246 @@
+package ui
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+var bundleExtensions = []string{".json", ".yaml", ".yml"}
+
+// handleExportPresets asks which presets to export and in which format, then
+// where to save them.
+func (t *TabLoad) handleExportPresets() {
+	presets, err := t.loadPresetsFromStorage()
+	if err != nil {
+		logging.Error("Error loading presets", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	names := make([]string, len(presets))
+	for i, preset := range presets {
+		names[i] = preset.Name
+	}
+	selection := widget.NewCheckGroup(names, nil)
+	if selected := t.presetDropdown.Selected; selected != "" && selected != "(Select one)" {
+		selection.SetSelected([]string{selected})
+	}
+	selectAll := widget.NewButton("Select All", func() {
+		selection.SetSelected(names)
+	})
+	format := widget.NewRadioGroup([]string{"JSON", "YAML"}, nil)
+	format.Horizontal = true
+	format.SetSelected("JSON")
+
+	dialog.ShowForm("Export Presets", "Export", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Presets", container.NewVBox(container.NewVScroll(selection), selectAll)),
+		widget.NewFormItem("Format", format),
+	}, func(export bool) {
+		if !export {
+			return
+		}
+		if len(selection.Selected) == 0 {
+			dialog.ShowInformation("Export Presets", "Please select at least one preset to export", t.window)
+			return
+		}
+
+		chosen := make(map[string]bool, len(selection.Selected))
+		for _, name := range selection.Selected {
+			chosen[name] = true
+		}
+		var exported []store.Preset
+		for _, preset := range presets {
+			if chosen[preset.Name] {
+				exported = append(exported, preset)
+			}
+		}
+
+		t.saveBundle(exported, strings.ToLower(format.Selected))
+	}, t.window)
+}
+
+func (t *TabLoad) saveBundle(presets []store.Preset, format string) {
+	data, err := store.ExportBundle(presets, format)
+	if err != nil {
+		logging.Error("Failed to export presets", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write(data); err != nil {
+			logging.Error("Failed to export presets", err)
+			dialog.ShowError(fmt.Errorf("exporting presets: %w", err), t.window)
+			return
+		}
+		logging.Info(fmt.Sprintf("Exported %d presets to %s", len(presets), writer.URI().Path()))
+	}, t.window)
+
+	fileName := "tabload-presets." + format
+	if len(presets) == 1 {
+		fileName = presets[0].Name + "." + format
+	}
+	saveDialog.SetFileName(fileName)
+	saveDialog.Show()
+}
+
+func (t *TabLoad) handleImportPresets() {
+	openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		t.importPresetFile(reader.URI().Name(), reader)
+	}, t.window)
+	openDialog.SetFilter(storage.NewExtensionFileFilter(bundleExtensions))
+	openDialog.Show()
+}
+
+// handlePresetsDropped imports preset files dropped onto the window.
+func (t *TabLoad) handlePresetsDropped(uris []fyne.URI) {
+	for _, uri := range uris {
+		if !hasBundleExtension(uri.Extension()) {
+			logging.Warn(fmt.Sprintf("Ignoring dropped file %s, not a JSON or YAML preset file", uri.Name()))
+			continue
+		}
+		reader, err := storage.Reader(uri)
+		if err != nil {
+			logging.Error("Failed to open dropped file", err)
+			dialog.ShowError(fmt.Errorf("opening %s: %w", uri.Name(), err), t.window)
+			continue
+		}
+		t.importPresetFile(uri.Name(), reader)
+		reader.Close()
+	}
+}
+
+func hasBundleExtension(ext string) bool {
+	for _, allowed := range bundleExtensions {
+		if strings.EqualFold(ext, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+// importPresetFile imports a preset bundle or a TabbyAPI config.yml.
+func (t *TabLoad) importPresetFile(name string, r io.Reader) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("reading %s: %w", name, err), t.window)
+		return
+	}
+
+	if store.IsTabbyConfig(data) {
+		t.importTabbyConfig(name, data)
+		return
+	}
+	t.importBundle(name, data)
+}
+
+// importBundle validates a bundle and, once the user has chosen what to do
+// with any presets whose names are taken, saves it.
+func (t *TabLoad) importBundle(name string, data []byte) {
+	bundle, err := store.ParseBundle(data)
+	if err != nil {
+		logging.Error("Invalid preset file "+name, err)
+		dialog.ShowError(fmt.Errorf("%s: %w", name, err), t.window)
+		return
+	}
+
+	existing, err := t.loadPresetsFromStorage()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	taken := make(map[string]bool, len(existing))
+	for _, preset := range existing {
+		taken[preset.Name] = true
+	}
+	var conflicts []string
+	for _, preset := range bundle.Presets {
+		if taken[preset.Name] {
+			conflicts = append(conflicts, preset.Name)
+		}
+	}
+
+	t.resolveConflicts(conflicts, make(map[string]store.ConflictAction), func(actions map[string]store.ConflictAction) {
+		result, err := store.ImportPresets(t.presetStore, bundle.Presets, func(preset store.Preset) store.ConflictAction {
+			return actions[preset.Name]
+		})
+		t.refreshPresetList()
+		if err != nil {
+			logging.Error("Failed to import presets", err)
+			dialog.ShowError(err, t.window)
+			return
+		}
+		logging.Info(fmt.Sprintf("Imported presets from %s: %s", name, result))
+		dialog.ShowInformation("Import Presets", fmt.Sprintf("Imported presets from %s: %s", name, result), t.window)
+	})
+}
+
+// resolveConflicts asks, one preset at a time, whether to rename, overwrite
+// or skip each conflicting preset, then calls done with the answers.
+func (t *TabLoad) resolveConflicts(conflicts []string, actions map[string]store.ConflictAction, done func(map[string]store.ConflictAction)) {
+	if len(conflicts) == 0 {
+		done(actions)
+		return
+	}
+
+	name := conflicts[0]
+	applyToRest := widget.NewCheck(fmt.Sprintf("Do the same for the other %d conflicts", len(conflicts)-1), nil)
+	if len(conflicts) == 1 {
+		applyToRest.Hide()
+	}
+
+	var conflictDialog dialog.Dialog
+	choose := func(action store.ConflictAction) func() {
+		return func() {
+			conflictDialog.Hide()
+			remaining := conflicts[1:]
+			if applyToRest.Checked {
+				for _, other := range remaining {
+					actions[other] = action
+				}
+				remaining = nil
+			}
+			actions[name] = action
+			t.resolveConflicts(remaining, actions, done)
+		}
+	}
+
+	content := container.NewVBox(
+		widget.NewLabel(fmt.Sprintf("A preset named %q already exists.", name)),
+		applyToRest,
+		container.NewHBox(
+			widget.NewButton("Rename", choose(store.Rename)),
+			widget.NewButton("Overwrite", choose(store.Overwrite)),
+			widget.NewButton("Skip", choose(store.Skip)),
+		),
+	)
+	conflictDialog = dialog.NewCustomWithoutButtons("Preset Already Exists", content, t.window)
+	conflictDialog.Show()
+}
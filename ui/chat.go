@@ -0,0 +1,235 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+)
+
+// chatTranscript is the format chat transcripts are saved in.
+type chatTranscript struct {
+	Model        string                 `json:"model,omitempty"`
+	SavedAt      time.Time              `json:"saved_at"`
+	SystemPrompt string                 `json:"system_prompt,omitempty"`
+	Sampling     map[string]interface{} `json:"sampling,omitempty"`
+	Messages     []api.ChatMessage      `json:"messages"`
+}
+
+func (t *TabLoad) buildChatTab() fyne.CanvasObject {
+	t.chatSystemPromptEntry = widget.NewMultiLineEntry()
+	t.chatSystemPromptEntry.SetPlaceHolder("System prompt (optional)")
+	t.chatSystemPromptEntry.SetMinRowsVisible(2)
+
+	t.chatMessages = container.NewVBox()
+	t.chatScroll = container.NewVScroll(t.chatMessages)
+
+	t.chatInputEntry = widget.NewMultiLineEntry()
+	t.chatInputEntry.SetPlaceHolder("Message")
+	t.chatInputEntry.SetMinRowsVisible(3)
+
+	t.chatSendButton = widget.NewButton("Send", t.handleChatSend)
+	t.chatStopButton = widget.NewButton("Stop", t.handleChatStop)
+	t.chatStopButton.Disable()
+	t.chatRegenerateButton = widget.NewButton("Regenerate", t.handleChatRegenerate)
+	clearButton := widget.NewButton("Clear", t.handleChatClear)
+	saveButton := widget.NewButton("Save Transcript", t.handleChatSave)
+
+	t.chatStatsLabel = widget.NewLabel("")
+
+	top := container.NewVBox(widget.NewLabel("System Prompt:"), t.chatSystemPromptEntry)
+	bottom := container.NewVBox(
+		t.chatInputEntry,
+		container.NewHBox(t.chatSendButton, t.chatStopButton, t.chatRegenerateButton, clearButton, saveButton),
+		t.chatStatsLabel,
+	)
+
+	return container.NewBorder(top, bottom, nil, nil, t.chatScroll)
+}
+
+func (t *TabLoad) handleChatSend() {
+	text := strings.TrimSpace(t.chatInputEntry.Text)
+	if text == "" {
+		return
+	}
+
+	t.chatInputEntry.SetText("")
+	t.chatMu.Lock()
+	t.chatHistory = append(t.chatHistory, api.ChatMessage{Role: api.RoleUser, Content: text})
+	t.chatMu.Unlock()
+	t.addChatMessage(api.RoleUser, text)
+	t.generateChatReply()
+}
+
+// handleChatRegenerate drops the last reply and asks for a new one.
+func (t *TabLoad) handleChatRegenerate() {
+	t.chatMu.Lock()
+	if n := len(t.chatHistory); n > 0 && t.chatHistory[n-1].Role == api.RoleAssistant {
+		t.chatHistory = t.chatHistory[:n-1]
+		t.chatMessages.Remove(t.chatMessages.Objects[len(t.chatMessages.Objects)-1])
+	}
+	empty := len(t.chatHistory) == 0
+	t.chatMu.Unlock()
+	if empty {
+		return
+	}
+	t.generateChatReply()
+}
+
+func (t *TabLoad) handleChatStop() {
+	if t.cancelChat != nil {
+		t.cancelChat()
+	}
+}
+
+// handleChatClear cancels any reply being generated and empties the
+// conversation. The cancelled reply is dropped rather than kept as a stopped
+// one would be.
+func (t *TabLoad) handleChatClear() {
+	t.handleChatStop()
+	t.chatMu.Lock()
+	t.chatHistory = nil
+	t.chatCleared++
+	t.chatMu.Unlock()
+	t.chatMessages.RemoveAll()
+	t.chatStatsLabel.SetText("")
+}
+
+// generateChatReply streams the model's reply to the conversation so far into
+// a new message.
+func (t *TabLoad) generateChatReply() {
+	params, err := t.samplingParams()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.chatMu.Lock()
+	messages := append([]api.ChatMessage(nil), t.chatHistory...)
+	cleared := t.chatCleared
+	t.chatMu.Unlock()
+	if systemPrompt := strings.TrimSpace(t.chatSystemPromptEntry.Text); systemPrompt != "" {
+		messages = append([]api.ChatMessage{{Role: api.RoleSystem, Content: systemPrompt}}, messages...)
+	}
+
+	ctx, cancel := context.WithCancel(t.requestContext())
+	t.cancelChat = cancel
+	t.setChatGenerating(true)
+	t.chatStatsLabel.SetText("Generating...")
+
+	reply := t.addChatMessage(api.RoleAssistant, "")
+	var content strings.Builder
+
+	go func() {
+		defer cancel()
+
+		result, err := t.client.ChatCompletionStream(ctx, messages, params, func(delta string) {
+			content.WriteString(delta)
+			reply.SetText(content.String())
+			t.chatScroll.ScrollToBottom()
+		})
+		t.setChatGenerating(false)
+
+		stopped := errors.Is(err, context.Canceled)
+		switch {
+		case err == nil:
+			t.chatStatsLabel.SetText(result.Stats.String())
+		case stopped:
+			t.chatStatsLabel.SetText("Stopped: " + result.Stats.String())
+		default:
+			logging.Error("Chat completion failed", err)
+			t.chatStatsLabel.SetText("Error: " + err.Error())
+		}
+
+		// Keep whatever was generated, including a reply that was stopped,
+		// unless the chat has been cleared since
+		t.chatMu.Lock()
+		defer t.chatMu.Unlock()
+		if result == nil || result.Message.Content == "" || cleared != t.chatCleared {
+			t.chatMessages.Remove(reply)
+			return
+		}
+		t.chatHistory = append(t.chatHistory, result.Message)
+	}()
+}
+
+func (t *TabLoad) setChatGenerating(generating bool) {
+	if generating {
+		t.chatSendButton.Disable()
+		t.chatRegenerateButton.Disable()
+		t.chatStopButton.Enable()
+		return
+	}
+	t.chatSendButton.Enable()
+	t.chatRegenerateButton.Enable()
+	t.chatStopButton.Disable()
+}
+
+// addChatMessage adds a message to the conversation view and returns the
+// label holding its text, so a streamed reply can be filled in.
+func (t *TabLoad) addChatMessage(role, text string) *widget.Label {
+	content := widget.NewLabel(text)
+	content.Wrapping = fyne.TextWrapWord
+
+	roleLabel := widget.NewLabelWithStyle(strings.ToUpper(role[:1])+role[1:]+":", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	t.chatMessages.Add(container.NewBorder(nil, nil, roleLabel, nil, content))
+	t.chatScroll.ScrollToBottom()
+	return content
+}
+
+func (t *TabLoad) handleChatSave() {
+	t.chatMu.Lock()
+	history := append([]api.ChatMessage(nil), t.chatHistory...)
+	t.chatMu.Unlock()
+	if len(history) == 0 {
+		dialog.ShowInformation("Save Transcript", "There is nothing to save yet", t.window)
+		return
+	}
+
+	transcript := chatTranscript{
+		SavedAt:      time.Now(),
+		SystemPrompt: strings.TrimSpace(t.chatSystemPromptEntry.Text),
+		Messages:     history,
+	}
+	if t.currentModelLabel != nil {
+		transcript.Model = t.currentModelLabel.Text
+	}
+	if params, err := t.samplingParams(); err == nil {
+		transcript.Sampling = params
+	}
+
+	data, err := json.MarshalIndent(transcript, "", "  ")
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("marshalling transcript: %w", err), t.window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write(data); err != nil {
+			logging.Error("Failed to save transcript", err)
+			dialog.ShowError(fmt.Errorf("saving transcript: %w", err), t.window)
+			return
+		}
+		logging.Info("Chat transcript saved to " + writer.URI().Path())
+	}, t.window)
+	saveDialog.SetFileName(fmt.Sprintf("chat-%s.json", time.Now().Format("20060102-150405")))
+	saveDialog.Show()
+}
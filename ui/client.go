@@ -1,6 +1,7 @@
 package ui
 
 import (
+	"context"
 	"fmt"
 
 	"fyne.io/fyne/v2"
@@ -23,32 +24,91 @@ func (t *TabLoad) handleConnect() {
 
 	t.client.BaseURL = url
 	t.client.AdminKey = key
+	t.client.APIKey = t.apiKeyEntry.Text
+	if err := t.client.ConfigureTLS(t.tlsOptionsFromFields()); err != nil {
+		logging.Error("Invalid TLS settings", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+	profileName := t.selectedProfileName()
+
+	// Drop anything still in flight against the previous server
+	ctx := t.resetRequestContext()
+	t.setConnectionStatus("Connecting to "+url+"...", "TabLoad (connecting to "+url+")")
 
 	go func() {
-		err := t.refreshData()
+		err := t.refreshData(ctx)
+
+		// Disconnected, or connected elsewhere, while refreshing: the newer
+		// connection owns the status now
+		if ctx.Err() != nil {
+			logging.Info(fmt.Sprintf("Connection to %s was replaced before it completed", url))
+			return
+		}
 		if err != nil {
 			logging.Error(fmt.Sprintf("Error refreshing data for %s", url), err)
-			func() {
-				dialog.ShowError(err, t.window)
-				t.connectionStatus.SetText("Connection failed")
-			}()
+			dialog.ShowError(err, t.window)
+			t.setConnectionStatus("Connection failed", "TabLoad")
 			return
 		}
 
-		// Save the last connected server
+		// Save the last connected server and profile
+		t.config.LastProfile = profileName
 		if err := t.saveLastConnectedServer(url); err != nil {
 			logging.Error("Failed to save last connected server", err)
 		}
 
 		func() {
 			t.RefreshUI()
-			t.window.SetTitle("TabLoad (connected to " + url + ")")
+			t.setConnectionStatus("Connected to "+url, "TabLoad (connected to "+url+")")
 			t.connectButton.Hide()
-			t.connectionStatus.SetText("Connected to " + url)
+			t.disconnectButton.Show()
+			t.applyProfileDefaultPreset(profileName)
+			t.startMonitor(ctx, url)
 		}()
 	}()
 }
 
+// handleDisconnect cancels every in-flight request and returns the UI to its
+// unconnected state.
+func (t *TabLoad) handleDisconnect() {
+	logging.Info(fmt.Sprintf("Disconnecting from %s", t.client.BaseURL))
+	t.resetRequestContext()
+
+	t.setConnectionStatus("Not connected", "TabLoad")
+	t.disconnectButton.Hide()
+	t.connectButton.Show()
+}
+
+// resetRequestContext cancels the current request context, if any, and starts
+// a fresh one for subsequent API calls, which it returns.
+func (t *TabLoad) resetRequestContext() context.Context {
+	t.ctxMu.Lock()
+	defer t.ctxMu.Unlock()
+	if t.cancelRequests != nil {
+		t.cancelRequests()
+	}
+	t.ctx, t.cancelRequests = context.WithCancel(context.Background())
+	return t.ctx
+}
+
+// requestContext returns the context API calls for the current connection
+// are made under.
+func (t *TabLoad) requestContext() context.Context {
+	t.ctxMu.Lock()
+	defer t.ctxMu.Unlock()
+	return t.ctx
+}
+
+// Close cancels all in-flight API calls. It is called when the window closes.
+func (t *TabLoad) Close() {
+	logging.Debug("Cancelling in-flight requests")
+	t.ctxMu.Lock()
+	t.cancelRequests()
+	t.ctxMu.Unlock()
+	t.downloads.Close()
+}
+
 func (t *TabLoad) buildConnectionTab() fyne.CanvasObject {
 	t.apiURLEntry = widget.NewEntry()
 	t.apiURLEntry.SetPlaceHolder("TabbyAPI Endpoint URL")
@@ -56,17 +116,29 @@ func (t *TabLoad) buildConnectionTab() fyne.CanvasObject {
 	t.adminKeyEntry = widget.NewPasswordEntry()
 	t.adminKeyEntry.SetPlaceHolder("Admin Key")
 
+	t.apiKeyEntry = widget.NewPasswordEntry()
+	t.apiKeyEntry.SetPlaceHolder("API Key (optional, defaults to the admin key)")
+
 	t.connectButton = widget.NewButton("Connect", t.handleConnect)
+	t.disconnectButton = widget.NewButton("Disconnect", t.handleDisconnect)
+	t.disconnectButton.Hide()
 
-	lastServer, err := t.loadLastConnectedServer()
-	if err == nil && lastServer != "" {
+	if lastServer := t.config.LastConnectedServer; lastServer != "" {
 		t.apiURLEntry.SetText(lastServer)
 	}
 
+	profileSelector := t.buildProfileSelector()
+
 	return container.NewVBox(
-		t.apiURLEntry,
-		t.adminKeyEntry,
+		profileSelector,
+		widget.NewForm(
+			widget.NewFormItem("URL", t.apiURLEntry),
+			widget.NewFormItem("Admin Key", t.adminKeyEntry),
+			widget.NewFormItem("API Key", t.apiKeyEntry),
+		),
+		t.buildProfileDetails(),
 		t.connectButton,
+		t.disconnectButton,
 	)
 }
 
@@ -76,7 +148,14 @@ func (t *TabLoad) AutoConnect() {
 		return
 	}
 
-	lastServer := config.LastConnectedServer
+	if profile, ok := t.config.Profile(t.config.LastProfile); ok {
+		logging.Info(fmt.Sprintf("Auto-connecting with last used profile: %s", profile.Name))
+		t.selectProfile(profile.Name)
+		t.handleConnect()
+		return
+	}
+
+	lastServer := t.config.LastConnectedServer
 	logging.Info(fmt.Sprintf("Auto-connect attempt. Last server: %s", lastServer))
 
 	if lastServer != "" {
@@ -0,0 +1,275 @@
+package ui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/chattemplate"
+	"github.com/sammcj/tabload/logging"
+)
+
+func (t *TabLoad) buildCompletionTab() fyne.CanvasObject {
+	t.completionPromptEntry = widget.NewMultiLineEntry()
+	t.completionPromptEntry.SetPlaceHolder("Raw prompt, sent verbatim")
+	t.completionPromptEntry.OnChanged = func(string) { t.renderCompletionPreview() }
+
+	t.completionPreview = widget.NewLabel("")
+	t.completionPreview.TextStyle = fyne.TextStyle{Monospace: true}
+	t.completionPreview.Wrapping = fyne.TextWrapWord
+
+	t.completionUseTemplateCheck = widget.NewCheck("Send the rendered prompt", nil)
+
+	t.completionMaxTokensEntry = widget.NewEntry()
+	t.completionMaxTokensEntry.SetPlaceHolder("e.g. 200")
+
+	t.completionStopEntry = widget.NewMultiLineEntry()
+	t.completionStopEntry.SetPlaceHolder("One stop string per line, \\n for newlines")
+	t.completionStopEntry.SetMinRowsVisible(2)
+
+	t.completionLogprobsEntry = widget.NewEntry()
+	t.completionLogprobsEntry.SetPlaceHolder("Top alternatives per token, e.g. 5 (empty for off)")
+
+	t.completionStreamCheck = widget.NewCheck("Stream", nil)
+	t.completionStreamCheck.SetChecked(true)
+
+	t.completionOutput = widget.NewMultiLineEntry()
+	t.completionOutput.Wrapping = fyne.TextWrapWord
+	t.completionOutput.SetPlaceHolder("Completion")
+
+	t.completionLogprobsLabel = widget.NewLabel("")
+	t.completionLogprobsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
+	t.completionGenerateButton = widget.NewButton("Generate", t.handleCompletionGenerate)
+	t.completionStopButton = widget.NewButton("Stop", func() {
+		if t.cancelCompletion != nil {
+			t.cancelCompletion()
+		}
+	})
+	t.completionStopButton.Disable()
+	renderButton := widget.NewButton("Render Template", t.renderCompletionPreview)
+
+	t.completionStatsLabel = widget.NewLabel("")
+
+	options := widget.NewForm(
+		widget.NewFormItem("Max Tokens", t.completionMaxTokensEntry),
+		widget.NewFormItem("Stop Strings", t.completionStopEntry),
+		widget.NewFormItem("Logprobs", t.completionLogprobsEntry),
+		widget.NewFormItem("", t.completionStreamCheck),
+	)
+
+	prompt := container.NewBorder(widget.NewLabel("Prompt:"), nil, nil, nil, t.completionPromptEntry)
+	preview := container.NewBorder(
+		container.NewBorder(nil, nil, widget.NewLabel("Rendered by selected template:"), renderButton),
+		t.completionUseTemplateCheck, nil, nil,
+		container.NewVScroll(t.completionPreview),
+	)
+	promptSplit := container.NewHSplit(prompt, preview)
+
+	output := container.NewAppTabs(
+		container.NewTabItem("Output", t.completionOutput),
+		container.NewTabItem("Token Probabilities", container.NewScroll(t.completionLogprobsLabel)),
+	)
+
+	controls := container.NewVBox(
+		options,
+		container.NewHBox(t.completionGenerateButton, t.completionStopButton),
+		t.completionStatsLabel,
+	)
+
+	return container.NewBorder(nil, controls, nil, nil, container.NewVSplit(promptSplit, output))
+}
+
+// renderCompletionPreview shows the prompt as the selected template would
+// render it as a single user turn.
+func (t *TabLoad) renderCompletionPreview() {
+	if t.completionPreview == nil {
+		return
+	}
+
+	rendered, err := t.renderCompletionPrompt()
+	if err != nil {
+		t.completionPreview.SetText(err.Error())
+		return
+	}
+	t.completionPreview.SetText(rendered)
+}
+
+// renderCompletionPrompt renders the prompt with the template selected on the
+// Model tab.
+func (t *TabLoad) renderCompletionPrompt() (string, error) {
+	name := ""
+	if t.promptTemplateDropdown != nil {
+		name = t.promptTemplateDropdown.Selected
+	}
+	if name == "" || name == "Create New..." {
+		return "", errors.New("no template selected on the Model tab")
+	}
+	if strings.HasSuffix(name, " (server)") {
+		return "", fmt.Errorf("the content of server template %s isn't available locally", strings.TrimSuffix(name, " (server)"))
+	}
+
+	source, err := t.localTemplate(name)
+	if err != nil {
+		return "", err
+	}
+
+	messages := []api.ChatMessage{{Role: api.RoleUser, Content: t.completionPromptEntry.Text}}
+	return chattemplate.Render(source, messages, chattemplate.DefaultVars)
+}
+
+// completionParams returns the completion options and the Advanced tab's
+// samplers as request fields.
+func (t *TabLoad) completionParams() (map[string]interface{}, error) {
+	params, err := t.samplingParams()
+	if err != nil {
+		return nil, err
+	}
+
+	if text := strings.TrimSpace(t.completionMaxTokensEntry.Text); text != "" {
+		maxTokens, err := strconv.Atoi(text)
+		if err != nil || maxTokens <= 0 {
+			return nil, fmt.Errorf("invalid max tokens %q", text)
+		}
+		params["max_tokens"] = maxTokens
+	}
+
+	if stop := parseStopStrings(t.completionStopEntry.Text); len(stop) > 0 {
+		params["stop"] = stop
+	}
+
+	if text := strings.TrimSpace(t.completionLogprobsEntry.Text); text != "" {
+		logprobs, err := strconv.Atoi(text)
+		if err != nil || logprobs < 0 {
+			return nil, fmt.Errorf("invalid logprobs %q", text)
+		}
+		params["logprobs"] = logprobs
+	}
+
+	return params, nil
+}
+
+// parseStopStrings returns one stop string per line, with \n, \t and \\
+// escapes expanded.
+func parseStopStrings(text string) []string {
+	unescape := strings.NewReplacer(`\\`, `\`, `\n`, "\n", `\t`, "\t")
+
+	var stop []string
+	for _, line := range strings.Split(text, "\n") {
+		if line == "" {
+			continue
+		}
+		stop = append(stop, unescape.Replace(line))
+	}
+	return stop
+}
+
+func (t *TabLoad) handleCompletionGenerate() {
+	params, err := t.completionParams()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	prompt := t.completionPromptEntry.Text
+	if t.completionUseTemplateCheck.Checked {
+		prompt, err = t.renderCompletionPrompt()
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+	}
+	if prompt == "" {
+		dialog.ShowInformation("Error", "Please enter a prompt", t.window)
+		return
+	}
+
+	ctx, cancel := context.WithCancel(t.requestContext())
+	t.cancelCompletion = cancel
+	t.setCompletionGenerating(true)
+	t.completionOutput.SetText("")
+	t.completionLogprobsLabel.SetText("")
+	t.completionStatsLabel.SetText("Generating...")
+
+	stream := t.completionStreamCheck.Checked
+
+	go func() {
+		defer cancel()
+
+		var result *api.CompletionResult
+		var err error
+		if stream {
+			var text strings.Builder
+			var logprobs []api.TokenLogprob
+			result, err = t.client.CompletionStream(ctx, prompt, params, func(chunk string, chunkLogprobs []api.TokenLogprob) {
+				text.WriteString(chunk)
+				t.completionOutput.SetText(text.String())
+				if len(chunkLogprobs) > 0 {
+					logprobs = append(logprobs, chunkLogprobs...)
+					t.completionLogprobsLabel.SetText(formatLogprobs(logprobs))
+				}
+			})
+		} else {
+			result, err = t.client.CompletionCtx(ctx, prompt, params)
+		}
+		t.setCompletionGenerating(false)
+
+		switch {
+		case err == nil:
+			t.completionStatsLabel.SetText(fmt.Sprintf("%s (finish reason: %s)", result.Stats, result.FinishReason))
+		case errors.Is(err, context.Canceled) && result == nil:
+			t.completionStatsLabel.SetText("Stopped")
+		case errors.Is(err, context.Canceled):
+			t.completionStatsLabel.SetText("Stopped: " + result.Stats.String())
+		default:
+			logging.Error("Completion failed", err)
+			t.completionStatsLabel.SetText("Error: " + err.Error())
+		}
+		if result == nil {
+			return
+		}
+
+		t.completionOutput.SetText(result.Text)
+		t.completionLogprobsLabel.SetText(formatLogprobs(result.Logprobs))
+	}()
+}
+
+func (t *TabLoad) setCompletionGenerating(generating bool) {
+	if generating {
+		t.completionGenerateButton.Disable()
+		t.completionStopButton.Enable()
+		return
+	}
+	t.completionGenerateButton.Enable()
+	t.completionStopButton.Disable()
+}
+
+// formatLogprobs lists each token with its probability and the most likely
+// alternatives.
+func formatLogprobs(logprobs []api.TokenLogprob) string {
+	var sb strings.Builder
+	for _, entry := range logprobs {
+		fmt.Fprintf(&sb, "%-20q %7.4f", entry.Token, entry.Logprob)
+
+		alternatives := make([]string, 0, len(entry.Top))
+		for token := range entry.Top {
+			alternatives = append(alternatives, token)
+		}
+		sort.Slice(alternatives, func(i, j int) bool {
+			return entry.Top[alternatives[i]] > entry.Top[alternatives[j]]
+		})
+		for _, token := range alternatives {
+			fmt.Fprintf(&sb, "  %q %.4f", token, entry.Top[token])
+		}
+		sb.WriteString("\n")
+	}
+	return sb.String()
+}
@@ -16,12 +16,3 @@ func (t *TabLoad) updateModelInfoContainer(data [][]string) {
 	}
 	t.currentModelInfo.Refresh()
 }
-
-func (t *TabLoad) refreshCurrentLoras() {
-	currentLoras, err := t.client.FetchCurrentLoras()
-	if err != nil {
-		fmt.Println("Error fetching current loras:", err)
-		return
-	}
-	t.currentLorasLabel.SetText(currentLoras)
-}
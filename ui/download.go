@@ -1,61 +1,300 @@
 package ui
 
 import (
+	"context"
 	"fmt"
-	"strings"
+	"time"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/apply"
+	"github.com/sammcj/tabload/download"
+	"github.com/sammcj/tabload/logging"
 )
 
-func (t *TabLoad) handleDownload() {
-	params := map[string]interface{}{
-		"repo_id":     t.repoIDEntry.Text,
-		"revision":    t.revisionEntry.Text,
-		"repo_type":   strings.ToLower(t.repoTypeDropdown.Selected),
-		"folder_name": t.folderNameEntry.Text,
-		"token":       t.tokenEntry.Text,
-		"include":     strings.Split(t.includeEntry.Text, ","),
-		"exclude":     strings.Split(t.excludeEntry.Text, ","),
+// downloadRow shows one job in the download queue.
+type downloadRow struct {
+	title        *widget.Label
+	status       *widget.Label
+	detail       *widget.Label
+	cancelButton *widget.Button
+	object       fyne.CanvasObject
+}
+
+// downloadForm returns the downloader form as typed.
+func (t *TabLoad) downloadForm() download.Form {
+	return download.Form{
+		RepoID:     t.repoIDEntry.Text,
+		RepoType:   t.repoTypeDropdown.Selected,
+		Revision:   t.revisionEntry.Text,
+		FolderName: t.folderNameEntry.Text,
+		Token:      t.tokenEntry.Text,
+		Include:    t.includeEntry.Text,
+		Exclude:    t.excludeEntry.Text,
+		ChunkLimit: t.chunkLimitEntry.Text,
+		Timeout:    t.downloadTimeoutEntry.Text,
 	}
+}
 
-	downloadPath, err := t.client.Download(params)
+// handleDownload queues a download of the repo entered on the downloader
+// tab. It runs in the background; the queue below the form shows its
+// progress.
+func (t *TabLoad) handleDownload() {
+	request, err := t.downloadForm().Build()
 	if err != nil {
-		fmt.Println("Error downloading:", err)
+		dialog.ShowError(fmt.Errorf("can't download, fix these first:\n%w", err), t.window)
+		return
+	}
+	t.saveHFToken(request.Token)
+
+	actions := download.Actions{
+		Refresh:      t.refreshAfterDownloadCheck.Checked,
+		CreatePreset: t.presetAfterDownloadCheck.Checked,
+		Load:         t.loadAfterDownloadCheck.Checked,
+	}
+	if _, err := t.downloads.Add(request, actions); err != nil {
+		logging.Error("Failed to queue download", err)
+		dialog.ShowError(err, t.window)
+	}
+}
+
+// handleCancelDownload cancels one job, whether it's waiting or running.
+func (t *TabLoad) handleCancelDownload(id int) {
+	go func() {
+		if err := t.downloads.Cancel(id); err != nil {
+			logging.Error("Error cancelling download", err)
+			dialog.ShowError(fmt.Errorf("cancelling download: %w", err), t.window)
+		}
+	}()
+}
+
+func (t *TabLoad) handleClearDownloads() {
+	t.downloads.ClearFinished()
+	t.refreshDownloads()
+}
+
+// handleDownloadChanged updates the queue for a job, and notifies the user
+// when one finishes.
+func (t *TabLoad) handleDownloadChanged(job download.Job) {
+	t.refreshDownloads()
+
+	switch job.Status {
+	case download.Running:
+		go t.tickDownload(job.ID)
+	case download.Done:
+		t.notify("Download finished", fmt.Sprintf("%s saved to %s", job.Request.RepoID, job.Path))
+		go t.runDownloadActions(job)
+	case download.Failed:
+		t.notify("Download failed", fmt.Sprintf("%s: %v", job.Request.RepoID, job.Err))
+	}
+}
+
+// tickDownload refreshes the queue every second while a job runs, to keep
+// its elapsed time current.
+func (t *TabLoad) tickDownload(id int) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		running := false
+		for _, job := range t.downloads.Jobs() {
+			if job.ID == id && job.Status == download.Running {
+				running = true
+			}
+		}
+		if !running {
+			return
+		}
+		t.refreshDownloads()
+	}
+}
+
+func (t *TabLoad) notify(title, content string) {
+	if app := fyne.CurrentApp(); app != nil {
+		app.SendNotification(fyne.NewNotification(title, content))
+	}
+}
+
+// refreshDownloads brings the queue's rows up to date with its jobs.
+func (t *TabLoad) refreshDownloads() {
+	if t.downloadsList == nil {
 		return
 	}
+	t.downloadsMu.Lock()
+	defer t.downloadsMu.Unlock()
+
+	jobs := t.downloads.Jobs()
+	rows := make(map[int]*downloadRow, len(jobs))
+	t.downloadsList.RemoveAll()
+	for i := len(jobs) - 1; i >= 0; i-- {
+		job := jobs[i]
+		row, ok := t.downloadRows[job.ID]
+		if !ok {
+			row = t.newDownloadRow(job)
+		}
+		row.update(job)
+		rows[job.ID] = row
+		t.downloadsList.Add(row.object)
+	}
+	t.downloadRows = rows
+	if len(jobs) == 0 {
+		t.downloadsList.Add(widget.NewLabel("No downloads yet"))
+	}
+	t.downloadsList.Refresh()
+}
 
-	fmt.Println("Download completed successfully. Path:", downloadPath)
+func (t *TabLoad) newDownloadRow(job download.Job) *downloadRow {
+	row := &downloadRow{
+		title:  widget.NewLabelWithStyle(job.Request.RepoID, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		status: widget.NewLabel(""),
+		detail: widget.NewLabel(""),
+	}
+	row.detail.Wrapping = fyne.TextWrapWord
+	id := job.ID
+	row.cancelButton = widget.NewButton("Cancel", func() { t.handleCancelDownload(id) })
+	row.object = container.NewVBox(
+		container.NewBorder(nil, nil, row.title, row.cancelButton, row.status),
+		row.detail,
+		widget.NewSeparator(),
+	)
+	return row
 }
 
-func (t *TabLoad) handleCancelDownload() {
-	err := t.client.CancelDownload()
+func (row *downloadRow) update(job download.Job) {
+	status := job.Status.String()
+	if job.Status != download.Queued {
+		status += fmt.Sprintf(" (%s)", job.Elapsed().Round(time.Second))
+	}
+	row.status.SetText(status)
+
+	switch job.Status {
+	case download.Done:
+		row.detail.SetText("Saved to " + job.Path)
+	case download.Failed:
+		row.detail.SetText(job.Err.Error())
+	case download.Cancelled:
+		row.detail.SetText("")
+	default:
+		row.detail.SetText("Then: " + job.Actions.String())
+	}
+	if row.detail.Text == "" {
+		row.detail.Hide()
+	} else {
+		row.detail.Show()
+	}
+
+	if job.IsFinished() {
+		row.cancelButton.Hide()
+	} else {
+		row.cancelButton.Show()
+	}
+}
+
+// runDownloadActions carries out what was asked for when a job was queued,
+// once its download has finished. Failures are logged and notified rather
+// than stopping the remaining actions.
+func (t *TabLoad) runDownloadActions(job download.Job) {
+	actions := job.Actions
+	name := job.Name()
+
+	if actions.Refresh {
+		if err := t.refreshData(t.requestContext()); err != nil {
+			logging.Error("Error refreshing data after download", err)
+		}
+	}
+
+	if job.Request.RepoType == download.RepoLora {
+		if actions.CreatePreset {
+			logging.Warn(fmt.Sprintf("Not creating a preset for %s, presets are only created for models", name))
+		}
+		if actions.Load {
+			t.loadDownloadedLora(name)
+		}
+		return
+	}
+
+	preset := t.config.DefaultParams.Preset(name, name)
+	if actions.CreatePreset {
+		t.createDownloadPreset(preset)
+	}
+	if actions.Load {
+		t.loadDownloadedModel(preset)
+	}
+}
+
+// createDownloadPreset saves a preset for a downloaded model, leaving any
+// existing preset of the same name alone.
+func (t *TabLoad) createDownloadPreset(preset Preset) {
+	presets, err := t.loadPresetsFromStorage()
 	if err != nil {
-		fmt.Println("Error cancelling download:", err)
+		logging.Error("Error loading presets", err)
+		t.notify("Preset not created", err.Error())
 		return
 	}
+	for _, existing := range presets {
+		if existing.Name == preset.Name {
+			logging.Info(fmt.Sprintf("Preset %s already exists, not replacing it", preset.Name))
+			return
+		}
+	}
 
-	fmt.Println("Download canceled successfully")
+	if err := t.savePresetToStorage(preset); err != nil {
+		logging.Error(fmt.Sprintf("Error creating preset for %s", preset.Name), err)
+		t.notify("Preset not created", fmt.Sprintf("%s: %v", preset.Name, err))
+		return
+	}
+	logging.Info(fmt.Sprintf("Created preset %s from the default params", preset.Name))
+	t.refreshPresetList()
 }
 
-func (t *TabLoad) refreshData() error {
+// loadDownloadedModel loads a downloaded model in place of the current one,
+// putting the previous model back if it fails.
+func (t *TabLoad) loadDownloadedModel(preset Preset) {
+	logging.Info(fmt.Sprintf("Loading downloaded model %s", preset.ModelID))
+	applier := apply.New(t.client, t.samplerStore)
+	if _, err := applier.Apply(t.requestContext(), preset); err != nil {
+		logging.Error(fmt.Sprintf("Error loading downloaded model %s", preset.ModelID), err)
+		t.notify("Model not loaded", fmt.Sprintf("%s: %v", preset.ModelID, err))
+		return
+	}
+	t.notify("Model loaded", preset.ModelID)
+	t.refreshCurrentModel()
+	t.refreshCurrentLoras()
+}
+
+// loadDownloadedLora adds a downloaded LoRA to the loaded model at full
+// scaling.
+func (t *TabLoad) loadDownloadedLora(name string) {
+	logging.Info(fmt.Sprintf("Loading downloaded LoRA %s", name))
+	if err := t.client.LoadLorasCtx(t.requestContext(), []string{name}, []float64{1}); err != nil {
+		logging.Error(fmt.Sprintf("Error loading downloaded LoRA %s", name), err)
+		t.notify("LoRA not loaded", fmt.Sprintf("%s: %v", name, err))
+		return
+	}
+	t.notify("LoRA loaded", name)
+	t.refreshCurrentLoras()
+}
+
+func (t *TabLoad) refreshData(ctx context.Context) error {
 	var err error
 
-	models, err := t.client.FetchModels()
+	models, err := t.client.FetchModelsCtx(ctx)
 	if err != nil {
 		return fmt.Errorf("fetching models: %w", err)
 	}
-	t.modelsDropdown.Options = models
+	t.modelsDropdown.Options = api.ModelIDs(models)
 
-	loras, err := t.client.FetchLoras()
+	loras, err := t.client.FetchLorasCtx(ctx)
 	if err != nil {
 		return fmt.Errorf("fetching LoRAs: %w", err)
 	}
-	t.lorasDropdown.Options = loras
+	t.setAvailableLoras(api.ModelIDs(loras))
 
 	t.refreshCurrentModel()
 	t.refreshCurrentLoras()
+	t.refreshOverrides()
 
 	return nil
 }
@@ -68,6 +307,7 @@ func (t *TabLoad) buildHFDownloaderTab() fyne.CanvasObject {
 	t.revisionEntry.SetPlaceHolder("Revision/Branch")
 
 	t.repoTypeDropdown = widget.NewSelect([]string{"Model", "LoRA"}, func(selected string) {})
+	t.repoTypeDropdown.SetSelected("Model")
 
 	t.folderNameEntry = widget.NewEntry()
 	t.folderNameEntry.SetPlaceHolder("Folder Name")
@@ -78,21 +318,54 @@ func (t *TabLoad) buildHFDownloaderTab() fyne.CanvasObject {
 	t.excludeEntry = widget.NewEntry()
 	t.excludeEntry.SetPlaceHolder("Exclude Patterns")
 
+	t.chunkLimitEntry = widget.NewEntry()
+	t.chunkLimitEntry.SetPlaceHolder("Chunk Limit (server default if empty)")
+
+	t.downloadTimeoutEntry = widget.NewEntry()
+	t.downloadTimeoutEntry.SetPlaceHolder("Timeout, e.g. 600 or 30m (server default if empty)")
+
 	t.tokenEntry = widget.NewPasswordEntry()
 	t.tokenEntry.SetPlaceHolder("HF Access Token")
+	t.tokenEntry.SetText(t.secret(t.config.HFTokenRef))
+
+	t.downloadSizeLabel = widget.NewLabel("")
+	t.downloadSizeLabel.Wrapping = fyne.TextWrapWord
+	for _, entry := range []*widget.Entry{t.repoIDEntry, t.revisionEntry, t.includeEntry, t.excludeEntry, t.chunkLimitEntry, t.downloadTimeoutEntry} {
+		entry.OnChanged = func(string) { t.updateDownloadSize() }
+	}
+	t.updateDownloadSize()
+	t.refreshAfterDownloadCheck = widget.NewCheck("Refresh model lists", nil)
+	t.refreshAfterDownloadCheck.SetChecked(true)
+	t.presetAfterDownloadCheck = widget.NewCheck("Create preset from defaults", nil)
+	t.loadAfterDownloadCheck = widget.NewCheck("Load it", nil)
+
+	browseButton := widget.NewButton("Browse...", t.handleBrowseRepo)
+	previewButton := widget.NewButton("Preview Files", t.handlePreviewDownload)
 
 	t.downloadButton = widget.NewButton("Download", t.handleDownload)
-	t.cancelDownloadButton = widget.NewButton("Cancel Download", t.handleCancelDownload)
+	clearButton := widget.NewButton("Clear Finished", t.handleClearDownloads)
 
-	return container.NewVBox(
-		t.repoIDEntry,
+	t.downloads.OnChange = t.handleDownloadChanged
+	t.downloadsList = container.NewVBox()
+	t.refreshDownloads()
+
+	form := container.NewVBox(
+		container.NewBorder(nil, nil, nil, browseButton, t.repoIDEntry),
 		t.revisionEntry,
 		t.repoTypeDropdown,
 		t.folderNameEntry,
 		t.includeEntry,
 		t.excludeEntry,
+		container.NewGridWithColumns(2, t.chunkLimitEntry, t.downloadTimeoutEntry),
 		t.tokenEntry,
+		container.NewBorder(nil, nil, nil, previewButton, t.downloadSizeLabel),
+		container.NewHBox(widget.NewLabel("When done:"), t.refreshAfterDownloadCheck, t.presetAfterDownloadCheck, t.loadAfterDownloadCheck),
 		t.downloadButton,
-		t.cancelDownloadButton,
 	)
+	queue := container.NewBorder(
+		container.NewBorder(nil, nil, widget.NewLabelWithStyle("Downloads", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), clearButton),
+		nil, nil, nil,
+		container.NewVScroll(t.downloadsList),
+	)
+	return container.NewBorder(form, nil, nil, nil, queue)
 }
@@ -0,0 +1,65 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2/dialog"
+	"github.com/sammcj/tabload/api"
+)
+
+// showFormError shows err in a dialog. When the server rejected individual
+// load parameters, their messages are also shown under the matching rows of
+// the model form.
+func (t *TabLoad) showFormError(err error) {
+	t.clearFormErrors()
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) || len(apiErr.Validation) == 0 {
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.showFieldErrors("the server rejected the request", apiErr.FieldErrors())
+}
+
+// showFieldErrors marks each field's message under its row in the model form
+// and lists them all, ordered by field, in a dialog under heading.
+func (t *TabLoad) showFieldErrors(heading string, fieldErrors map[string]string) {
+	fields := make([]string, 0, len(fieldErrors))
+	for field := range fieldErrors {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+
+	lines := make([]string, 0, len(fields))
+	for _, field := range fields {
+		message := fieldErrors[field]
+		label := field
+		if row, ok := t.formRows[field]; ok {
+			row.hint.Text = message
+			row.hint.Show()
+			row.hint.Refresh()
+			label = row.label
+		}
+		lines = append(lines, fmt.Sprintf("%s: %s", label, message))
+	}
+	if t.form != nil {
+		t.form.Refresh()
+	}
+
+	dialog.ShowError(fmt.Errorf("%s:\n%s", heading, strings.Join(lines, "\n")), t.window)
+}
+
+// clearFormErrors removes any server validation messages from the model form.
+func (t *TabLoad) clearFormErrors() {
+	for _, row := range t.formRows {
+		row.hint.Text = ""
+		row.hint.Hide()
+	}
+	if t.form != nil {
+		t.form.Refresh()
+	}
+}
@@ -0,0 +1,79 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/monitor"
+)
+
+// startMonitor starts polling the connected server in the background, with
+// the request context the connection was made under. The monitor stops when
+// that context is reset by a disconnect or a new connection, and isn't
+// started at all if that has already happened.
+func (t *TabLoad) startMonitor(ctx context.Context, url string) {
+	if ctx.Err() != nil {
+		return
+	}
+	m := monitor.New(t.client, func(status monitor.Status) {
+		// Once the connection is replaced or dropped the monitor still
+		// reports its final state; the UI has already moved on
+		if ctx.Err() != nil {
+			return
+		}
+		t.handleConnectionState(ctx, url, status)
+	})
+
+	go m.Run(ctx)
+}
+
+// handleConnectionState updates the status bar and window title for a change
+// in the connection, and refreshes what the server may have lost.
+func (t *TabLoad) handleConnectionState(ctx context.Context, url string, status monitor.Status) {
+	switch status.State {
+	case monitor.Connecting:
+		t.setConnectionStatus("Connecting to "+url+"...", "TabLoad (connecting to "+url+")")
+	case monitor.Connected:
+		t.setConnectionStatus("Connected to "+url, "TabLoad (connected to "+url+")")
+	case monitor.Degraded:
+		t.setConnectionStatus(fmt.Sprintf("Degraded: %s (%s)", url, degradedReason(status)), "TabLoad (degraded: "+url+")")
+	case monitor.Reconnecting:
+		t.setConnectionStatus(fmt.Sprintf("Connection to %s lost, retrying in %s", url, status.NextRetry), "TabLoad (reconnecting to "+url+")")
+	case monitor.Disconnected:
+		t.setConnectionStatus("Not connected", "TabLoad")
+	}
+
+	switch {
+	case status.Recovered:
+		logging.Info(fmt.Sprintf("Server %s is back, refreshing", url))
+		if err := t.refreshData(ctx); err != nil {
+			logging.Error("Error refreshing data after reconnecting", err)
+		}
+	case status.ModelChanged:
+		logging.Info(fmt.Sprintf("Loaded model on %s changed, refreshing", url))
+		t.refreshCurrentModel()
+		t.refreshCurrentLoras()
+	}
+}
+
+func (t *TabLoad) setConnectionStatus(status, title string) {
+	t.connectionStatus.SetText(status)
+	t.window.SetTitle(title)
+}
+
+// degradedReason summarises why the server is degraded for the status bar.
+func degradedReason(status monitor.Status) string {
+	if status.Health != nil && len(status.Health.Issues) > 0 {
+		issues := make([]string, len(status.Health.Issues))
+		for i, issue := range status.Health.Issues {
+			issues[i] = issue.Description
+		}
+		return strings.Join(issues, "; ")
+	}
+	if status.Err != nil {
+		return status.Err.Error()
+	}
+	return "server reports unhealthy"
+}
@@ -0,0 +1,344 @@
+package ui
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/download"
+	"github.com/sammcj/tabload/hf"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/utils"
+)
+
+// repoFile is a file listed in the repo browser.
+type repoFile struct {
+	file  hf.File
+	check *widget.Check
+}
+
+// handleBrowseRepo opens the repo browser: it lists a Hugging Face repo's
+// branches and files, and fills the downloader form from the files and
+// branch picked, so nothing has to be typed blind.
+func (t *TabLoad) handleBrowseRepo() {
+	client := hf.NewClient(t.config.HFEndpoint, t.tokenEntry.Text)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	repoEntry := widget.NewEntry()
+	repoEntry.SetPlaceHolder("Repo ID, e.g. turboderp/Llama-3-8B-Instruct-exl2")
+	repoEntry.SetText(strings.TrimSpace(t.repoIDEntry.Text))
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	sizeLabel := widget.NewLabel("")
+	filesBox := container.NewVBox()
+	branchSelect := widget.NewSelect(nil, nil)
+	branchSelect.PlaceHolder = "(Fetch the repo first)"
+	branchSelect.Disable()
+
+	// The files are listed from a goroutine, so everything it sets is read
+	// under mu
+	var (
+		mu    sync.Mutex
+		files []*repoFile
+		// listing counts file listings, so a slow one for a branch that is
+		// no longer selected doesn't overwrite a newer one
+		listing     int
+		listedRepo  string
+		listedFiles []hf.File
+	)
+
+	// listedRows returns the rows of the files listed so far.
+	listedRows := func() []*repoFile {
+		mu.Lock()
+		defer mu.Unlock()
+		return files
+	}
+
+	updateSize := func() {
+		rows := listedRows()
+		var selected []hf.File
+		for _, file := range rows {
+			if file.check.Checked {
+				selected = append(selected, file.file)
+			}
+		}
+		sizeLabel.SetText(fmt.Sprintf("%d of %d files selected, %s", len(selected), len(rows), utils.FormatBytes(hf.TotalBytes(selected))))
+	}
+
+	listFiles := func(repoID, revision string) {
+		mu.Lock()
+		listing++
+		current := listing
+		files = nil
+		mu.Unlock()
+		statusLabel.SetText(fmt.Sprintf("Listing files on %s...", revision))
+		filesBox.RemoveAll()
+		updateSize()
+
+		go func() {
+			listed, err := client.Files(ctx, repoID, revision)
+			mu.Lock()
+			if current != listing {
+				mu.Unlock()
+				return
+			}
+			if err != nil {
+				mu.Unlock()
+				logging.Error("Failed to list repo files", err)
+				statusLabel.SetText(err.Error())
+				return
+			}
+
+			rows := make([]*repoFile, len(listed))
+			for i, file := range listed {
+				rows[i] = &repoFile{file: file}
+				rows[i].check = widget.NewCheck(fmt.Sprintf("%s (%s)", file.Path, utils.FormatBytes(file.Bytes())), func(bool) { updateSize() })
+				rows[i].check.Checked = true
+			}
+			listedRepo, listedFiles, files = repoID, listed, rows
+			mu.Unlock()
+
+			for _, row := range rows {
+				filesBox.Add(row.check)
+			}
+			filesBox.Refresh()
+			statusLabel.SetText(fmt.Sprintf("%s@%s: %d files, %s in total", repoID, revision, len(listed), utils.FormatBytes(hf.TotalBytes(listed))))
+			updateSize()
+		}()
+	}
+
+	fetchButton := widget.NewButton("Fetch", func() {
+		repoID := strings.TrimSpace(repoEntry.Text)
+		if repoID == "" {
+			statusLabel.SetText("Enter a repo ID first")
+			return
+		}
+		statusLabel.SetText(fmt.Sprintf("Fetching branches of %s from %s...", repoID, client.Endpoint))
+		branchSelect.Disable()
+
+		go func() {
+			refs, err := client.Refs(ctx, repoID)
+			if err != nil {
+				logging.Error("Failed to fetch repo branches", err)
+				statusLabel.SetText(err.Error())
+				return
+			}
+
+			names := make([]string, len(refs.Branches))
+			for i, branch := range refs.Branches {
+				names[i] = branch.Name
+			}
+			branchSelect.Options = names
+			branchSelect.OnChanged = func(branch string) { listFiles(repoID, branch) }
+			branchSelect.Enable()
+
+			// Start on the branch already entered, if the repo has it
+			selected := strings.TrimSpace(t.revisionEntry.Text)
+			if !slices.Contains(names, selected) {
+				selected = "main"
+			}
+			if !slices.Contains(names, selected) && len(names) > 0 {
+				selected = names[0]
+			}
+			if selected == branchSelect.Selected {
+				listFiles(repoID, selected)
+			} else {
+				branchSelect.SetSelected(selected)
+			}
+		}()
+	})
+	repoEntry.OnSubmitted = func(string) { fetchButton.OnTapped() }
+
+	setAll := func(checked bool) {
+		for _, file := range listedRows() {
+			file.check.SetChecked(checked)
+		}
+	}
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil, fetchButton, repoEntry),
+			widget.NewForm(widget.NewFormItem("Branch", branchSelect)),
+			statusLabel,
+			container.NewHBox(
+				widget.NewButton("Select All", func() { setAll(true) }),
+				widget.NewButton("Select None", func() { setAll(false) }),
+			),
+		),
+		sizeLabel,
+		nil, nil,
+		container.NewVScroll(filesBox),
+	)
+
+	dlg := dialog.NewCustomConfirm("Browse Hugging Face Repo", "Use Selection", "Cancel", content, func(confirmed bool) {
+		if !confirmed {
+			return
+		}
+		mu.Lock()
+		rows, repoID, repoFiles := files, listedRepo, listedFiles
+		mu.Unlock()
+
+		var selected []string
+		for _, file := range rows {
+			if file.check.Checked {
+				selected = append(selected, file.file.Path)
+			}
+		}
+		if len(rows) == 0 || len(selected) == 0 {
+			dialog.ShowInformation("Browse Hugging Face Repo", "No files were selected", t.window)
+			return
+		}
+		t.useRepoSelection(repoID, branchSelect.Selected, repoFiles, selected)
+	}, t.window)
+	dlg.SetOnClosed(cancel)
+	dlg.Resize(fyne.NewSize(700, 550))
+	dlg.Show()
+
+	if repoEntry.Text != "" {
+		fetchButton.OnTapped()
+	}
+}
+
+// useRepoSelection fills the downloader form with the repo, branch and files
+// picked in the browser. Picking every file leaves the include patterns empty,
+// so files added to the branch later are still downloaded.
+func (t *TabLoad) useRepoSelection(repoID, revision string, files []hf.File, selected []string) {
+	t.setRepoFiles(repoID, revision, files)
+
+	include := strings.Join(selected, ",")
+	if len(selected) == len(files) {
+		include = ""
+	}
+	t.repoIDEntry.SetText(repoID)
+	t.revisionEntry.SetText(revision)
+	t.includeEntry.SetText(include)
+	t.excludeEntry.SetText("")
+	t.updateDownloadSize()
+}
+
+// sameRevision reports whether two revisions name the same branch, an empty
+// one meaning main.
+func sameRevision(a, b string) bool {
+	if a == "" {
+		a = "main"
+	}
+	if b == "" {
+		b = "main"
+	}
+	return a == b
+}
+
+// setRepoFiles records the files listed for a repo's revision.
+func (t *TabLoad) setRepoFiles(repoID, revision string, files []hf.File) {
+	t.repoFilesMu.Lock()
+	defer t.repoFilesMu.Unlock()
+	t.repoFiles = files
+	t.repoFilesID = repoID
+	t.repoFilesRevision = revision
+}
+
+// cachedRepoFiles returns the files listed for the request's repo and
+// revision, if they have been.
+func (t *TabLoad) cachedRepoFiles(request download.Request) ([]hf.File, bool) {
+	t.repoFilesMu.Lock()
+	defer t.repoFilesMu.Unlock()
+	if t.repoFiles == nil || request.RepoID != t.repoFilesID || !sameRevision(request.Revision, t.repoFilesRevision) {
+		return nil, false
+	}
+	return t.repoFiles, true
+}
+
+// selectRepoFiles splits files into those the request downloads and those
+// it skips.
+func selectRepoFiles(request download.Request, files []hf.File) (selected, skipped []hf.File) {
+	for _, file := range files {
+		if request.Selects(file.Path) {
+			selected = append(selected, file)
+		} else {
+			skipped = append(skipped, file)
+		}
+	}
+	return selected, skipped
+}
+
+// updateDownloadSize shows how many of the repo's files the downloader form
+// matches and their size, once the repo's files have been listed.
+func (t *TabLoad) updateDownloadSize() {
+	if t.downloadSizeLabel == nil {
+		return
+	}
+	request, err := t.downloadForm().Build()
+	if err != nil {
+		t.downloadSizeLabel.SetText(strings.ReplaceAll(err.Error(), "\n", "; "))
+		return
+	}
+	files, ok := t.cachedRepoFiles(request)
+	if !ok {
+		t.downloadSizeLabel.SetText("Browse or preview the repo to see what will be downloaded")
+		return
+	}
+	selected, _ := selectRepoFiles(request, files)
+	t.downloadSizeLabel.SetText(fmt.Sprintf("Will download %d of %d files, %s", len(selected), len(files), utils.FormatBytes(hf.TotalBytes(selected))))
+}
+
+// handlePreviewDownload lists the repo's files and shows which the include
+// and exclude patterns select, before anything is sent to the server.
+func (t *TabLoad) handlePreviewDownload() {
+	request, err := t.downloadForm().Build()
+	if err != nil {
+		dialog.ShowError(fmt.Errorf("can't preview, fix these first:\n%w", err), t.window)
+		return
+	}
+
+	if files, ok := t.cachedRepoFiles(request); ok {
+		t.showDownloadPreview(request, files)
+		return
+	}
+
+	t.downloadSizeLabel.SetText(fmt.Sprintf("Listing files of %s...", request.RepoID))
+	client := hf.NewClient(t.config.HFEndpoint, request.Token)
+	go func() {
+		files, err := client.Files(context.Background(), request.RepoID, request.Revision)
+		if err != nil {
+			logging.Error("Failed to list repo files", err)
+			t.updateDownloadSize()
+			dialog.ShowError(err, t.window)
+			return
+		}
+		t.setRepoFiles(request.RepoID, request.Revision, files)
+		t.updateDownloadSize()
+		t.showDownloadPreview(request, files)
+	}()
+}
+
+func (t *TabLoad) showDownloadPreview(request download.Request, files []hf.File) {
+	selected, skipped := selectRepoFiles(request, files)
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Will download %d of %d files, %s:\n", len(selected), len(files), utils.FormatBytes(hf.TotalBytes(selected)))
+	for _, file := range selected {
+		fmt.Fprintf(&sb, "  %s (%s)\n", file.Path, utils.FormatBytes(file.Bytes()))
+	}
+	if len(skipped) > 0 {
+		fmt.Fprintf(&sb, "\nSkipping %d files, %s:\n", len(skipped), utils.FormatBytes(hf.TotalBytes(skipped)))
+		for _, file := range skipped {
+			fmt.Fprintf(&sb, "  %s\n", file.Path)
+		}
+	}
+
+	label := widget.NewLabel(sb.String())
+	scroll := container.NewVScroll(label)
+	scroll.SetMinSize(fyne.NewSize(600, 400))
+	title := request.RepoID
+	if request.Revision != "" {
+		title += "@" + request.Revision
+	}
+	dialog.ShowCustom("Preview: "+title, "Close", scroll, t.window)
+}
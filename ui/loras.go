@@ -0,0 +1,212 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+// loraRow is a LoRA in the LoRAs tab's list: whether it's part of the stack
+// to load, and the scaling to load it with.
+type loraRow struct {
+	name       string
+	check      *widget.Check
+	slider     *widget.Slider
+	valueLabel *widget.Label
+	available  bool // Listed by the server, rather than only in a preset
+}
+
+func newLoraRow(name string) *loraRow {
+	row := &loraRow{
+		name:       name,
+		slider:     widget.NewSlider(0, 2),
+		valueLabel: widget.NewLabel(""),
+		available:  true,
+	}
+	row.check = widget.NewCheck(name, nil)
+	row.slider.Step = 0.05
+	row.slider.OnChanged = func(value float64) {
+		row.valueLabel.SetText(fmt.Sprintf("%.2f", value))
+	}
+	row.slider.SetValue(1)
+	return row
+}
+
+func (r *loraRow) object() fyne.CanvasObject {
+	r.check.Text = r.name
+	if !r.available {
+		r.check.Text += " (not on server)"
+	}
+	return container.NewGridWithColumns(2,
+		r.check,
+		container.NewBorder(nil, nil, nil, r.valueLabel, r.slider),
+	)
+}
+
+func (t *TabLoad) buildLorasTab() fyne.CanvasObject {
+	t.lorasList = container.NewVBox()
+	t.loadLorasButton = widget.NewButton("Load Selected LoRAs", t.handleLoadLoras)
+	t.unloadLorasButton = widget.NewButton("Unload LoRAs", t.handleUnloadLoras)
+
+	t.loadedLorasTable = widget.NewTable(
+		func() (int, int) { return len(t.loadedLoras) + 1, 2 },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.TableCellID, object fyne.CanvasObject) {
+			label := object.(*widget.Label)
+			if id.Row == 0 {
+				label.TextStyle = fyne.TextStyle{Bold: true}
+				label.SetText([]string{"LoRA", "Scaling"}[id.Col])
+				return
+			}
+			label.TextStyle = fyne.TextStyle{}
+			lora := t.loadedLoras[id.Row-1]
+			if id.Col == 0 {
+				label.SetText(lora.ID)
+			} else {
+				label.SetText(fmt.Sprintf("%.2f", lora.Scaling))
+			}
+		},
+	)
+	t.loadedLorasTable.SetColumnWidth(0, 400)
+	t.loadedLorasTable.SetColumnWidth(1, 100)
+
+	available := container.NewBorder(
+		widget.NewLabel("Available LoRAs (tick to stack, slide to scale):"),
+		container.NewHBox(t.loadLorasButton, t.unloadLorasButton),
+		nil, nil,
+		container.NewVScroll(t.lorasList),
+	)
+	loaded := container.NewBorder(
+		widget.NewLabel("Currently loaded:"), nil, nil, nil,
+		t.loadedLorasTable,
+	)
+	return container.NewVSplit(available, loaded)
+}
+
+// setAvailableLoras rebuilds the LoRA list from the server's LoRAs, keeping
+// the selection and scalings of those already listed.
+func (t *TabLoad) setAvailableLoras(names []string) {
+	existing := make(map[string]*loraRow, len(t.loraRows))
+	for _, row := range t.loraRows {
+		existing[row.name] = row
+	}
+
+	rows := make([]*loraRow, 0, len(names))
+	for _, name := range names {
+		row, ok := existing[name]
+		if !ok {
+			row = newLoraRow(name)
+		}
+		row.available = true
+		delete(existing, name)
+		rows = append(rows, row)
+	}
+	// Keep LoRAs a preset selected even if the server doesn't list them, so
+	// the stack isn't silently changed
+	for _, row := range t.loraRows {
+		if _, ok := existing[row.name]; ok && row.check.Checked {
+			row.available = false
+			rows = append(rows, row)
+		}
+	}
+
+	t.loraRows = rows
+	t.refreshLorasList()
+}
+
+func (t *TabLoad) refreshLorasList() {
+	if t.lorasList == nil {
+		return
+	}
+	t.lorasList.RemoveAll()
+	for _, row := range t.loraRows {
+		t.lorasList.Add(row.object())
+	}
+	t.lorasList.Refresh()
+}
+
+// selectedLoras returns the ticked LoRAs and their scalings, in list order.
+func (t *TabLoad) selectedLoras() []store.LoraSpec {
+	var loras []store.LoraSpec
+	for _, row := range t.loraRows {
+		if row.check.Checked {
+			loras = append(loras, store.LoraSpec{Name: row.name, Scaling: row.slider.Value})
+		}
+	}
+	return loras
+}
+
+// setLoraStack ticks exactly the given LoRAs at their scalings, adding rows
+// for any the server hasn't listed.
+func (t *TabLoad) setLoraStack(loras []store.LoraSpec) {
+	scalings := make(map[string]float64, len(loras))
+	for _, lora := range loras {
+		scalings[lora.Name] = lora.Scaling
+	}
+
+	for _, row := range t.loraRows {
+		scaling, ok := scalings[row.name]
+		row.check.SetChecked(ok)
+		if ok {
+			row.slider.SetValue(scaling)
+			delete(scalings, row.name)
+		}
+	}
+	for _, lora := range loras {
+		if _, ok := scalings[lora.Name]; !ok {
+			continue
+		}
+		row := newLoraRow(lora.Name)
+		row.available = false
+		row.check.SetChecked(true)
+		row.slider.SetValue(lora.Scaling)
+		t.loraRows = append(t.loraRows, row)
+	}
+	t.refreshLorasList()
+}
+
+func (t *TabLoad) handleLoadLoras() {
+	loras := t.selectedLoras()
+	if len(loras) == 0 {
+		dialog.ShowError(fmt.Errorf("select at least one LoRA to load"), t.window)
+		return
+	}
+
+	names, scalings := store.Preset{Loras: loras}.LoraLists()
+	if err := t.client.LoadLorasCtx(t.requestContext(), names, scalings); err != nil {
+		logging.Error("Error loading LoRAs", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	logging.Info(fmt.Sprintf("Loaded %d LoRAs", len(loras)))
+	t.refreshCurrentLoras()
+}
+
+func (t *TabLoad) handleUnloadLoras() {
+	err := t.client.UnloadLorasCtx(t.requestContext())
+	if err != nil {
+		logging.Error("Error unloading LoRAs", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.refreshCurrentLoras()
+}
+
+func (t *TabLoad) refreshCurrentLoras() {
+	loras, err := t.client.FetchLoadedLorasCtx(t.requestContext())
+	if err != nil {
+		logging.Error("Error fetching current LoRAs", err)
+		return
+	}
+	t.loadedLoras = loras
+	if t.loadedLorasTable != nil {
+		t.loadedLorasTable.Refresh()
+	}
+}
@@ -1,17 +1,21 @@
 package ui
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strconv"
 	"strings"
 
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
 	"github.com/sammcj/tabload/logging"
-	"github.com/sammcj/tabload/utils"
 )
 
 func (t *TabLoad) initialiseUIElements() {
@@ -64,11 +68,12 @@ func (t *TabLoad) initialiseUIElements() {
 	t.currentModelLabel = widget.NewLabel("")
 	t.savePresetButton = widget.NewButton("Save Preset", t.handleSavePreset)
 	t.deletePresetButton = widget.NewButton("Delete Preset", t.handleDeletePreset)
+	t.applyPresetButton = widget.NewButton("Apply Preset", t.handleApplyPreset)
 
 	// Set placeholder text for entries
-	t.maxSeqLenEntry.SetPlaceHolder("Enter max sequence length")
+	t.maxSeqLenEntry.SetPlaceHolder("Enter max sequence length, e.g. 8k or 32768")
 	t.overrideBaseSeqLenEntry.SetPlaceHolder("Enter override base seq length")
-	t.cacheSizeEntry.SetPlaceHolder("Enter cache size")
+	t.cacheSizeEntry.SetPlaceHolder("Enter cache size, e.g. 32k")
 	t.gpuSplitEntry.SetPlaceHolder("Enter GPU split")
 	t.ropeScaleEntry.SetPlaceHolder("Enter rope scale")
 	t.ropeAlphaEntry.SetPlaceHolder("Enter rope alpha")
@@ -77,7 +82,7 @@ func (t *TabLoad) initialiseUIElements() {
 	t.draftModelNameEntry.SetPlaceHolder("Enter draft model name")
 	t.draftRopeScaleEntry.SetPlaceHolder("Enter draft rope scale")
 	t.draftRopeAlphaEntry.SetPlaceHolder("Enter draft rope alpha")
-	t.autosplitReserveEntry.SetPlaceHolder("Enter autosplit reserve")
+	t.autosplitReserveEntry.SetPlaceHolder("Enter autosplit reserve, e.g. 96 or 1.5GiB")
 	t.chunkSizeEntry.SetPlaceHolder("Enter chunk size")
 
 	t.refreshPresetList()
@@ -85,7 +90,7 @@ func (t *TabLoad) initialiseUIElements() {
 	// Fetch and set prompt templates
 	// only if we have a client
 	if t.client != nil {
-		templates, err := t.client.FetchTemplates()
+		templates, err := t.client.FetchTemplatesCtx(t.requestContext())
 		if err != nil {
 			logging.Error("Error fetching templates", err)
 			dialog.ShowError(err, t.window)
@@ -125,94 +130,71 @@ func (t *TabLoad) initialiseUIElements() {
 
 // Update the handleLoadModel function to use the new UI elements
 func (t *TabLoad) handleLoadModel() {
-	params := make(map[string]interface{})
-
-	// Only add parameters that have been set by the user
-	if t.modelsDropdown.Selected != "" {
-		params["name"] = t.modelsDropdown.Selected
-	}
-
-	if t.maxSeqLenCheck.Checked {
-		if maxSeqLen, err := strconv.Atoi(t.maxSeqLenEntry.Text); err == nil {
-			params["max_seq_len"] = maxSeqLen
-		}
-	}
-
-	if t.overrideBaseSeqLenCheck.Checked {
-		params["override_base_seq_len"] = utils.ParseIntOrZero(t.overrideBaseSeqLenEntry.Text)
-	}
-
-	if t.cacheSizeCheck.Checked {
-		params["cache_size"] = utils.ParseIntOrZero(t.cacheSizeEntry.Text)
-	}
-
-	if t.gpuSplitAutoCheck.Checked {
-		params["gpu_split_auto"] = true
-	} else if t.gpuSplitCheck.Checked {
-		params["gpu_split"] = t.gpuSplitEntry.Text
-	}
-
-	if t.ropeScaleCheck.Checked {
-		params["rope_scale"] = utils.ParseFloatOrZero(t.ropeScaleEntry.Text)
-	}
-
-	if t.ropeAlphaCheck.Checked {
-		params["rope_alpha"] = utils.ParseFloatOrZero(t.ropeAlphaEntry.Text)
+	preset, err := t.createPresetFromFields()
+	if err != nil {
+		t.presetFieldErrors("load the model", err)
+		return
 	}
 
-	if t.cacheModeDropdown.Selected != "" {
-		params["cache_mode"] = t.cacheModeDropdown.Selected
+	params, err := preset.LoadParams()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
 	}
 
-	if t.promptTemplateCheck.Checked && t.promptTemplateEntry.Text != "" {
-		params["prompt_template"] = t.promptTemplateEntry.Text
-	}
+	t.loadModelWithProgress(params)
+}
 
-	if t.numExpertsPerTokenCheck.Checked {
-		params["num_experts_per_token"] = utils.ParseIntOrZero(t.numExpertsPerTokenEntry.Text)
-	}
+// loadModelWithProgress loads a model in the background, showing the streamed
+// per-module progress in a dialog that also lets the user cancel the load.
+func (t *TabLoad) loadModelWithProgress(params map[string]interface{}) {
+	ctx, cancel := context.WithCancel(t.requestContext())
 
-	if t.fasttensorsCheck.Checked {
-		params["fasttensors"] = true
-	}
+	statusLabel := widget.NewLabel("Waiting for server...")
+	progressBar := widget.NewProgressBar()
+	cancelButton := widget.NewButton("Cancel", func() {
+		logging.Info("Cancelling model load")
+		cancel()
+	})
 
-	if t.autosplitReserveCheck.Checked {
-		params["autosplit_reserve"] = t.autosplitReserveEntry.Text
-	}
+	content := container.NewVBox(statusLabel, progressBar, cancelButton)
+	progressDialog := dialog.NewCustomWithoutButtons("Loading Model", content, t.window)
+	progressDialog.Resize(fyne.NewSize(400, 150))
+	progressDialog.Show()
 
-	if t.chunkSizeCheck.Checked {
-		params["chunk_size"] = utils.ParseIntOrZero(t.chunkSizeEntry.Text)
-	}
+	t.loadModelButton.Disable()
+	t.clearFormErrors()
 
-	// Handle draft model parameters
-	if t.draftModelNameCheck.Checked && t.draftModelNameEntry.Text != "" {
-		draftParams := make(map[string]interface{})
-		draftParams["draft_model_name"] = t.draftModelNameEntry.Text
+	go func() {
+		defer cancel()
 
-		if t.draftRopeScaleCheck.Checked {
-			draftParams["draft_rope_scale"] = utils.ParseFloatOrZero(t.draftRopeScaleEntry.Text)
-		}
+		err := t.client.LoadModelStream(ctx, params, func(progress api.LoadProgress) {
+			modelType := "model"
+			if progress.ModelType == api.ModelTypeDraft {
+				modelType = "draft model"
+			}
+			statusLabel.SetText(fmt.Sprintf("Loading %s: module %d of %d", modelType, progress.Module, progress.Modules))
+			progressBar.SetValue(progress.Fraction())
+		})
 
-		if t.draftRopeAlphaCheck.Checked {
-			draftParams["draft_rope_alpha"] = utils.ParseFloatOrZero(t.draftRopeAlphaEntry.Text)
-		}
+		progressDialog.Hide()
+		t.loadModelButton.Enable()
 
-		if t.draftCacheModeDropdown.Selected != "" {
-			draftParams["draft_cache_mode"] = t.draftCacheModeDropdown.Selected
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				logging.Info("Model load cancelled")
+				dialog.ShowInformation("Cancelled", "Model load was cancelled", t.window)
+			} else {
+				logging.Error("Error loading model", err)
+				t.showFormError(err)
+			}
+			t.refreshCurrentModel()
+			return
 		}
 
-		params["draft"] = draftParams
-	}
-
-	err := t.client.LoadModel(t.modelsDropdown.Selected, params)
-	if err != nil {
-		logging.Error("Error loading model", err)
-		dialog.ShowError(err, t.window)
-		return
-	}
-
-	t.refreshCurrentModel()
-	logging.Info("Model loaded successfully")
+		t.refreshCurrentModel()
+		logging.Info("Model loaded successfully")
+	}()
 }
 
 func (t *TabLoad) buildModelTab() fyne.CanvasObject {
@@ -222,6 +204,7 @@ func (t *TabLoad) buildModelTab() fyne.CanvasObject {
 	t.form = &widget.Form{
 		Items: []*widget.FormItem{},
 	}
+	t.formRows = make(map[string]*formRow)
 
 	// Fetch templates
 	templates := t.loadTemplates()
@@ -244,74 +227,72 @@ func (t *TabLoad) buildModelTab() fyne.CanvasObject {
 
 	t.promptTemplateDropdown = widget.NewSelect(templateNames, func(selected string) {
 		if selected == "Create New..." {
-			t.showCreateTemplateDialog()
+			t.showTemplateEditor("")
 		} else {
 			t.promptTemplateEntry.SetText(strings.TrimSuffix(selected, " (server)"))
 		}
+		t.renderCompletionPreview()
 	})
 
 	deleteButton := widget.NewButton("Delete", func() {
 		if t.promptTemplateDropdown.Selected != "" && t.promptTemplateDropdown.Selected != "Create New..." {
-			t.deleteTemplate(t.promptTemplateDropdown.Selected)
+			t.deleteTemplate(t.promptTemplateDropdown.Selected, nil)
+		}
+	})
+
+	editButton := widget.NewButton("Edit", func() {
+		selected := t.promptTemplateDropdown.Selected
+		if selected == "Create New..." || strings.HasSuffix(selected, " (server)") {
+			selected = ""
 		}
+		t.showTemplateEditor(selected)
 	})
 
-	templateContainer := container.NewBorder(nil, nil, nil, deleteButton, t.promptTemplateDropdown)
+	templateContainer := container.NewBorder(nil, nil, nil, container.NewHBox(editButton, deleteButton), t.promptTemplateDropdown)
 
 	if t.promptTemplateEntry.Text != "" {
 		t.promptTemplateDropdown.SetSelected(t.promptTemplateEntry.Text)
 	}
 
 	// Add rows to the form
-	t.addFormRow("Model", t.modelsDropdown)
-	t.addFormRow("Max Sequence Length", t.createCheckboxEntry(t.maxSeqLenCheck, t.maxSeqLenEntry))
-	t.addFormRow("Override Base Seq Length", t.createCheckboxEntry(t.overrideBaseSeqLenCheck, t.overrideBaseSeqLenEntry))
-	t.addFormRow("Cache Size", t.createCheckboxEntry(t.cacheSizeCheck, t.cacheSizeEntry))
-	t.addFormRow("GPU Split Auto", t.gpuSplitAutoCheck)
-	t.addFormRow("GPU Split", t.createCheckboxEntry(t.gpuSplitCheck, t.gpuSplitEntry))
-	t.addFormRow("Rope Scale", t.createCheckboxEntry(t.ropeScaleCheck, t.ropeScaleEntry))
-	t.addFormRow("Rope Alpha", t.createCheckboxEntry(t.ropeAlphaCheck, t.ropeAlphaEntry))
-	t.addFormRow("Cache Mode", t.cacheModeDropdown)
-	t.addFormRow("Prompt Template", templateContainer)
-	t.addFormRow("Num Experts Per Token", t.createCheckboxEntry(t.numExpertsPerTokenCheck, t.numExpertsPerTokenEntry))
-	t.addFormRow("Draft Model Name", t.createCheckboxEntry(t.draftModelNameCheck, t.draftModelNameEntry))
-	t.addFormRow("Draft Rope Scale", t.createCheckboxEntry(t.draftRopeScaleCheck, t.draftRopeScaleEntry))
-	t.addFormRow("Draft Rope Alpha", t.createCheckboxEntry(t.draftRopeAlphaCheck, t.draftRopeAlphaEntry))
-	t.addFormRow("Draft Cache Mode", t.draftCacheModeDropdown)
-	t.addFormRow("Use Fasttensors", t.fasttensorsCheck)
-	t.addFormRow("Autosplit Reserve", t.createCheckboxEntry(t.autosplitReserveCheck, t.autosplitReserveEntry))
-	t.addFormRow("Chunk Size", t.createCheckboxEntry(t.chunkSizeCheck, t.chunkSizeEntry))
+	t.addFormRow("Model", "name", t.modelsDropdown)
+	t.addFormRow("Max Sequence Length", "max_seq_len", t.createCheckboxEntry(t.maxSeqLenCheck, t.maxSeqLenEntry))
+	t.addFormRow("Override Base Seq Length", "override_base_seq_len", t.createCheckboxEntry(t.overrideBaseSeqLenCheck, t.overrideBaseSeqLenEntry))
+	t.addFormRow("Cache Size", "cache_size", t.createCheckboxEntry(t.cacheSizeCheck, t.cacheSizeEntry))
+	t.addFormRow("GPU Split Auto", "gpu_split_auto", t.gpuSplitAutoCheck)
+	t.addFormRow("GPU Split", "gpu_split", t.createCheckboxEntry(t.gpuSplitCheck, t.gpuSplitEntry))
+	t.addFormRow("Rope Scale", "rope_scale", t.createCheckboxEntry(t.ropeScaleCheck, t.ropeScaleEntry))
+	t.addFormRow("Rope Alpha", "rope_alpha", t.createCheckboxEntry(t.ropeAlphaCheck, t.ropeAlphaEntry))
+	t.addFormRow("Cache Mode", "cache_mode", t.cacheModeDropdown)
+	t.addFormRow("Prompt Template", "prompt_template", templateContainer)
+	t.addFormRow("Num Experts Per Token", "num_experts_per_token", t.createCheckboxEntry(t.numExpertsPerTokenCheck, t.numExpertsPerTokenEntry))
+	t.addFormRow("Draft Model Name", "draft.draft_model_name", t.createCheckboxEntry(t.draftModelNameCheck, t.draftModelNameEntry))
+	t.addFormRow("Draft Rope Scale", "draft.draft_rope_scale", t.createCheckboxEntry(t.draftRopeScaleCheck, t.draftRopeScaleEntry))
+	t.addFormRow("Draft Rope Alpha", "draft.draft_rope_alpha", t.createCheckboxEntry(t.draftRopeAlphaCheck, t.draftRopeAlphaEntry))
+	t.addFormRow("Draft Cache Mode", "draft.draft_cache_mode", t.draftCacheModeDropdown)
+	t.addFormRow("Use Fasttensors", "fasttensors", t.fasttensorsCheck)
+	t.addFormRow("Autosplit Reserve", "autosplit_reserve", t.createCheckboxEntry(t.autosplitReserveCheck, t.autosplitReserveEntry))
+	t.addFormRow("Chunk Size", "chunk_size", t.createCheckboxEntry(t.chunkSizeCheck, t.chunkSizeEntry))
 
 	// Create containers for presets and buttons
-	presetContainer := container.NewHBox(t.presetDropdown, t.savePresetButton, t.deletePresetButton)
+	presetContainer := container.NewHBox(t.presetDropdown, t.applyPresetButton, t.savePresetButton, t.deletePresetButton)
 	buttonsContainer := container.NewHBox(t.loadModelButton, t.unloadModelButton)
 
 	// Combine all elements
+	t.presetNotesLabel = widget.NewLabel("")
+	t.presetNotesLabel.Wrapping = fyne.TextWrapWord
+
 	return container.NewVBox(
 		presetContainer,
+		t.presetNotesLabel,
 		t.form,
 		buttonsContainer,
 		t.currentModelLabel,
 	)
 }
 
-func (t *TabLoad) buildLorasTab() fyne.CanvasObject {
-	t.lorasDropdown = widget.NewSelect([]string{}, func(selected string) {})
-	t.loadLorasButton = widget.NewButton("Load LoRAs", t.handleLoadLoras)
-	t.unloadLorasButton = widget.NewButton("Unload LoRAs", t.handleUnloadLoras)
-
-	t.currentLorasLabel = widget.NewLabel("")
-
-	return container.NewVBox(
-		t.lorasDropdown,
-		t.loadLorasButton,
-		t.unloadLorasButton,
-		t.currentLorasLabel,
-	)
-}
-
 func (t *TabLoad) handleUnloadModel() {
-	err := t.client.UnloadModel()
+	err := t.client.UnloadModelCtx(t.requestContext())
 	if err != nil {
 		// Handle error (e.g., show an error dialog)
 		fmt.Println("Error unloading model:", err)
@@ -321,29 +302,6 @@ func (t *TabLoad) handleUnloadModel() {
 	t.refreshCurrentModel()
 }
 
-func (t *TabLoad) handleLoadLoras() {
-	selectedLoras := []string{t.lorasDropdown.Selected}
-	err := t.client.LoadLoras(selectedLoras, []float64{1.0})
-	if err != nil {
-		// Handle error (e.g., show an error dialog)
-		fmt.Println("Error loading LoRAs:", err)
-		return
-	}
-
-	t.refreshCurrentLoras()
-}
-
-func (t *TabLoad) handleUnloadLoras() {
-	err := t.client.UnloadLoras()
-	if err != nil {
-		// Handle error (e.g., show an error dialog)
-		fmt.Println("Error unloading loras:", err)
-		return
-	}
-
-	t.refreshCurrentLoras()
-}
-
 func (t *TabLoad) refreshCurrentModel() {
 	if t.client == nil {
 		logging.Warn("Cannot refresh current model: client is nil")
@@ -351,42 +309,74 @@ func (t *TabLoad) refreshCurrentModel() {
 		return
 	}
 
-	currentModel, err := t.client.FetchCurrentModel()
+	currentModel, err := t.client.FetchCurrentModelCtx(t.requestContext())
 	if err != nil {
 		logging.Error("Error fetching current model", err)
 		t.updateModelInfoContainer([][]string{{"Error fetching current model", ""}})
+		t.currentModelLabel.SetText("")
 		return
 	}
 
 	if currentModel == nil {
 		logging.Warn("Received nil current model")
 		t.updateModelInfoContainer([][]string{{"No model loaded", ""}})
+		t.currentModelLabel.SetText("")
 		return
 	}
 
-	modelInfo := [][]string{
-		{"Model", currentModel.ID},
-		{"Max Sequence Length", fmt.Sprintf("%d", currentModel.Parameters.MaxSeqLen)},
-		{"Cache Size", fmt.Sprintf("%d", currentModel.Parameters.CacheSize)},
-		{"Rope Scale", fmt.Sprintf("%.2f", currentModel.Parameters.RopeScale)},
-		{"Rope Alpha", fmt.Sprintf("%.2f", currentModel.Parameters.RopeAlpha)},
-	}
+	modelInfo := [][]string{{"Model", currentModel.ID}}
 
-	if currentModel.Parameters.Draft != nil {
+	if params := currentModel.Parameters; params != nil {
 		modelInfo = append(modelInfo,
-			[]string{"Draft Model", currentModel.Parameters.Draft.ID},
-			[]string{"Draft Rope Scale", fmt.Sprintf("%.2f", currentModel.Parameters.Draft.Parameters.RopeScale)},
-			[]string{"Draft Rope Alpha", fmt.Sprintf("%.2f", currentModel.Parameters.Draft.Parameters.RopeAlpha)},
+			[]string{"Max Sequence Length", fmt.Sprintf("%d", params.MaxSeqLen)},
+			[]string{"Cache Size", fmt.Sprintf("%d", params.CacheSize)},
+			[]string{"Cache Mode", params.CacheMode},
+			[]string{"Rope Scale", fmt.Sprintf("%.2f", params.RopeScale)},
+			[]string{"Rope Alpha", fmt.Sprintf("%.2f", params.RopeAlpha)},
 		)
+		if params.ChunkSize > 0 {
+			modelInfo = append(modelInfo, []string{"Chunk Size", fmt.Sprintf("%d", params.ChunkSize)})
+		}
+		if params.PromptTemplate != "" {
+			modelInfo = append(modelInfo, []string{"Prompt Template", params.PromptTemplate})
+		}
+		if params.NumExpertsPerToken > 0 {
+			modelInfo = append(modelInfo, []string{"Experts Per Token", fmt.Sprintf("%d", params.NumExpertsPerToken)})
+		}
+		if params.GPUSplitAuto {
+			modelInfo = append(modelInfo, []string{"GPU Split", "auto"})
+		} else if len(params.GPUSplit) > 0 {
+			modelInfo = append(modelInfo, []string{"GPU Split", formatGPUSplit(params.GPUSplit)})
+		}
+	}
+
+	if draft := currentModel.Draft(); draft != nil {
+		modelInfo = append(modelInfo, []string{"Draft Model", draft.ID})
+		if params := draft.Parameters; params != nil {
+			modelInfo = append(modelInfo,
+				[]string{"Draft Rope Scale", fmt.Sprintf("%.2f", params.RopeScale)},
+				[]string{"Draft Rope Alpha", fmt.Sprintf("%.2f", params.RopeAlpha)},
+			)
+			if params.CacheMode != "" {
+				modelInfo = append(modelInfo, []string{"Draft Cache Mode", params.CacheMode})
+			}
+		}
 	}
 
+	t.currentModelLabel.SetText(currentModel.Summary())
 	t.updateModelInfoContainer(modelInfo)
 }
 
-func (t *TabLoad) addFormRow(label string, widget fyne.CanvasObject) {
-	// t.form.Add(widget.NewLabel(label))
-	// t.form.Add(widget)
-	t.form.Append(label, widget)
+// addFormRow appends a row to the model form. param is the load request field
+// the row sets (dotted for nested fields), so server validation errors can be
+// shown against it.
+func (t *TabLoad) addFormRow(label, param string, input fyne.CanvasObject) {
+	hint := canvas.NewText("", theme.ErrorColor())
+	hint.TextSize = theme.CaptionTextSize()
+	hint.Hide()
+
+	t.form.Append(label, container.NewVBox(input, hint))
+	t.formRows[param] = &formRow{label: label, hint: hint}
 	t.form.Refresh()
 }
 
@@ -406,3 +396,12 @@ func (t *TabLoad) handleModelSelection(modelName string) {
 		// Load model-specific parameters if available
 	}
 }
+
+// formatGPUSplit formats a GPU split as TabbyAPI accepts it, e.g. "20,24".
+func formatGPUSplit(split []float64) string {
+	parts := make([]string, len(split))
+	for i, gb := range split {
+		parts[i] = strconv.FormatFloat(gb, 'f', -1, 64)
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,224 @@
+package ui
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+// overrideDiff is a sampler whose override differs from the Advanced tab.
+type overrideDiff struct {
+	key      string
+	override api.OverrideValue
+	current  interface{} // nil if the Advanced tab doesn't set it
+}
+
+func (t *TabLoad) buildOverridesTab() fyne.CanvasObject {
+	t.overridesList = widget.NewList(
+		func() int {
+			if t.overrideStatus == nil {
+				return 0
+			}
+			return len(t.overrideStatus.Presets)
+		},
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, item fyne.CanvasObject) {
+			name := t.overrideStatus.Presets[id]
+			if name == t.overrideStatus.Preset {
+				name += " (active)"
+			}
+			item.(*widget.Label).SetText(name)
+		},
+	)
+	t.overridesList.OnSelected = func(id widget.ListItemID) {
+		t.selectedOverride = t.overrideStatus.Presets[id]
+		t.showOverrideDiff()
+	}
+
+	t.activeOverrideLabel = widget.NewLabel("Not connected")
+
+	t.overrideDiffLabel = widget.NewLabel("")
+	t.overrideDiffLabel.TextStyle = fyne.TextStyle{Monospace: true}
+
+	refreshButton := widget.NewButton("Refresh", t.refreshOverrides)
+	switchButton := widget.NewButton("Switch", t.handleSwitchOverride)
+	unloadButton := widget.NewButton("Unload", t.handleUnloadOverride)
+
+	top := container.NewVBox(
+		t.activeOverrideLabel,
+		container.NewHBox(refreshButton, switchButton, unloadButton),
+	)
+	diff := container.NewBorder(widget.NewLabel("Compared with the Advanced tab:"), nil, nil, nil, container.NewScroll(t.overrideDiffLabel))
+
+	split := container.NewHSplit(t.overridesList, diff)
+	split.Offset = 0.3
+
+	return container.NewBorder(top, nil, nil, nil, split)
+}
+
+// refreshOverrides fetches the server's override presets and which is active.
+func (t *TabLoad) refreshOverrides() {
+	if t.overridesList == nil {
+		return
+	}
+
+	status, err := t.client.FetchOverrideStatusCtx(t.requestContext())
+	if err != nil {
+		logging.Error("Error fetching sampler overrides", err)
+		t.activeOverrideLabel.SetText("Error fetching sampler overrides")
+		return
+	}
+
+	t.overrideStatus = status
+	switch {
+	case status.Preset != "":
+		t.activeOverrideLabel.SetText(fmt.Sprintf("Active preset: %s (%d overrides)", status.Preset, len(status.Overrides)))
+	case len(status.Overrides) > 0:
+		t.activeOverrideLabel.SetText(fmt.Sprintf("Active: custom overrides (%d)", len(status.Overrides)))
+	default:
+		t.activeOverrideLabel.SetText("Active: none")
+	}
+
+	t.overridesList.Refresh()
+	t.showOverrideDiff()
+}
+
+func (t *TabLoad) handleSwitchOverride() {
+	if t.selectedOverride == "" {
+		dialog.ShowInformation("Error", "Please select a preset to switch to", t.window)
+		return
+	}
+
+	if err := t.client.LoadOverrideCtx(t.requestContext(), t.selectedOverride); err != nil {
+		logging.Error("Error switching sampler override preset", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	logging.Info(fmt.Sprintf("Switched to sampler override preset '%s'", t.selectedOverride))
+	t.refreshOverrides()
+}
+
+func (t *TabLoad) handleUnloadOverride() {
+	if err := t.client.UnloadOverrideCtx(t.requestContext()); err != nil {
+		logging.Error("Error unloading sampler overrides", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	logging.Info("Sampler overrides unloaded")
+	t.refreshOverrides()
+}
+
+// showOverrideDiff shows what switching to the selected preset would change
+// compared to the Advanced tab's values.
+func (t *TabLoad) showOverrideDiff() {
+	if t.selectedOverride == "" || t.overrideStatus == nil {
+		t.overrideDiffLabel.SetText("Select a preset to compare it")
+		return
+	}
+
+	overrides, ok := t.overrideValues(t.selectedOverride)
+	if !ok {
+		t.overrideDiffLabel.SetText(fmt.Sprintf("The server only reports the values of the active preset.\nSwitch to '%s', or save a sampler profile of the same name, to compare it.", t.selectedOverride))
+		return
+	}
+
+	current, err := t.samplingParams()
+	if err != nil {
+		t.overrideDiffLabel.SetText(err.Error())
+		return
+	}
+
+	diffs := diffOverrides(overrides, current)
+	if len(diffs) == 0 {
+		t.overrideDiffLabel.SetText(fmt.Sprintf("'%s' matches the Advanced tab", t.selectedOverride))
+		return
+	}
+	t.overrideDiffLabel.SetText(formatOverrideDiffs(diffs))
+}
+
+// overrideValues returns the overrides of a preset: the server's if it's the
+// active preset, otherwise those of a local sampler profile of the same name.
+func (t *TabLoad) overrideValues(name string) (map[string]api.OverrideValue, bool) {
+	if name == t.overrideStatus.Preset {
+		return t.overrideStatus.Overrides, true
+	}
+
+	profile, err := t.samplerProfile(name)
+	if err != nil {
+		return nil, false
+	}
+	return overrideValuesFromProfile(profile), true
+}
+
+func overrideValuesFromProfile(profile *store.SamplerProfile) map[string]api.OverrideValue {
+	values := make(map[string]api.OverrideValue, len(profile.Overrides))
+	for key, override := range profile.Overrides {
+		values[key] = api.OverrideValue{
+			Override: override.Override,
+			Force:    override.Force,
+			Additive: override.Additive,
+		}
+	}
+	return values
+}
+
+// diffOverrides returns the overrides whose value differs from current,
+// sorted by sampler.
+func diffOverrides(overrides map[string]api.OverrideValue, current map[string]interface{}) []overrideDiff {
+	var diffs []overrideDiff
+	for key, override := range overrides {
+		value, ok := current[key]
+		if ok && valuesEqual(override.Override, value) {
+			continue
+		}
+		diffs = append(diffs, overrideDiff{key: key, override: override, current: value})
+	}
+
+	sort.Slice(diffs, func(i, j int) bool { return diffs[i].key < diffs[j].key })
+	return diffs
+}
+
+// valuesEqual compares sampler values, treating numbers of any type as equal
+// if they are within the Advanced tab's slider precision.
+func valuesEqual(a, b interface{}) bool {
+	af, aNumber := toFloat(a)
+	bf, bNumber := toFloat(b)
+	if aNumber && bNumber {
+		diff := af - bf
+		return diff < 0.005 && diff > -0.005
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func formatOverrideDiffs(diffs []overrideDiff) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "%-20s %-18s %s\n", "Sampler", "Advanced tab", "Preset")
+	for _, diff := range diffs {
+		current := "(not set)"
+		if diff.current != nil {
+			current = fmt.Sprint(diff.current)
+		}
+
+		override := fmt.Sprint(diff.override.Override)
+		if diff.override.Force {
+			override += " (forced)"
+		}
+		if diff.override.Additive {
+			override += " (additive)"
+		}
+
+		fmt.Fprintf(&sb, "%-20s %-18s %s\n", diff.key, current, override)
+	}
+	return sb.String()
+}
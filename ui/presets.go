@@ -1,112 +1,23 @@
 package ui
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
-	"os"
-	"path/filepath"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
 	"github.com/sammcj/tabload/logging"
-	"github.com/sammcj/tabload/utils"
+	"github.com/sammcj/tabload/store"
 )
 
-var cachedPresets []Preset
-
 func (t *TabLoad) loadPresetsFromStorage() ([]Preset, error) {
-	if cachedPresets != nil {
-		return cachedPresets, nil
-	}
-
-	defaultPresets := []Preset{
-		{
-			Name:           "Default Preset",
-			CacheMode:      "Q4",
-			DraftCacheMode: "Q4",
-		},
-	}
-
-	logging.Info(fmt.Sprintf("Loading presets from: %s", presetsFilePath))
-
-	data, err := os.ReadFile(presetsFilePath)
-	if err != nil {
-		if os.IsNotExist(err) {
-			logging.Info("Presets file not found. Using default presets.")
-			cachedPresets = defaultPresets
-			return cachedPresets, nil
-		}
-		return nil, fmt.Errorf("error reading presets file: %w", err)
-	}
-
-	if len(data) == 0 {
-		logging.Info("Presets file is empty. Using default presets.")
-		cachedPresets = defaultPresets
-		return cachedPresets, nil
-	}
-
-	var presetsWrapper struct {
-		Presets []Preset `json:"presets"`
-	}
-
-	err = json.Unmarshal(data, &presetsWrapper)
-	if err != nil {
-		return nil, fmt.Errorf("error unmarshalling presets: %w", err)
-	}
-
-	loadedPresets := presetsWrapper.Presets
-
-	// Merge loaded presets with default presets
-	presetMap := make(map[string]Preset)
-	for _, p := range defaultPresets {
-		presetMap[p.Name] = p
-	}
-	for _, p := range loadedPresets {
-		presetMap[p.Name] = p
-	}
-
-	mergedPresets := make([]Preset, 0, len(presetMap))
-	for _, p := range presetMap {
-		mergedPresets = append(mergedPresets, p)
-	}
-
-	cachedPresets = mergedPresets
-	logging.Info(fmt.Sprintf("Loaded %d presets", len(mergedPresets)))
-
-	return cachedPresets, nil
-}
-
-func (t *TabLoad) savePresetsToStorage(presets []Preset) error {
-	presetsWrapper := struct {
-		Presets []Preset `json:"presets"`
-	}{
-		Presets: presets,
-	}
-
-	data, err := json.MarshalIndent(presetsWrapper, "", "  ")
-	if err != nil {
-		return fmt.Errorf("error marshalling presets: %w", err)
-	}
-
-	if err := os.MkdirAll(filepath.Dir(presetsFilePath), 0755); err != nil {
-		return fmt.Errorf("error creating presets directory: %w", err)
-	}
-
-	if err := os.WriteFile(presetsFilePath, data, 0644); err != nil {
-		return fmt.Errorf("error writing presets file: %w", err)
-	}
-
-	return nil
+	return t.presetStore.Presets()
 }
 
 func (t *TabLoad) validatePreset(preset Preset) error {
-	if preset.Name == "" {
-		return fmt.Errorf("preset name cannot be empty")
-	}
-	// Add more validation rules as needed
-	return nil
+	return preset.Validate()
 }
 
 func (t *TabLoad) savePresetToStorage(preset Preset) error {
@@ -114,67 +25,15 @@ func (t *TabLoad) savePresetToStorage(preset Preset) error {
 		return fmt.Errorf("invalid preset: %w", err)
 	}
 
-	presets, err := t.loadPresetsFromStorage()
-	if err != nil {
-		return fmt.Errorf("error loading existing presets: %w", err)
-	}
-
-	updated := false
-	for i, p := range presets {
-		if p.Name == preset.Name {
-			presets[i] = preset
-			updated = true
-			break
-		}
-	}
-	if !updated {
-		presets = append(presets, preset)
-	}
-
-	return t.savePresetsToStorage(presets)
+	return t.presetStore.SavePreset(preset)
 }
 
 func (t *TabLoad) loadPresetFromStorage(presetName string) (*Preset, error) {
-	presets, err := t.loadPresetsFromStorage()
-	if err != nil {
-		return nil, fmt.Errorf("error loading presets: %w", err)
-	}
-
-	for _, preset := range presets {
-		if preset.Name == presetName {
-			return &preset, nil
-		}
-	}
-
-	return nil, fmt.Errorf("preset not found: %s", presetName)
+	return t.presetStore.Preset(presetName)
 }
 
 func (t *TabLoad) deletePresetFromStorage(presetName string) error {
-	presets, err := t.loadPresetsFromStorage()
-	if err != nil {
-		return fmt.Errorf("error loading existing presets: %w", err)
-	}
-
-	newPresets := make([]Preset, 0, len(presets))
-	found := false
-	for _, p := range presets {
-		if p.Name != presetName {
-			newPresets = append(newPresets, p)
-		} else {
-			found = true
-		}
-	}
-
-	if !found {
-		return fmt.Errorf("preset not found: %s", presetName)
-	}
-
-	// take a backup of the presets file before deleting
-	if err := os.Rename(presetsFilePath, presetsFilePath+".bak"); err != nil {
-		return fmt.Errorf("error taking backup of presets file: %w", err)
-	}
-
-	return t.savePresetsToStorage(newPresets)
+	return t.presetStore.DeletePreset(presetName)
 }
 
 // func (t *TabLoad) refreshPresetList() {
@@ -235,18 +94,50 @@ func (t *TabLoad) refreshPresetList() {
 }
 
 func (t *TabLoad) handleSavePreset() {
+	preset, err := t.createPresetFromFields()
+	if err != nil {
+		t.presetFieldErrors("save the preset", err)
+		return
+	}
+	t.clearFormErrors()
+
+	// Start from the selected preset so re-saving it keeps its name, notes
+	// and sampler override
+	current := &Preset{}
+	if selected := t.presetDropdown.Selected; selected != "" && selected != "(Select one)" {
+		if preset, err := t.loadPresetFromStorage(selected); err == nil {
+			current = preset
+		}
+	}
+
 	presetName := widget.NewEntry()
 	presetName.SetPlaceHolder("Enter preset name")
+	presetName.SetText(current.Name)
+
+	samplerOverride := widget.NewSelect(t.samplerOverrideOptions(), nil)
+	samplerOverride.SetSelected(noSamplerOverride)
+	if current.SamplerOverride != "" {
+		samplerOverride.SetSelected(current.SamplerOverride)
+	}
+
+	notes := widget.NewMultiLineEntry()
+	notes.SetPlaceHolder("What this preset is for, hardware it suits, etc.")
+	notes.SetText(current.Notes)
 
 	dialog.ShowForm("Save Preset", "Save", "Cancel", []*widget.FormItem{
 		widget.NewFormItem("Preset Name", presetName),
+		widget.NewFormItem("Sampler Override", samplerOverride),
+		widget.NewFormItem("Notes", notes),
 	}, func(save bool) {
 		if !save || presetName.Text == "" {
 			return
 		}
 
-		preset := t.createPresetFromFields()
 		preset.Name = presetName.Text
+		if samplerOverride.Selected != noSamplerOverride {
+			preset.SamplerOverride = samplerOverride.Selected
+		}
+		preset.Notes = notes.Text
 
 		if err := t.savePresetToStorage(preset); err != nil {
 			logging.Error("Failed to save preset", err)
@@ -255,19 +146,43 @@ func (t *TabLoad) handleSavePreset() {
 		}
 
 		t.refreshPresetList()
+		t.presetDropdown.SetSelected(preset.Name)
 		logging.Info(fmt.Sprintf("Preset '%s' saved successfully", preset.Name))
 		dialog.ShowInformation("Success", "Preset saved successfully", t.window)
 	}, t.window)
 }
 
+// noSamplerOverride is the sampler override option for presets that leave
+// the server's overrides alone.
+const noSamplerOverride = "(None)"
+
+// samplerOverrideOptions lists the local sampler profiles followed by the
+// server's override presets that aren't shadowed by a profile.
+func (t *TabLoad) samplerOverrideOptions() []string {
+	options := []string{noSamplerOverride}
+	seen := make(map[string]bool)
+	for _, name := range t.samplerProfileNames() {
+		seen[name] = true
+		options = append(options, name)
+	}
+	if t.overrideStatus != nil {
+		for _, name := range t.overrideStatus.Presets {
+			if !seen[name] {
+				options = append(options, name)
+			}
+		}
+	}
+	return options
+}
+
 func (t *TabLoad) applyPresetToFields(preset *Preset) {
 	// Helper function to set entry text and enable if value is present
-	setEntryText := func(entry *widget.Entry, checkbox *widget.Check, value interface{}) {
+	setEntryText := func(entry *widget.Entry, checkbox *widget.Check, value string) {
 		if entry == nil || checkbox == nil {
 			return
 		}
-		if value != nil && value != "" {
-			entry.SetText(fmt.Sprintf("%v", value))
+		if value != "" {
+			entry.SetText(value)
 			checkbox.SetChecked(true)
 			entry.Enable()
 		} else {
@@ -279,25 +194,28 @@ func (t *TabLoad) applyPresetToFields(preset *Preset) {
 
 	// Apply preset values to fields
 	if t.modelsDropdown != nil {
-		t.modelsDropdown.SetSelected(preset.Name)
+		t.modelsDropdown.SetSelected(preset.ModelID)
 	}
-	setEntryText(t.maxSeqLenEntry, t.maxSeqLenCheck, preset.MaxSeqLen)
-	setEntryText(t.overrideBaseSeqLenEntry, t.overrideBaseSeqLenCheck, preset.OverrideBaseSeqLen)
-	setEntryText(t.cacheSizeEntry, t.cacheSizeCheck, preset.CacheSize)
+	if t.presetNotesLabel != nil {
+		t.presetNotesLabel.SetText(preset.Notes)
+	}
+	setEntryText(t.maxSeqLenEntry, t.maxSeqLenCheck, pointerText(preset.MaxSeqLen))
+	setEntryText(t.overrideBaseSeqLenEntry, t.overrideBaseSeqLenCheck, pointerText(preset.OverrideBaseSeqLen))
+	setEntryText(t.cacheSizeEntry, t.cacheSizeCheck, pointerText(preset.CacheSize))
 	if t.gpuSplitAutoCheck != nil {
 		t.gpuSplitAutoCheck.SetChecked(preset.GPUSplitAuto)
 	}
 	setEntryText(t.gpuSplitEntry, t.gpuSplitCheck, preset.GPUSplit)
-	setEntryText(t.ropeScaleEntry, t.ropeScaleCheck, preset.RopeScale)
-	setEntryText(t.ropeAlphaEntry, t.ropeAlphaCheck, preset.RopeAlpha)
+	setEntryText(t.ropeScaleEntry, t.ropeScaleCheck, pointerText(preset.RopeScale))
+	setEntryText(t.ropeAlphaEntry, t.ropeAlphaCheck, pointerText(preset.RopeAlpha))
 	if t.cacheModeDropdown != nil {
 		t.cacheModeDropdown.SetSelected(preset.CacheMode)
 	}
-	setEntryText(t.promptTemplateEntry, t.promptTemplateCheck, preset.PromptTemplate)
-	setEntryText(t.numExpertsPerTokenEntry, t.numExpertsPerTokenCheck, preset.NumExpertsPerToken)
-	setEntryText(t.draftModelNameEntry, t.draftModelNameCheck, preset.DraftModelName)
-	setEntryText(t.draftRopeScaleEntry, t.draftRopeScaleCheck, preset.DraftRopeScale)
-	setEntryText(t.draftRopeAlphaEntry, t.draftRopeAlphaCheck, preset.DraftRopeAlpha)
+	setEntryText(t.promptTemplateEntry, t.promptTemplateCheck, pointerText(preset.PromptTemplate))
+	setEntryText(t.numExpertsPerTokenEntry, t.numExpertsPerTokenCheck, pointerText(preset.NumExpertsPerToken))
+	setEntryText(t.draftModelNameEntry, t.draftModelNameCheck, pointerText(preset.DraftModelName))
+	setEntryText(t.draftRopeScaleEntry, t.draftRopeScaleCheck, pointerText(preset.DraftRopeScale))
+	setEntryText(t.draftRopeAlphaEntry, t.draftRopeAlphaCheck, pointerText(preset.DraftRopeAlpha))
 	if t.draftCacheModeDropdown != nil {
 		t.draftCacheModeDropdown.SetSelected(preset.DraftCacheMode)
 	}
@@ -305,33 +223,61 @@ func (t *TabLoad) applyPresetToFields(preset *Preset) {
 		t.fasttensorsCheck.SetChecked(preset.Fasttensors)
 	}
 	setEntryText(t.autosplitReserveEntry, t.autosplitReserveCheck, preset.AutosplitReserve)
-	setEntryText(t.chunkSizeEntry, t.chunkSizeCheck, preset.ChunkSize)
+	setEntryText(t.chunkSizeEntry, t.chunkSizeCheck, pointerText(preset.ChunkSize))
+	t.setLoraStack(preset.Loras)
+}
+
+// pointerText formats an optional preset value for its entry, nil being "".
+func pointerText[T int | float64 | string](v *T) string {
+	if v == nil {
+		return ""
+	}
+	return fmt.Sprint(*v)
 }
 
-func (t *TabLoad) createPresetFromFields() Preset {
+// createPresetFromFields reads the model form into a preset. Fields that don't
+// parse or that TabbyAPI would reject are returned as store.ValidationErrors.
+func (t *TabLoad) createPresetFromFields() (Preset, error) {
+	var f fieldParser
 	preset := Preset{
-		Name:             t.modelsDropdown.Selected,
+		ModelID:          t.modelsDropdown.Selected,
 		GPUSplitAuto:     t.gpuSplitAutoCheck.Checked,
-		GPUSplit:         t.gpuSplitEntry.Text,
+		GPUSplit:         f.text(t.gpuSplitCheck, t.gpuSplitEntry),
 		CacheMode:        t.cacheModeDropdown.Selected,
-		PromptTemplate:   utils.ParseStringPointer(t.promptTemplateEntry.Text),
+		PromptTemplate:   f.stringPointer(t.promptTemplateCheck, t.promptTemplateEntry),
+		DraftModelName:   f.stringPointer(t.draftModelNameCheck, t.draftModelNameEntry),
 		DraftCacheMode:   t.draftCacheModeDropdown.Selected,
 		Fasttensors:      t.fasttensorsCheck.Checked,
-		AutosplitReserve: t.autosplitReserveEntry.Text,
+		AutosplitReserve: f.text(t.autosplitReserveCheck, t.autosplitReserveEntry),
 	}
 
-	preset.MaxSeqLen = utils.ParseIntPointer(t.maxSeqLenEntry.Text)
-	preset.OverrideBaseSeqLen = utils.ParseIntPointer(t.overrideBaseSeqLenEntry.Text)
-	preset.CacheSize = utils.ParseIntPointer(t.cacheSizeEntry.Text)
-	preset.RopeScale = utils.ParseFloat64Pointer(t.ropeScaleEntry.Text)
-	preset.RopeAlpha = utils.ParseFloat64Pointer(t.ropeAlphaEntry.Text)
-	preset.NumExpertsPerToken = utils.ParseIntPointer(t.numExpertsPerTokenEntry.Text)
-	preset.DraftModelName = utils.ParseStringPointer(t.draftModelNameEntry.Text)
-	preset.DraftRopeScale = utils.ParseFloat64Pointer(t.draftRopeScaleEntry.Text)
-	preset.DraftRopeAlpha = utils.ParseFloat64Pointer(t.draftRopeAlphaEntry.Text)
-	preset.ChunkSize = utils.ParseIntPointer(t.chunkSizeEntry.Text)
-
-	return preset
+	preset.MaxSeqLen = f.tokens("max_seq_len", t.maxSeqLenCheck, t.maxSeqLenEntry)
+	preset.OverrideBaseSeqLen = f.tokens("override_base_seq_len", t.overrideBaseSeqLenCheck, t.overrideBaseSeqLenEntry)
+	preset.CacheSize = f.tokens("cache_size", t.cacheSizeCheck, t.cacheSizeEntry)
+	preset.RopeScale = f.float("rope_scale", t.ropeScaleCheck, t.ropeScaleEntry)
+	preset.RopeAlpha = f.float("rope_alpha", t.ropeAlphaCheck, t.ropeAlphaEntry)
+	preset.NumExpertsPerToken = f.int("num_experts_per_token", t.numExpertsPerTokenCheck, t.numExpertsPerTokenEntry)
+	preset.DraftRopeScale = f.float("draft.draft_rope_scale", t.draftRopeScaleCheck, t.draftRopeScaleEntry)
+	preset.DraftRopeAlpha = f.float("draft.draft_rope_alpha", t.draftRopeAlphaCheck, t.draftRopeAlphaEntry)
+	preset.ChunkSize = f.tokens("chunk_size", t.chunkSizeCheck, t.chunkSizeEntry)
+	preset.Loras = t.selectedLoras()
+
+	errs := append(f.errs, preset.ValidateLoadParams()...)
+	if len(errs) > 0 {
+		return preset, errs
+	}
+	return preset, nil
+}
+
+// presetFieldErrors shows err inline on the model form if it's a validation
+// failure, and reports whether it was.
+func (t *TabLoad) presetFieldErrors(action string, err error) bool {
+	var errs store.ValidationErrors
+	if !errors.As(err, &errs) {
+		return false
+	}
+	t.showValidationErrors(action, errs)
+	return true
 }
 
 func (t *TabLoad) handleLoadPreset(selectedPreset string) {
@@ -382,6 +328,9 @@ func (t *TabLoad) clearAllFields() {
 	t.fasttensorsCheck.SetChecked(false)
 	t.cacheModeDropdown.SetSelected("")
 	t.draftCacheModeDropdown.SetSelected("")
+	if t.presetNotesLabel != nil {
+		t.presetNotesLabel.SetText("")
+	}
 }
 
 func (t *TabLoad) handleDeletePreset() {
@@ -411,6 +360,18 @@ func (t *TabLoad) buildPresetTab() fyne.CanvasObject {
 
 	saveButton := widget.NewButton("Save Preset", t.handleSavePreset)
 	deleteButton := widget.NewButton("Delete Preset", t.handleDeletePreset)
-
-	return container.NewVBox(t.presetDropdown, saveButton, deleteButton)
+	exportButton := widget.NewButton("Export Presets...", t.handleExportPresets)
+	importButton := widget.NewButton("Import Presets...", t.handleImportPresets)
+
+	return container.NewVBox(
+		t.presetDropdown,
+		saveButton,
+		deleteButton,
+		widget.NewSeparator(),
+		exportButton,
+		importButton,
+		widget.NewButton("Export as TabbyAPI config.yml...", t.handleExportTabbyConfig),
+		widget.NewButton("Import TabbyAPI config.yml...", t.handleImportTabbyConfig),
+		widget.NewLabel("Drop preset files or a TabbyAPI config.yml here to import them."),
+	)
 }
@@ -0,0 +1,70 @@
+package ui
+
+import (
+	"reflect"
+	"testing"
+
+	"fyne.io/fyne/v2/test"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
+)
+
+func TestPresetFieldsRoundTrip(t *testing.T) {
+	test.NewApp()
+	st := store.NewMemoryStore()
+	tl := NewTabLoad(test.NewWindow(nil), st, st, st, secrets.NewMemoryStore())
+
+	maxSeqLen, numExperts := 32768, 2
+	ropeAlpha, draftRopeScale := 2.5, 1.0
+	template, draft := "chatml", "tiny"
+	tests := []struct {
+		name   string
+		preset Preset
+	}{
+		{name: "nothing set", preset: Preset{ModelID: "llama-8b", GPUSplitAuto: true}},
+		{
+			name: "every field set",
+			preset: Preset{
+				ModelID: "llama-70b", MaxSeqLen: &maxSeqLen, GPUSplit: "20,24.5", RopeAlpha: &ropeAlpha,
+				CacheMode: "Q6", PromptTemplate: &template, NumExpertsPerToken: &numExperts,
+				DraftModelName: &draft, DraftRopeScale: &draftRopeScale, AutosplitReserve: "96",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tl.modelsDropdown.Options = []string{tt.preset.ModelID}
+			tl.applyPresetToFields(&tt.preset)
+			if text := tl.maxSeqLenEntry.Text; tt.preset.MaxSeqLen != nil && text != "32768" {
+				t.Errorf("max_seq_len entry = %q, want 32768", text)
+			}
+
+			got, err := tl.createPresetFromFields()
+			if err != nil {
+				t.Fatalf("fields don't read back: %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.preset) {
+				t.Errorf("got %+v, want %+v", got, tt.preset)
+			}
+		})
+	}
+}
+
+func TestPointerText(t *testing.T) {
+	i, f, s := 8192, 0.5, "chatml"
+	tests := []struct {
+		got, want string
+	}{
+		{pointerText(&i), "8192"},
+		{pointerText((*int)(nil)), ""},
+		{pointerText(&f), "0.5"},
+		{pointerText((*float64)(nil)), ""},
+		{pointerText(&s), "chatml"},
+		{pointerText((*string)(nil)), ""},
+	}
+	for _, tt := range tests {
+		if tt.got != tt.want {
+			t.Errorf("got %q, want %q", tt.got, tt.want)
+		}
+	}
+}
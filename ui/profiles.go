@@ -0,0 +1,250 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
+)
+
+const newProfileOption = "New Profile..."
+
+// buildProfileSelector returns the profile switcher shown at the top of the
+// Connection tab, and creates the widgets for the profile's details.
+func (t *TabLoad) buildProfileSelector() fyne.CanvasObject {
+	t.profileNameEntry = widget.NewEntry()
+	t.profileNameEntry.SetPlaceHolder("Profile name, e.g. gpu-host-1")
+
+	t.defaultPresetDropdown = widget.NewSelect(t.presetNames(), nil)
+	t.defaultPresetDropdown.PlaceHolder = "(None)"
+
+	t.tlsInsecureCheck = widget.NewCheck("Skip certificate verification", nil)
+
+	t.caCertEntry = widget.NewEntry()
+	t.caCertEntry.SetPlaceHolder("Path to CA certificate (PEM)")
+
+	t.profileDropdown = widget.NewSelect(t.profileOptions(), func(selected string) {
+		if selected == newProfileOption {
+			t.clearProfileFields()
+			return
+		}
+		t.fillProfileFields(selected)
+	})
+	t.profileDropdown.PlaceHolder = "(No profile)"
+
+	saveButton := widget.NewButton("Save Profile", t.handleSaveProfile)
+	deleteButton := widget.NewButton("Delete Profile", t.handleDeleteProfile)
+
+	if t.config.LastProfile != "" {
+		t.selectProfile(t.config.LastProfile)
+	}
+
+	return container.NewBorder(nil, nil, widget.NewLabel("Profile:"), container.NewHBox(saveButton, deleteButton), t.profileDropdown)
+}
+
+// buildProfileDetails returns the per-profile settings shown below the
+// connection fields.
+func (t *TabLoad) buildProfileDetails() fyne.CanvasObject {
+	return widget.NewForm(
+		widget.NewFormItem("Profile Name", t.profileNameEntry),
+		widget.NewFormItem("Default Preset", t.defaultPresetDropdown),
+		widget.NewFormItem("TLS", t.tlsInsecureCheck),
+		widget.NewFormItem("CA Certificate", t.caCertEntry),
+	)
+}
+
+func (t *TabLoad) profileOptions() []string {
+	return append(t.config.ProfileNames(), newProfileOption)
+}
+
+func (t *TabLoad) presetNames() []string {
+	presets, err := t.loadPresetsFromStorage()
+	if err != nil {
+		logging.Error("Error loading presets", err)
+		return nil
+	}
+
+	names := make([]string, len(presets))
+	for i, preset := range presets {
+		names[i] = preset.Name
+	}
+	return names
+}
+
+// selectedProfileName returns the name of the selected saved profile, or ""
+// when connecting without one.
+func (t *TabLoad) selectedProfileName() string {
+	if t.profileDropdown == nil {
+		return ""
+	}
+	if _, ok := t.config.Profile(t.profileDropdown.Selected); !ok {
+		return ""
+	}
+	return t.profileDropdown.Selected
+}
+
+// selectProfile selects a saved profile and fills the connection fields from
+// it.
+func (t *TabLoad) selectProfile(name string) {
+	if t.profileDropdown == nil {
+		return
+	}
+	t.profileDropdown.SetSelected(name)
+}
+
+func (t *TabLoad) fillProfileFields(name string) {
+	profile, ok := t.config.Profile(name)
+	if !ok {
+		return
+	}
+
+	t.apiURLEntry.SetText(profile.URL)
+	t.adminKeyEntry.SetText(t.secret(profile.AdminKeyRef))
+	t.apiKeyEntry.SetText(t.secret(profile.APIKeyRef))
+	t.profileNameEntry.SetText(profile.Name)
+	t.defaultPresetDropdown.Options = t.presetNames()
+	t.defaultPresetDropdown.SetSelected(profile.DefaultPreset)
+	t.tlsInsecureCheck.SetChecked(profile.TLS.InsecureSkipVerify)
+	t.caCertEntry.SetText(profile.TLS.CACertFile)
+}
+
+func (t *TabLoad) clearProfileFields() {
+	t.profileNameEntry.SetText("")
+	t.apiURLEntry.SetText("")
+	t.adminKeyEntry.SetText("")
+	t.apiKeyEntry.SetText("")
+	t.defaultPresetDropdown.ClearSelected()
+	t.tlsInsecureCheck.SetChecked(false)
+	t.caCertEntry.SetText("")
+}
+
+func (t *TabLoad) tlsOptionsFromFields() api.TLSOptions {
+	if t.tlsInsecureCheck == nil || t.caCertEntry == nil {
+		return api.TLSOptions{}
+	}
+	return api.TLSOptions{
+		InsecureSkipVerify: t.tlsInsecureCheck.Checked,
+		CACertFile:         t.caCertEntry.Text,
+	}
+}
+
+func (t *TabLoad) profileFromFields() store.ServerProfile {
+	tlsOptions := t.tlsOptionsFromFields()
+	return store.ServerProfile{
+		Name:          t.profileNameEntry.Text,
+		URL:           t.apiURLEntry.Text,
+		DefaultPreset: t.defaultPresetDropdown.Selected,
+		TLS: store.TLSOptions{
+			InsecureSkipVerify: tlsOptions.InsecureSkipVerify,
+			CACertFile:         tlsOptions.CACertFile,
+		},
+	}
+}
+
+// saveProfileKeys saves the keys entered for a profile to the secrets store and
+// sets the profile's references to them.
+func (t *TabLoad) saveProfileKeys(profile *store.ServerProfile) error {
+	keys := []struct {
+		name   string
+		secret string
+		ref    *string
+	}{
+		{"admin_key", t.adminKeyEntry.Text, &profile.AdminKeyRef},
+		{"api_key", t.apiKeyEntry.Text, &profile.APIKeyRef},
+	}
+
+	for _, key := range keys {
+		ref := secrets.ProfileKeyRef(profile.Name, key.name)
+		if err := t.saveSecret(ref, key.secret); err != nil {
+			return fmt.Errorf("saving %s: %w", key.name, err)
+		}
+		if key.secret != "" {
+			*key.ref = ref
+		}
+	}
+	return nil
+}
+
+func (t *TabLoad) handleSaveProfile() {
+	profile := t.profileFromFields()
+	if profile.Name == "" {
+		dialog.ShowInformation("Error", "Please enter a profile name", t.window)
+		return
+	}
+	if profile.URL == "" {
+		dialog.ShowInformation("Error", "Please enter the server URL", t.window)
+		return
+	}
+
+	if err := t.saveProfileKeys(&profile); err != nil {
+		logging.Error("Failed to save profile keys", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.config.SetProfile(profile)
+	if err := t.saveConfig(); err != nil {
+		logging.Error("Failed to save profile", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.profileDropdown.Options = t.profileOptions()
+	t.profileDropdown.SetSelected(profile.Name)
+	logging.Info(fmt.Sprintf("Profile '%s' saved successfully", profile.Name))
+}
+
+func (t *TabLoad) handleDeleteProfile() {
+	name := t.selectedProfileName()
+	if name == "" {
+		dialog.ShowInformation("Error", "Please select a profile to delete", t.window)
+		return
+	}
+
+	dialog.ShowConfirm("Delete Profile", fmt.Sprintf("Are you sure you want to delete the profile '%s'?", name), func(confirm bool) {
+		if !confirm {
+			return
+		}
+
+		if profile, ok := t.config.Profile(name); ok {
+			for _, ref := range []string{profile.AdminKeyRef, profile.APIKeyRef} {
+				if ref == "" {
+					continue
+				}
+				if err := t.secrets.Delete(ref); err != nil {
+					logging.Error(fmt.Sprintf("Failed to delete secret %s", ref), err)
+				}
+			}
+		}
+
+		t.config.DeleteProfile(name)
+		if err := t.saveConfig(); err != nil {
+			logging.Error("Failed to delete profile", err)
+			dialog.ShowError(err, t.window)
+			return
+		}
+
+		t.profileDropdown.Options = t.profileOptions()
+		t.profileDropdown.ClearSelected()
+		t.clearProfileFields()
+		logging.Info(fmt.Sprintf("Profile '%s' deleted successfully", name))
+	}, t.window)
+}
+
+// applyProfileDefaultPreset fills the model form from the profile's default
+// preset after connecting.
+func (t *TabLoad) applyProfileDefaultPreset(profileName string) {
+	profile, ok := t.config.Profile(profileName)
+	if !ok || profile.DefaultPreset == "" || t.presetDropdown == nil {
+		return
+	}
+
+	logging.Info(fmt.Sprintf("Applying default preset '%s' for profile '%s'", profile.DefaultPreset, profile.Name))
+	t.presetDropdown.SetSelected(profile.DefaultPreset)
+}
@@ -0,0 +1,321 @@
+package ui
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+// samplerControl is a sampler on the Advanced tab, with whether it is part of
+// the override profile and whether the override is forced.
+type samplerControl struct {
+	key        string
+	label      string
+	input      fyne.CanvasObject
+	valueLabel *widget.Label
+	include    *widget.Check
+	force      *widget.Check
+
+	// value returns the input's value, or nil if it is empty or invalid.
+	value    func() interface{}
+	setValue func(interface{})
+}
+
+func newSamplerControl(key, label string, input fyne.CanvasObject) *samplerControl {
+	return &samplerControl{
+		key:        key,
+		label:      label,
+		input:      input,
+		valueLabel: widget.NewLabel(""),
+		include:    widget.NewCheck("", nil),
+		force:      widget.NewCheck("", nil),
+	}
+}
+
+func newSliderControl(key, label string, slider *widget.Slider) *samplerControl {
+	control := newSamplerControl(key, label, slider)
+	control.value = func() interface{} { return slider.Value }
+	control.setValue = func(v interface{}) {
+		if f, ok := toFloat(v); ok {
+			slider.SetValue(f)
+		}
+	}
+
+	showValue := func(value float64) { control.valueLabel.SetText(strconv.FormatFloat(value, 'f', 2, 64)) }
+	slider.OnChanged = showValue
+	showValue(slider.Value)
+	return control
+}
+
+func newIntEntryControl(key, label string, entry *widget.Entry) *samplerControl {
+	control := newSamplerControl(key, label, entry)
+	control.value = func() interface{} {
+		value, err := strconv.Atoi(strings.TrimSpace(entry.Text))
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+	control.setValue = func(v interface{}) {
+		if f, ok := toFloat(v); ok {
+			entry.SetText(strconv.Itoa(int(f)))
+		}
+	}
+	return control
+}
+
+func newIntSelectControl(key, label string, selectWidget *widget.Select) *samplerControl {
+	control := newSamplerControl(key, label, selectWidget)
+	control.value = func() interface{} {
+		value, err := strconv.Atoi(selectWidget.Selected)
+		if err != nil {
+			return nil
+		}
+		return value
+	}
+	control.setValue = func(v interface{}) {
+		if f, ok := toFloat(v); ok {
+			selectWidget.SetSelected(strconv.Itoa(int(f)))
+		}
+	}
+	return control
+}
+
+// toFloat converts a number decoded from JSON or set in code to a float64.
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	default:
+		return 0, false
+	}
+}
+
+// buildSamplerProfileBar returns the controls for choosing, saving and
+// applying sampler override profiles.
+func (t *TabLoad) buildSamplerProfileBar() fyne.CanvasObject {
+	t.samplerProfileEntry = widget.NewEntry()
+	t.samplerProfileEntry.SetPlaceHolder("Profile name")
+
+	t.samplerProfileDropdown = widget.NewSelect(t.samplerProfileNames(), func(selected string) {
+		t.handleSelectSamplerProfile(selected)
+	})
+	t.samplerProfileDropdown.PlaceHolder = "(Select sampler profile)"
+
+	saveButton := widget.NewButton("Save", t.handleSaveSamplerProfile)
+	deleteButton := widget.NewButton("Delete", t.handleDeleteSamplerProfile)
+	applyButton := widget.NewButton("Apply to Server", t.handleApplySamplerOverrides)
+	exportButton := widget.NewButton("Export YAML...", t.handleExportSamplerYAML)
+
+	return container.NewVBox(
+		container.NewGridWithColumns(2, t.samplerProfileDropdown, t.samplerProfileEntry),
+		container.NewHBox(saveButton, deleteButton, applyButton, exportButton),
+	)
+}
+
+func (t *TabLoad) samplerProfileNames() []string {
+	profiles, err := t.samplerStore.SamplerProfiles()
+	if err != nil {
+		logging.Error("Error loading sampler profiles", err)
+		return nil
+	}
+
+	names := make([]string, len(profiles))
+	for i, profile := range profiles {
+		names[i] = profile.Name
+	}
+	return names
+}
+
+func (t *TabLoad) samplerProfile(name string) (*store.SamplerProfile, error) {
+	profiles, err := t.samplerStore.SamplerProfiles()
+	if err != nil {
+		return nil, err
+	}
+	for _, profile := range profiles {
+		if profile.Name == name {
+			return &profile, nil
+		}
+	}
+	return nil, fmt.Errorf("sampler profile not found: %s", name)
+}
+
+// samplerProfileFromFields builds a profile from the samplers ticked for
+// override on the Advanced tab.
+func (t *TabLoad) samplerProfileFromFields(name string) (store.SamplerProfile, error) {
+	profile := store.SamplerProfile{
+		Name:      name,
+		Overrides: make(map[string]store.SamplerOverride),
+	}
+
+	for _, control := range t.samplerControls {
+		if !control.include.Checked {
+			continue
+		}
+		value := control.value()
+		if value == nil {
+			return profile, fmt.Errorf("%s needs a value to be overridden", control.label)
+		}
+		profile.Overrides[control.key] = store.SamplerOverride{
+			Override: value,
+			Force:    control.force.Checked,
+		}
+	}
+
+	return profile, nil
+}
+
+// applySamplerProfileToFields shows a profile's overrides on the Advanced tab.
+// Samplers it doesn't override keep their values but are unticked.
+func (t *TabLoad) applySamplerProfileToFields(profile *store.SamplerProfile) {
+	for _, control := range t.samplerControls {
+		override, ok := profile.Overrides[control.key]
+		control.include.SetChecked(ok)
+		control.force.SetChecked(ok && override.Force)
+		if ok {
+			control.setValue(override.Override)
+		}
+	}
+}
+
+func (t *TabLoad) handleSelectSamplerProfile(name string) {
+	profile, err := t.samplerProfile(name)
+	if err != nil {
+		logging.Error("Error loading sampler profile", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.samplerProfileEntry.SetText(profile.Name)
+	t.applySamplerProfileToFields(profile)
+}
+
+func (t *TabLoad) handleSaveSamplerProfile() {
+	name := strings.TrimSpace(t.samplerProfileEntry.Text)
+	if name == "" {
+		dialog.ShowInformation("Error", "Please enter a sampler profile name", t.window)
+		return
+	}
+
+	profile, err := t.samplerProfileFromFields(name)
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	if len(profile.Overrides) == 0 {
+		dialog.ShowInformation("Error", "Tick Override for at least one sampler", t.window)
+		return
+	}
+
+	if err := t.samplerStore.SaveSamplerProfile(profile); err != nil {
+		logging.Error("Error saving sampler profile", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	t.samplerProfileDropdown.Options = t.samplerProfileNames()
+	t.samplerProfileDropdown.Refresh()
+	logging.Info(fmt.Sprintf("Sampler profile '%s' saved successfully", name))
+}
+
+func (t *TabLoad) handleDeleteSamplerProfile() {
+	name := t.samplerProfileDropdown.Selected
+	if name == "" {
+		dialog.ShowInformation("Error", "Please select a sampler profile to delete", t.window)
+		return
+	}
+
+	dialog.ShowConfirm("Delete Sampler Profile", fmt.Sprintf("Are you sure you want to delete the sampler profile '%s'?", name), func(confirm bool) {
+		if !confirm {
+			return
+		}
+
+		if err := t.samplerStore.DeleteSamplerProfile(name); err != nil {
+			logging.Error("Error deleting sampler profile", err)
+			dialog.ShowError(err, t.window)
+			return
+		}
+
+		t.samplerProfileDropdown.Options = t.samplerProfileNames()
+		t.samplerProfileDropdown.ClearSelected()
+		t.samplerProfileEntry.SetText("")
+		logging.Info(fmt.Sprintf("Sampler profile '%s' deleted successfully", name))
+	}, t.window)
+}
+
+// handleApplySamplerOverrides replaces the server's sampler overrides with the
+// ones ticked on the Advanced tab.
+func (t *TabLoad) handleApplySamplerOverrides() {
+	profile, err := t.samplerProfileFromFields(t.samplerProfileEntry.Text)
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	if len(profile.Overrides) == 0 {
+		dialog.ShowInformation("Error", "Tick Override for at least one sampler", t.window)
+		return
+	}
+
+	if err := t.client.ApplyOverridesCtx(t.requestContext(), profile.OverrideParams()); err != nil {
+		logging.Error("Error applying sampler overrides", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	logging.Info(fmt.Sprintf("Applied %d sampler overrides", len(profile.Overrides)))
+	t.refreshOverrides()
+	dialog.ShowInformation("Sampler Overrides", fmt.Sprintf("Applied %d sampler overrides to the server", len(profile.Overrides)), t.window)
+}
+
+// handleExportSamplerYAML saves the ticked overrides as a TabbyAPI sampler
+// override preset, for the server's sampler_overrides directory.
+func (t *TabLoad) handleExportSamplerYAML() {
+	name := strings.TrimSpace(t.samplerProfileEntry.Text)
+	if name == "" {
+		name = "overrides"
+	}
+
+	profile, err := t.samplerProfileFromFields(name)
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	data, err := profile.YAML()
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write(data); err != nil {
+			logging.Error("Failed to export sampler profile", err)
+			dialog.ShowError(fmt.Errorf("exporting sampler profile: %w", err), t.window)
+			return
+		}
+		logging.Info("Sampler profile exported to " + writer.URI().Path())
+	}, t.window)
+	saveDialog.SetFileName(t.normaliseTemplateName(name) + ".yml")
+	saveDialog.Show()
+}
@@ -0,0 +1,146 @@
+package ui
+
+import (
+	"errors"
+	"fmt"
+
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/secrets"
+)
+
+// secretsLocked reports whether secrets are kept in an encrypted file that
+// hasn't been unlocked yet.
+func (t *TabLoad) secretsLocked() bool {
+	fileStore, ok := t.secrets.(*secrets.FileStore)
+	return ok && fileStore.Locked()
+}
+
+// unlockSecrets asks for the secrets file passphrase, then runs next. If the
+// file doesn't exist yet the passphrase entered becomes its passphrase.
+func (t *TabLoad) unlockSecrets(next func()) {
+	fileStore, ok := t.secrets.(*secrets.FileStore)
+	if !ok || !fileStore.Locked() {
+		next()
+		return
+	}
+
+	message := "No OS keyring is available, so keys are stored in an encrypted file.\nEnter its passphrase to unlock it."
+	if !fileStore.Exists() {
+		message = "No OS keyring is available, so keys will be stored in an encrypted file.\nChoose a passphrase to protect it."
+	}
+
+	passphraseEntry := widget.NewPasswordEntry()
+	items := []*widget.FormItem{
+		widget.NewFormItem("", widget.NewLabel(message)),
+		widget.NewFormItem("Passphrase", passphraseEntry),
+	}
+	dialog.ShowForm("Unlock Secrets", "Unlock", "Skip", items, func(confirm bool) {
+		if !confirm {
+			logging.Warn("Secrets left locked, saved keys are unavailable")
+			next()
+			return
+		}
+
+		if err := fileStore.Unlock(passphraseEntry.Text); err != nil {
+			logging.Error("Failed to unlock secrets", err)
+			dialog.ShowError(err, t.window)
+			t.unlockSecrets(next)
+			return
+		}
+
+		logging.Info("Secrets unlocked")
+		t.migrateSecrets()
+		t.fillSecretFields()
+		next()
+	}, t.window)
+}
+
+// migrateSecrets moves plaintext keys left in the config by older versions
+// into the secrets store.
+func (t *TabLoad) migrateSecrets() {
+	if t.secretsLocked() {
+		return
+	}
+
+	migrated, err := t.config.MigrateSecrets(t.secrets.Set, secrets.ProfileKeyRef)
+	if err != nil {
+		logging.Error("Failed to migrate keys to "+t.secrets.Backend(), err)
+	}
+	if !migrated {
+		return
+	}
+
+	if err := t.saveConfig(); err != nil {
+		logging.Error("Failed to save config after migrating keys", err)
+		return
+	}
+	logging.Info("Migrated plaintext keys to " + t.secrets.Backend())
+}
+
+// secret returns the secret saved under ref, or "" if there isn't one or it
+// can't be read.
+func (t *TabLoad) secret(ref string) string {
+	secret, err := secrets.Lookup(t.secrets, ref)
+	if errors.Is(err, secrets.ErrLocked) {
+		return ""
+	}
+	if err != nil {
+		logging.Error(fmt.Sprintf("Failed to read secret %s", ref), err)
+		return ""
+	}
+	return secret
+}
+
+// saveSecret saves secret under ref, or deletes it when secret is empty.
+func (t *TabLoad) saveSecret(ref, secret string) error {
+	var err error
+	if secret == "" {
+		err = t.secrets.Delete(ref)
+	} else {
+		err = t.secrets.Set(ref, secret)
+	}
+	if err != nil {
+		if errors.Is(err, secrets.ErrLocked) {
+			return fmt.Errorf("unlock the secrets file to save keys: %w", err)
+		}
+		return err
+	}
+	return nil
+}
+
+// fillSecretFields fills the key fields from the secrets store, once it can be
+// read.
+func (t *TabLoad) fillSecretFields() {
+	if name := t.selectedProfileName(); name != "" {
+		t.fillProfileFields(name)
+	}
+	if t.tokenEntry != nil && t.tokenEntry.Text == "" {
+		t.tokenEntry.SetText(t.secret(t.config.HFTokenRef))
+	}
+}
+
+// saveHFToken saves the Hugging Face token entered on the downloader tab.
+func (t *TabLoad) saveHFToken(token string) {
+	if token == t.secret(t.config.HFTokenRef) {
+		return
+	}
+
+	if err := t.saveSecret(secrets.HFTokenRef, token); err != nil {
+		logging.Error("Failed to save Hugging Face token", err)
+		return
+	}
+
+	ref := secrets.HFTokenRef
+	if token == "" {
+		ref = ""
+	}
+	if ref == t.config.HFTokenRef {
+		return
+	}
+	t.config.HFTokenRef = ref
+	if err := t.saveConfig(); err != nil {
+		logging.Error("Failed to save config", err)
+	}
+}
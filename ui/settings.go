@@ -1,165 +1,203 @@
 package ui
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"strconv"
+	"strings"
 
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/widget"
-	"github.com/spf13/viper"
+	"github.com/sammcj/tabload/hf"
 )
 
 func (t *TabLoad) buildSettingsTab() fyne.CanvasObject {
 	autoConnectCheck := widget.NewCheck("Auto-connect on startup", func(checked bool) {
 		t.saveAutoConnectSetting(checked)
 	})
-	return container.NewVBox(autoConnectCheck)
+
+	hfEndpointEntry := widget.NewEntry()
+	hfEndpointEntry.SetPlaceHolder(hf.ResolveEndpoint(""))
+	hfEndpointEntry.SetText(t.config.HFEndpoint)
+	saveHFEndpointButton := widget.NewButton("Save", func() {
+		t.saveHFEndpoint(hfEndpointEntry.Text)
+	})
+	hfEndpointEntry.OnSubmitted = t.saveHFEndpoint
+
+	return container.NewVBox(
+		autoConnectCheck,
+		widget.NewLabel("Hugging Face Hub endpoint, for browsing repos through a mirror:"),
+		container.NewBorder(nil, nil, nil, saveHFEndpointButton, hfEndpointEntry),
+	)
 }
 
 func (t *TabLoad) ShouldAutoConnect() bool {
-	return viper.GetBool("auto_connect")
+	return t.config.AutoConnect
 }
 
 func (t *TabLoad) buildAdvancedSettingsTab() fyne.CanvasObject {
 	// Sampling parameters
-	temperatureSlider := widget.NewSlider(0, 2)
-	temperatureSlider.SetValue(1)
-
-	topKEntry := widget.NewEntry()
-	topKEntry.SetPlaceHolder("Top K (e.g., 40)")
-
-	topPSlider := widget.NewSlider(0, 1)
-	topPSlider.SetValue(1)
-
-	minPSlider := widget.NewSlider(0, 1)
-	minPSlider.SetValue(0)
-
-	topASlider := widget.NewSlider(0, 1)
-	topASlider.SetValue(0)
-
-	tfsSlider := widget.NewSlider(0, 1)
-	tfsSlider.SetValue(1)
+	t.temperatureSlider = newSamplerSlider(0, 2, 1)
 
-	typicalPSlider := widget.NewSlider(0, 1)
-	typicalPSlider.SetValue(1)
+	t.topKEntry = widget.NewEntry()
+	t.topKEntry.SetPlaceHolder("Top K (e.g., 40)")
 
-	repetitionPenaltySlider := widget.NewSlider(1, 2)
-	repetitionPenaltySlider.SetValue(1)
+	t.topPSlider = newSamplerSlider(0, 1, 1)
+	t.minPSlider = newSamplerSlider(0, 1, 0)
+	t.topASlider = newSamplerSlider(0, 1, 0)
+	t.tfsSlider = newSamplerSlider(0, 1, 1)
+	t.typicalPSlider = newSamplerSlider(0, 1, 1)
+	t.repetitionPenaltySlider = newSamplerSlider(1, 2, 1)
+	t.presencePenaltySlider = newSamplerSlider(-2, 2, 0)
+	t.frequencyPenaltySlider = newSamplerSlider(-2, 2, 0)
 
-	presencePenaltySlider := widget.NewSlider(-2, 2)
-	presencePenaltySlider.SetValue(0)
+	t.mirostatModeSelect = widget.NewSelect([]string{"0", "1", "2"}, func(s string) {})
+	t.mirostatModeSelect.SetSelected("0")
 
-	frequencyPenaltySlider := widget.NewSlider(-2, 2)
-	frequencyPenaltySlider.SetValue(0)
-
-	mirostatModeSelect := widget.NewSelect([]string{"0", "1", "2"}, func(s string) {})
-	mirostatModeSelect.SetSelected("0")
-
-	mirostatTauSlider := widget.NewSlider(0, 10)
-	mirostatTauSlider.SetValue(5)
-
-	mirostatEtaSlider := widget.NewSlider(0, 1)
-	mirostatEtaSlider.SetValue(0.1)
+	t.mirostatTauSlider = newSamplerSlider(0, 10, 5)
+	t.mirostatEtaSlider = newSamplerSlider(0, 1, 0.1)
 
 	// Other settings
-	streamingCheck := widget.NewCheck("Enable Streaming", func(bool) {})
-
-	grammarEntry := widget.NewMultiLineEntry()
-	grammarEntry.SetPlaceHolder("Enter grammar string here")
-
-	logitBiasEntry := widget.NewEntry()
-	logitBiasEntry.SetPlaceHolder("Logit bias (format: 'tokenID:bias,tokenID:bias')")
-
-	negativePromptEntry := widget.NewMultiLineEntry()
-	negativePromptEntry.SetPlaceHolder("Enter negative prompt here")
-
-	jsonModeCheck := widget.NewCheck("Enable JSON Mode", func(bool) {})
-
-	// Speculative decoding
-	speculativeNgramCheck := widget.NewCheck("Enable Speculative Decoding", func(bool) {})
-
-	saveButton := widget.NewButton("Save Advanced Settings", func() {
-		// Handle saving advanced settings
-		t.saveAdvancedSettings()
-	})
+	t.grammarEntry = widget.NewMultiLineEntry()
+	t.grammarEntry.SetPlaceHolder("Enter grammar string here")
+
+	t.logitBiasEntry = widget.NewEntry()
+	t.logitBiasEntry.SetPlaceHolder("Logit bias (format: 'tokenID:bias,tokenID:bias')")
+
+	t.negativePromptEntry = widget.NewMultiLineEntry()
+	t.negativePromptEntry.SetPlaceHolder("Enter negative prompt here")
+
+	t.samplerControls = []*samplerControl{
+		newSliderControl("temperature", "Temperature", t.temperatureSlider),
+		newIntEntryControl("top_k", "Top K", t.topKEntry),
+		newSliderControl("top_p", "Top P", t.topPSlider),
+		newSliderControl("min_p", "Min P", t.minPSlider),
+		newSliderControl("top_a", "Top A", t.topASlider),
+		newSliderControl("tfs", "TFS", t.tfsSlider),
+		newSliderControl("typical", "Typical P", t.typicalPSlider),
+		newSliderControl("repetition_penalty", "Repetition Penalty", t.repetitionPenaltySlider),
+		newSliderControl("presence_penalty", "Presence Penalty", t.presencePenaltySlider),
+		newSliderControl("frequency_penalty", "Frequency Penalty", t.frequencyPenaltySlider),
+		newIntSelectControl("mirostat_mode", "Mirostat Mode", t.mirostatModeSelect),
+		newSliderControl("mirostat_tau", "Mirostat Tau", t.mirostatTauSlider),
+		newSliderControl("mirostat_eta", "Mirostat Eta", t.mirostatEtaSlider),
+	}
 
-	// Create a grid layout for sampling parameters
-	samplingGrid := container.NewGridWithColumns(2,
-		widget.NewLabel("Temperature:"), temperatureSlider,
-		widget.NewLabel("Top K:"), topKEntry,
-		widget.NewLabel("Top P:"), topPSlider,
-		widget.NewLabel("Min P:"), minPSlider,
-		widget.NewLabel("Top A:"), topASlider,
-		widget.NewLabel("TFS:"), tfsSlider,
-		widget.NewLabel("Typical P:"), typicalPSlider,
-		widget.NewLabel("Repetition Penalty:"), repetitionPenaltySlider,
-		widget.NewLabel("Presence Penalty:"), presencePenaltySlider,
-		widget.NewLabel("Frequency Penalty:"), frequencyPenaltySlider,
-		widget.NewLabel("Mirostat Mode:"), mirostatModeSelect,
-		widget.NewLabel("Mirostat Tau:"), mirostatTauSlider,
-		widget.NewLabel("Mirostat Eta:"), mirostatEtaSlider,
+	// Create a grid layout for sampling parameters: name, value, and whether
+	// it's part of the override profile and forced
+	samplingGrid := container.NewGridWithColumns(4,
+		widget.NewLabelWithStyle("Sampler", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Value", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Override", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		widget.NewLabelWithStyle("Force", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
 	)
+	for _, control := range t.samplerControls {
+		samplingGrid.Add(container.NewBorder(nil, nil, nil, control.valueLabel, widget.NewLabel(control.label+":")))
+		samplingGrid.Add(control.input)
+		samplingGrid.Add(control.include)
+		samplingGrid.Add(control.force)
+	}
 
 	// Combine all elements into a scrollable container
 	return container.NewVScroll(container.NewVBox(
+		widget.NewLabel("Sampler Override Profile:"),
+		t.buildSamplerProfileBar(),
+		widget.NewSeparator(),
 		widget.NewLabel("Sampling Settings:"),
 		samplingGrid,
 		widget.NewSeparator(),
-		streamingCheck,
 		widget.NewLabel("Grammar-based Sampling:"),
-		grammarEntry,
+		t.grammarEntry,
 		widget.NewLabel("Logit Bias:"),
-		logitBiasEntry,
+		t.logitBiasEntry,
 		widget.NewLabel("Negative Prompt:"),
-		negativePromptEntry,
-		jsonModeCheck,
-		speculativeNgramCheck,
-		saveButton,
+		t.negativePromptEntry,
 	))
 }
 
-func (t *TabLoad) saveAdvancedSettings() {
-	// Collect all the settings
-	settings := map[string]interface{}{
+// newSamplerSlider returns a slider fine-grained enough for sampler values.
+func newSamplerSlider(min, max, value float64) *widget.Slider {
+	slider := widget.NewSlider(min, max)
+	slider.Step = 0.01
+	slider.SetValue(value)
+	return slider
+}
+
+// samplingParams returns the Advanced tab's sampler settings as generation
+// request fields, for the chat and completion tabs.
+func (t *TabLoad) samplingParams() (map[string]interface{}, error) {
+	if t.temperatureSlider == nil {
+		return map[string]interface{}{}, nil
+	}
+
+	params := map[string]interface{}{
 		"temperature":        t.temperatureSlider.Value,
-		"top_k":              t.topKEntry.Text,
 		"top_p":              t.topPSlider.Value,
 		"min_p":              t.minPSlider.Value,
 		"top_a":              t.topASlider.Value,
 		"tfs":                t.tfsSlider.Value,
-		"typical_p":          t.typicalPSlider.Value,
+		"typical":            t.typicalPSlider.Value,
 		"repetition_penalty": t.repetitionPenaltySlider.Value,
 		"presence_penalty":   t.presencePenaltySlider.Value,
 		"frequency_penalty":  t.frequencyPenaltySlider.Value,
-		"mirostat_mode":      t.mirostatModeSelect.Selected,
-		"mirostat_tau":       t.mirostatTauSlider.Value,
-		"mirostat_eta":       t.mirostatEtaSlider.Value,
-		"stream":             t.streamingCheck.Checked,
-		"grammar_string":     t.grammarEntry.Text,
-		"logit_bias":         t.logitBiasEntry.Text,
-		"negative_prompt":    t.negativePromptEntry.Text,
-		"json_mode":          t.jsonModeCheck.Checked,
-		"speculative_ngram":  t.speculativeNgramCheck.Checked,
 	}
 
-	// Convert the settings to JSON
-	jsonSettings, err := json.MarshalIndent(settings, "", "  ")
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to serialize settings: %v", err), t.window)
-		return
+	if text := strings.TrimSpace(t.topKEntry.Text); text != "" {
+		topK, err := strconv.Atoi(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid top K %q", text)
+		}
+		params["top_k"] = topK
 	}
 
-	// Save the settings to a file
-	err = os.WriteFile("advanced_settings.json", jsonSettings, 0644)
-	if err != nil {
-		dialog.ShowError(fmt.Errorf("failed to save settings: %v", err), t.window)
-		return
+	if mode, err := strconv.Atoi(t.mirostatModeSelect.Selected); err == nil && mode > 0 {
+		params["mirostat_mode"] = mode
+		params["mirostat_tau"] = t.mirostatTauSlider.Value
+		params["mirostat_eta"] = t.mirostatEtaSlider.Value
 	}
 
-	dialog.ShowInformation("Settings Saved", "Advanced settings have been saved successfully.", t.window)
+	if t.grammarEntry.Text != "" {
+		params["grammar_string"] = t.grammarEntry.Text
+	}
+	if t.negativePromptEntry.Text != "" {
+		params["negative_prompt"] = t.negativePromptEntry.Text
+	}
+
+	if text := strings.TrimSpace(t.logitBiasEntry.Text); text != "" {
+		logitBias, err := parseLogitBias(text)
+		if err != nil {
+			return nil, err
+		}
+		params["logit_bias"] = logitBias
+	}
+
+	return params, nil
+}
+
+// parseLogitBias parses "tokenID:bias,tokenID:bias".
+func parseLogitBias(text string) (map[string]float64, error) {
+	logitBias := make(map[string]float64)
+	for _, entry := range strings.Split(text, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		token, biasText, ok := strings.Cut(entry, ":")
+		if !ok {
+			return nil, fmt.Errorf("invalid logit bias %q, expected tokenID:bias", entry)
+		}
+		token = strings.TrimSpace(token)
+		if _, err := strconv.Atoi(token); err != nil {
+			return nil, fmt.Errorf("invalid token ID %q in logit bias", token)
+		}
+		bias, err := strconv.ParseFloat(strings.TrimSpace(biasText), 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid bias %q for token %s", biasText, token)
+		}
+		logitBias[token] = bias
+	}
+	return logitBias, nil
 }
 
 func (t *TabLoad) showSettingsDialog() {
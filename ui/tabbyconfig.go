@@ -0,0 +1,118 @@
+package ui
+
+import (
+	"fmt"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/storage"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/store"
+)
+
+func (t *TabLoad) handleImportTabbyConfig() {
+	openDialog := dialog.NewFileOpen(func(reader fyne.URIReadCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if reader == nil {
+			return
+		}
+		defer reader.Close()
+
+		t.importPresetFile(reader.URI().Name(), reader)
+	}, t.window)
+	openDialog.SetFilter(storage.NewExtensionFileFilter([]string{".yml", ".yaml"}))
+	openDialog.Show()
+}
+
+// importTabbyConfig turns the model sections of a TabbyAPI config.yml into a
+// preset, asking what to call it.
+func (t *TabLoad) importTabbyConfig(name string, data []byte) {
+	preset, err := store.ParseTabbyConfig(data)
+	if err != nil {
+		logging.Error("Invalid TabbyAPI config "+name, err)
+		dialog.ShowError(fmt.Errorf("%s: %w", name, err), t.window)
+		return
+	}
+
+	presetName := widget.NewEntry()
+	presetName.SetText(preset.Name)
+
+	dialog.ShowForm("Import config.yml", "Save", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Model", widget.NewLabel(preset.ModelID)),
+		widget.NewFormItem("Preset Name", presetName),
+	}, func(save bool) {
+		if !save || presetName.Text == "" {
+			return
+		}
+		preset.Name = presetName.Text
+
+		saveImported := func() {
+			if err := t.savePresetToStorage(preset); err != nil {
+				logging.Error("Failed to save preset", err)
+				dialog.ShowError(fmt.Errorf("failed to save preset: %w", err), t.window)
+				return
+			}
+			t.refreshPresetList()
+			t.presetDropdown.SetSelected(preset.Name)
+			logging.Info(fmt.Sprintf("Imported %s as preset '%s'", name, preset.Name))
+		}
+
+		if existing, err := t.loadPresetFromStorage(preset.Name); err == nil && existing != nil {
+			dialog.ShowConfirm("Preset Already Exists",
+				fmt.Sprintf("Overwrite the preset %q?", preset.Name),
+				func(overwrite bool) {
+					if overwrite {
+						saveImported()
+					}
+				}, t.window)
+			return
+		}
+		saveImported()
+	}, t.window)
+}
+
+// handleExportTabbyConfig saves the selected preset as config.yml sections for
+// the server's startup config.
+func (t *TabLoad) handleExportTabbyConfig() {
+	selected := t.presetDropdown.Selected
+	if selected == "" || selected == "(Select one)" {
+		dialog.ShowInformation("Export config.yml", "Please select a preset to export", t.window)
+		return
+	}
+
+	preset, err := t.loadPresetFromStorage(selected)
+	if err != nil {
+		dialog.ShowError(err, t.window)
+		return
+	}
+	data, err := preset.TabbyConfig()
+	if err != nil {
+		logging.Error("Failed to export preset as config.yml", err)
+		dialog.ShowError(err, t.window)
+		return
+	}
+
+	saveDialog := dialog.NewFileSave(func(writer fyne.URIWriteCloser, err error) {
+		if err != nil {
+			dialog.ShowError(err, t.window)
+			return
+		}
+		if writer == nil {
+			return
+		}
+		defer writer.Close()
+
+		if _, err := writer.Write(data); err != nil {
+			logging.Error("Failed to export preset as config.yml", err)
+			dialog.ShowError(fmt.Errorf("exporting config.yml: %w", err), t.window)
+			return
+		}
+		logging.Info(fmt.Sprintf("Exported preset '%s' to %s", preset.Name, writer.URI().Path()))
+	}, t.window)
+	saveDialog.SetFileName("config.yml")
+	saveDialog.Show()
+}
@@ -0,0 +1,515 @@
+package ui
+
+import (
+	"fmt"
+	"image/color"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/chattemplate"
+	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/utils"
+)
+
+// templateEditor edits local prompt templates: it checks the source as it is
+// typed, highlights its tags and renders it with a sample conversation.
+type templateEditor struct {
+	t *TabLoad
+	// name is the template being edited, empty for one not yet saved.
+	name  string
+	names []string
+	dirty bool
+	// loading is set while the source is replaced, so it isn't taken as an
+	// edit.
+	loading bool
+	// problems are those found in the source as it stands.
+	problems []chattemplate.Problem
+
+	dlg             *dialog.CustomDialog
+	list            *widget.List
+	titleLabel      *widget.Label
+	sourceEntry     *widget.Entry
+	highlight       *widget.TextGrid
+	previewLabel    *widget.Label
+	generationCheck *widget.Check
+	metadataLabel   *widget.Label
+	problemsLabel   *widget.Label
+	renameButton    *widget.Button
+	deleteButton    *widget.Button
+}
+
+// showTemplateEditor opens the template editor on a local template, or on a
+// new one if name is empty.
+func (t *TabLoad) showTemplateEditor(name string) {
+	e := &templateEditor{t: t, names: t.localTemplateNames()}
+
+	e.titleLabel = widget.NewLabel("")
+	e.titleLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	e.sourceEntry = widget.NewMultiLineEntry()
+	e.sourceEntry.TextStyle = fyne.TextStyle{Monospace: true}
+	e.sourceEntry.Wrapping = fyne.TextWrapOff
+	e.sourceEntry.SetPlaceHolder("{% for message in messages %}...{% endfor %}")
+	e.sourceEntry.OnChanged = func(string) {
+		if !e.loading {
+			e.dirty = true
+		}
+		e.update()
+	}
+
+	e.highlight = widget.NewTextGrid()
+	e.highlight.ShowLineNumbers = true
+
+	e.previewLabel = widget.NewLabel("")
+	e.previewLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	e.generationCheck = widget.NewCheck("Add generation prompt", func(bool) { e.update() })
+	e.generationCheck.Checked = chattemplate.DefaultVars.AddGenerationPrompt
+
+	e.metadataLabel = widget.NewLabel("")
+	e.metadataLabel.Wrapping = fyne.TextWrapWord
+	e.problemsLabel = widget.NewLabel("")
+	e.problemsLabel.Wrapping = fyne.TextWrapWord
+
+	e.list = widget.NewList(
+		func() int { return len(e.names) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, obj fyne.CanvasObject) {
+			obj.(*widget.Label).SetText(e.names[id])
+		},
+	)
+	e.list.OnSelected = func(id widget.ListItemID) {
+		if id >= len(e.names) || e.names[id] == e.name {
+			return
+		}
+		selected := e.names[id]
+		e.confirmDiscard(func() { e.open(selected) }, e.selectCurrent)
+	}
+
+	newButton := widget.NewButtonWithIcon("New", theme.DocumentCreateIcon(), func() {
+		e.confirmDiscard(func() { e.open("") }, nil)
+	})
+	e.renameButton = widget.NewButton("Rename", e.handleRename)
+	e.deleteButton = widget.NewButton("Delete", e.handleDelete)
+
+	editMetadataButton := widget.NewButton("Edit Metadata", e.handleEditMetadata)
+
+	sourceTabs := container.NewAppTabs(
+		container.NewTabItem("Source", e.sourceEntry),
+		container.NewTabItem("Highlighted", container.NewScroll(e.highlight)),
+	)
+	preview := container.NewBorder(
+		container.NewHBox(widget.NewLabel("Preview with a sample conversation"), e.generationCheck),
+		nil, nil, nil,
+		container.NewScroll(e.previewLabel),
+	)
+	editor := container.NewHSplit(sourceTabs, preview)
+	editor.Offset = 0.55
+
+	templates := container.NewBorder(
+		nil,
+		container.NewHBox(newButton, e.renameButton, e.deleteButton),
+		nil, nil,
+		e.list,
+	)
+	main := container.NewHSplit(templates, container.NewBorder(
+		e.titleLabel,
+		container.NewVBox(
+			container.NewBorder(nil, nil, nil, editMetadataButton, e.metadataLabel),
+			widget.NewSeparator(),
+			e.problemsLabel,
+		),
+		nil, nil,
+		editor,
+	))
+	main.Offset = 0.2
+
+	e.dlg = dialog.NewCustomWithoutButtons("Template Editor", main, t.window)
+	e.dlg.SetButtons([]fyne.CanvasObject{
+		widget.NewButton("Close", func() { e.confirmDiscard(e.dlg.Hide, nil) }),
+		widget.NewButtonWithIcon("Save", theme.DocumentSaveIcon(), e.handleSave),
+	})
+	e.dlg.Resize(fyne.NewSize(1100, 750))
+
+	e.open(name)
+	e.dlg.Show()
+}
+
+// open loads a template into the editor, or clears it for a new one.
+func (e *templateEditor) open(name string) {
+	source := ""
+	if name != "" {
+		var err error
+		if source, err = e.t.localTemplate(name); err != nil {
+			logging.Error("Failed to open template", err)
+			dialog.ShowError(err, e.t.window)
+			e.selectCurrent()
+			return
+		}
+	}
+
+	e.name = name
+	e.loading = true
+	e.sourceEntry.SetText(source)
+	e.loading = false
+	e.dirty = false
+	e.update()
+	e.selectCurrent()
+}
+
+// selectCurrent selects the template being edited in the list.
+func (e *templateEditor) selectCurrent() {
+	for i, name := range e.names {
+		if name == e.name {
+			e.list.Select(i)
+			return
+		}
+	}
+	e.list.UnselectAll()
+}
+
+// confirmDiscard runs then, after asking first if there are unsaved changes.
+// If they are kept, cancelled is run instead.
+func (e *templateEditor) confirmDiscard(then, cancelled func()) {
+	if !e.dirty {
+		then()
+		return
+	}
+	dialog.ShowConfirm("Unsaved Changes", "Discard the changes to this template?", func(confirm bool) {
+		switch {
+		case confirm:
+			then()
+		case cancelled != nil:
+			cancelled()
+		}
+	}, e.t.window)
+}
+
+// update checks, highlights and renders the source after a change.
+func (e *templateEditor) update() {
+	source := e.sourceEntry.Text
+	e.problems = chattemplate.Check(source)
+
+	title := "New template"
+	if e.name != "" {
+		title = e.name
+	}
+	if e.dirty {
+		title += " (unsaved changes)"
+	}
+	e.titleLabel.SetText(title)
+	if e.name == "" {
+		e.renameButton.Disable()
+		e.deleteButton.Disable()
+	} else {
+		e.renameButton.Enable()
+		e.deleteButton.Enable()
+	}
+
+	e.updateHighlight(source)
+	e.updateProblems()
+	e.updateMetadata(source)
+	e.updatePreview(source)
+}
+
+func (e *templateEditor) updateProblems() {
+	if len(e.problems) == 0 {
+		e.problemsLabel.SetText("No problems found")
+		return
+	}
+	lines := make([]string, len(e.problems))
+	for i, problem := range e.problems {
+		lines[i] = problem.String()
+	}
+	e.problemsLabel.SetText(fmt.Sprintf("%d problem(s):\n%s", len(e.problems), strings.Join(lines, "\n")))
+}
+
+func (e *templateEditor) updateMetadata(source string) {
+	if len(e.problems) > 0 {
+		e.metadataLabel.SetText("Metadata: fix the problems below to read it")
+		return
+	}
+	md, err := chattemplate.ExtractMetadata(source)
+	if err != nil {
+		e.metadataLabel.SetText(fmt.Sprintf("Metadata: %v", err))
+		return
+	}
+	e.metadataLabel.SetText("Metadata: " + describeMetadata(md))
+}
+
+func (e *templateEditor) updatePreview(source string) {
+	switch {
+	case strings.TrimSpace(source) == "":
+		e.previewLabel.SetText("")
+		return
+	case len(e.problems) > 0:
+		e.previewLabel.SetText("Fix the problems below to see a preview")
+		return
+	}
+	vars := chattemplate.DefaultVars
+	vars.AddGenerationPrompt = e.generationCheck.Checked
+	rendered, err := chattemplate.Render(source, chattemplate.SampleMessages, vars)
+	if err != nil {
+		e.previewLabel.SetText(err.Error())
+		return
+	}
+	e.previewLabel.SetText(rendered)
+}
+
+// updateHighlight shows the source with its tags coloured by kind and the
+// lines with problems marked.
+func (e *templateEditor) updateHighlight(source string) {
+	styles := map[chattemplate.TokenKind]widget.TextGridStyle{
+		chattemplate.Statement:  &widget.CustomTextGridStyle{FGColor: theme.PrimaryColor()},
+		chattemplate.Expression: &widget.CustomTextGridStyle{FGColor: theme.SuccessColor()},
+		chattemplate.Comment:    &widget.CustomTextGridStyle{FGColor: theme.DisabledColor()},
+	}
+
+	rows := []widget.TextGridRow{{}}
+	for _, token := range chattemplate.Tokens(source) {
+		style := styles[token.Kind]
+		for _, r := range source[token.Start:token.End] {
+			if r == '\n' {
+				rows = append(rows, widget.TextGridRow{})
+				continue
+			}
+			row := &rows[len(rows)-1]
+			row.Cells = append(row.Cells, widget.TextGridCell{Rune: r, Style: style})
+		}
+	}
+
+	errorColor := translucent(theme.ErrorColor())
+	for _, problem := range e.problems {
+		if problem.Line < 1 || problem.Line > len(rows) {
+			continue
+		}
+		row := &rows[problem.Line-1]
+		row.Style = &widget.CustomTextGridStyle{BGColor: errorColor}
+		if col := problem.Column - 1; col >= 0 && col < len(row.Cells) {
+			var fg color.Color
+			if row.Cells[col].Style != nil {
+				fg = row.Cells[col].Style.TextColor()
+			}
+			row.Cells[col].Style = &widget.CustomTextGridStyle{FGColor: fg, BGColor: theme.ErrorColor()}
+		}
+	}
+
+	e.highlight.Rows = rows
+	e.highlight.Refresh()
+}
+
+func translucent(c color.Color) color.Color {
+	r, g, b, _ := c.RGBA()
+	return color.NRGBA{R: uint8(r >> 8), G: uint8(g >> 8), B: uint8(b >> 8), A: 0x40}
+}
+
+// describeMetadata summarises template metadata for the editor.
+func describeMetadata(md chattemplate.Metadata) string {
+	if md.IsZero() {
+		return "none set"
+	}
+	var parts []string
+	if len(md.StopStrings) > 0 {
+		quoted := make([]string, len(md.StopStrings))
+		for i, s := range md.StopStrings {
+			quoted[i] = strconv.Quote(s)
+		}
+		parts = append(parts, "stop strings "+strings.Join(quoted, ", "))
+	}
+	if md.ToolStart != "" {
+		parts = append(parts, "tool start "+strconv.Quote(md.ToolStart))
+	}
+	if md.ToolStartToken != nil {
+		parts = append(parts, fmt.Sprintf("tool start token %d", *md.ToolStartToken))
+	}
+	return strings.Join(parts, "; ")
+}
+
+// handleEditMetadata edits the template's metadata in a form, rewriting its
+// {% set %} statements to match.
+func (e *templateEditor) handleEditMetadata() {
+	if len(e.problems) > 0 {
+		dialog.ShowInformation("Edit Metadata", "Fix the problems in the template first", e.t.window)
+		return
+	}
+	md, err := chattemplate.ExtractMetadata(e.sourceEntry.Text)
+	if err != nil {
+		dialog.ShowError(err, e.t.window)
+		return
+	}
+
+	stopStringsEntry := widget.NewMultiLineEntry()
+	stopStringsEntry.SetPlaceHolder("One per line, e.g. <|im_end|> or \\n\\nUser:")
+	stopStringsEntry.SetMinRowsVisible(4)
+	escaped := make([]string, len(md.StopStrings))
+	for i, s := range md.StopStrings {
+		escaped[i] = escapeMetadata(s)
+	}
+	stopStringsEntry.SetText(strings.Join(escaped, "\n"))
+
+	toolStartEntry := widget.NewEntry()
+	toolStartEntry.SetPlaceHolder("e.g. <tool_call>")
+	toolStartEntry.SetText(escapeMetadata(md.ToolStart))
+
+	toolStartTokenEntry := widget.NewEntry()
+	toolStartTokenEntry.SetPlaceHolder("Token ID, for older TabbyAPI versions")
+	if md.ToolStartToken != nil {
+		toolStartTokenEntry.SetText(strconv.Itoa(*md.ToolStartToken))
+	}
+
+	dialog.ShowForm("Template Metadata", "Apply", "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Stop Strings", stopStringsEntry),
+		widget.NewFormItem("Tool Start", toolStartEntry),
+		widget.NewFormItem("Tool Start Token", toolStartTokenEntry),
+	}, func(confirm bool) {
+		if !confirm {
+			return
+		}
+
+		var updated chattemplate.Metadata
+		for _, line := range strings.Split(stopStringsEntry.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			s, err := unescapeMetadata("stop string", line)
+			if err != nil {
+				dialog.ShowError(err, e.t.window)
+				return
+			}
+			updated.StopStrings = append(updated.StopStrings, s)
+		}
+		if updated.ToolStart, err = unescapeMetadata("tool start", strings.TrimSpace(toolStartEntry.Text)); err != nil {
+			dialog.ShowError(err, e.t.window)
+			return
+		}
+		if text := strings.TrimSpace(toolStartTokenEntry.Text); text != "" {
+			token, err := utils.Int("tool_start_token", text)
+			if err != nil {
+				dialog.ShowError(err, e.t.window)
+				return
+			}
+			updated.ToolStartToken = &token
+		}
+
+		e.sourceEntry.SetText(chattemplate.SetMetadata(e.sourceEntry.Text, updated))
+	}, e.t.window)
+}
+
+// escapeMetadata shows a metadata string on one line, with escapes such as
+// \n for the characters that can't be typed into an entry.
+func escapeMetadata(s string) string {
+	quoted := strconv.Quote(s)
+	return strings.ReplaceAll(quoted[1:len(quoted)-1], `\"`, `"`)
+}
+
+// unescapeMetadata reverses escapeMetadata.
+func unescapeMetadata(field, s string) (string, error) {
+	unquoted, err := strconv.Unquote(`"` + strings.ReplaceAll(s, `"`, `\"`) + `"`)
+	if err != nil {
+		return "", fmt.Errorf("%s %q has a bad escape sequence", field, s)
+	}
+	return unquoted, nil
+}
+
+// handleSave saves the template, asking for a name if it is new and for
+// confirmation if it has problems.
+func (e *templateEditor) handleSave() {
+	save := func(name string) {
+		// The name as it reads back from the file name
+		name = e.t.denormaliseTemplateName(e.t.normaliseTemplateName(name))
+		if err := e.t.saveTemplate(name, e.sourceEntry.Text); err != nil {
+			logging.Error("Failed to save template", err)
+			dialog.ShowError(err, e.t.window)
+			return
+		}
+		e.name = name
+		e.dirty = false
+		e.names = e.t.localTemplateNames()
+		e.list.Refresh()
+		e.update()
+		e.selectCurrent()
+		e.t.refreshTemplateList()
+	}
+
+	withName := func() {
+		if e.name != "" {
+			save(e.name)
+			return
+		}
+		e.askName("Save Template", "Save", "", save)
+	}
+
+	if len(e.problems) == 0 {
+		withName()
+		return
+	}
+	dialog.ShowConfirm("Save Template", fmt.Sprintf("The template has %d problem(s) and won't render until they are fixed. Save it anyway?", len(e.problems)), func(confirm bool) {
+		if confirm {
+			withName()
+		}
+	}, e.t.window)
+}
+
+// handleRename renames the template being edited.
+func (e *templateEditor) handleRename() {
+	if e.name == "" {
+		return
+	}
+	e.askName("Rename Template", "Rename", e.name, func(name string) {
+		name = e.t.denormaliseTemplateName(e.t.normaliseTemplateName(name))
+		if err := e.t.renameTemplate(e.name, name); err != nil {
+			logging.Error("Failed to rename template", err)
+			dialog.ShowError(err, e.t.window)
+			return
+		}
+		e.name = name
+		e.names = e.t.localTemplateNames()
+		e.list.Refresh()
+		e.update()
+		e.selectCurrent()
+	})
+}
+
+// handleDelete deletes the template being edited.
+func (e *templateEditor) handleDelete() {
+	if e.name == "" {
+		return
+	}
+	e.t.deleteTemplate(e.name, func() {
+		e.names = e.t.localTemplateNames()
+		e.list.Refresh()
+		e.open("")
+	})
+}
+
+// askName asks for a template name, refusing an empty one or one already
+// taken by another template.
+func (e *templateEditor) askName(title, confirm, current string, then func(string)) {
+	nameEntry := widget.NewEntry()
+	nameEntry.SetText(current)
+	nameEntry.SetPlaceHolder("Template Name")
+
+	dialog.ShowForm(title, confirm, "Cancel", []*widget.FormItem{
+		widget.NewFormItem("Name", nameEntry),
+	}, func(ok bool) {
+		if !ok {
+			return
+		}
+		name := strings.TrimSpace(nameEntry.Text)
+		if name == "" {
+			dialog.ShowInformation(title, "The template needs a name", e.t.window)
+			return
+		}
+		for _, existing := range e.names {
+			if existing != e.name && e.t.normaliseTemplateName(existing) == e.t.normaliseTemplateName(name) {
+				dialog.ShowInformation(title, fmt.Sprintf("A template named %s already exists", existing), e.t.window)
+				return
+			}
+		}
+		then(name)
+	}, e.t.window)
+}
@@ -4,15 +4,20 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
-	"fyne.io/fyne/v2"
-	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/dialog"
-	"fyne.io/fyne/v2/widget"
 	"github.com/sammcj/tabload/logging"
 )
 
+// Local templates are saved as .jinja files, like TabbyAPI's own; .txt is
+// what earlier versions saved, and is still read.
+const (
+	templateExt       = ".jinja"
+	legacyTemplateExt = ".txt"
+)
+
 func (t *TabLoad) getTemplatesDir() string {
 	configDir, err := os.UserConfigDir()
 	if err != nil {
@@ -22,6 +27,18 @@ func (t *TabLoad) getTemplatesDir() string {
 	return filepath.Join(configDir, "tabload", "templates")
 }
 
+// templatePath returns the file a local template is saved in: the .jinja file,
+// unless only a legacy .txt one exists.
+func (t *TabLoad) templatePath(name string) string {
+	base := filepath.Join(t.getTemplatesDir(), t.normaliseTemplateName(name))
+	if _, err := os.Stat(base + templateExt); err != nil {
+		if _, err := os.Stat(base + legacyTemplateExt); err == nil {
+			return base + legacyTemplateExt
+		}
+	}
+	return base + templateExt
+}
+
 func (t *TabLoad) loadLocalTemplatesFromFiles() map[string]string {
 	templatesDir := t.getTemplatesDir()
 	templates := make(map[string]string)
@@ -37,12 +54,16 @@ func (t *TabLoad) loadLocalTemplatesFromFiles() map[string]string {
 	}
 
 	for _, file := range files {
-		if file.IsDir() || !strings.HasSuffix(file.Name(), ".txt") {
+		ext := filepath.Ext(file.Name())
+		if file.IsDir() || (ext != templateExt && ext != legacyTemplateExt) {
 			continue
 		}
 
-		normalizedName := strings.TrimSuffix(file.Name(), ".txt")
+		normalizedName := strings.TrimSuffix(file.Name(), ext)
 		name := t.denormaliseTemplateName(normalizedName)
+		if _, ok := templates[name]; ok && ext == legacyTemplateExt {
+			continue // The .jinja file takes precedence
+		}
 
 		content, err := os.ReadFile(filepath.Join(templatesDir, file.Name()))
 		if err != nil {
@@ -57,52 +78,55 @@ func (t *TabLoad) loadLocalTemplatesFromFiles() map[string]string {
 	return templates
 }
 
-func (t *TabLoad) showCreateTemplateDialog() {
-	nameEntry := widget.NewEntry()
-	nameEntry.SetPlaceHolder("Template Name")
-
-	contentEntry := widget.NewMultiLineEntry()
-	contentEntry.SetPlaceHolder("Enter template content")
-	contentEntry.Resize(fyne.NewSize(500, 400))
-	contentEntry.SetMinRowsVisible(5)
-
-	content := container.NewVBox(
-		widget.NewForm(
-			widget.NewFormItem("Name", nameEntry),
-		),
-		widget.NewLabel("Content:"),
-		contentEntry,
-	)
-
-	dlg := dialog.NewCustom("Create New Template", "Save", content, t.window)
-	dlg.SetOnClosed(func() {
-		if nameEntry.Text != "" && contentEntry.Text != "" {
-			t.saveNewTemplate(nameEntry.Text, contentEntry.Text)
-		}
-	})
-	dlg.Resize(fyne.NewSize(500, 400))
-	dlg.Show()
+// localTemplateNames returns the names of the locally saved templates, sorted.
+func (t *TabLoad) localTemplateNames() []string {
+	templates := t.loadLocalTemplatesFromFiles()
+	names := make([]string, 0, len(templates))
+	for name := range templates {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-func (t *TabLoad) saveNewTemplate(name, content string) {
-	templatesDir := t.getTemplatesDir()
-	if err := os.MkdirAll(templatesDir, 0755); err != nil {
-		logging.Error("Failed to create templates directory", err)
-		dialog.ShowError(err, t.window)
-		return
+// saveTemplate saves a local template, replacing a legacy .txt file of the
+// same name.
+func (t *TabLoad) saveTemplate(name, content string) error {
+	if err := os.MkdirAll(t.getTemplatesDir(), 0755); err != nil {
+		return fmt.Errorf("creating templates directory: %w", err)
 	}
 
-	normalizedName := t.normaliseTemplateName(name)
-	filePath := filepath.Join(templatesDir, normalizedName+".txt")
+	base := filepath.Join(t.getTemplatesDir(), t.normaliseTemplateName(name))
+	if err := os.WriteFile(base+templateExt, []byte(content), 0644); err != nil {
+		return fmt.Errorf("saving template %s: %w", name, err)
+	}
+	if err := os.Remove(base + legacyTemplateExt); err != nil && !os.IsNotExist(err) {
+		logging.Warn(fmt.Sprintf("Failed to remove legacy template file for %s: %v", name, err))
+	}
+	logging.Info(fmt.Sprintf("Saved template %s", name))
+	return nil
+}
 
-	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
-		logging.Error("Failed to save new template", err)
-		dialog.ShowError(err, t.window)
-		return
+// renameTemplate renames a local template, keeping the Model tab's selection
+// on it.
+func (t *TabLoad) renameTemplate(oldName, newName string) error {
+	if t.normaliseTemplateName(oldName) == t.normaliseTemplateName(newName) {
+		return nil
 	}
+	newPath := filepath.Join(t.getTemplatesDir(), t.normaliseTemplateName(newName)+templateExt)
+	if _, err := os.Stat(t.templatePath(newName)); err == nil {
+		return fmt.Errorf("a template named %s already exists", newName)
+	}
+	if err := os.Rename(t.templatePath(oldName), newPath); err != nil {
+		return fmt.Errorf("renaming template %s: %w", oldName, err)
+	}
+	logging.Info(fmt.Sprintf("Renamed template %s to %s", oldName, newName))
 
 	t.refreshTemplateList()
-	dialog.ShowInformation("Success", "Template saved successfully", t.window)
+	if t.promptTemplateDropdown != nil && t.promptTemplateDropdown.Selected == oldName {
+		t.promptTemplateDropdown.SetSelected(newName)
+	}
+	return nil
 }
 
 func (t *TabLoad) refreshTemplateList() {
@@ -119,7 +143,9 @@ func (t *TabLoad) refreshTemplateList() {
 	}
 }
 
-func (t *TabLoad) deleteTemplate(name string) {
+// deleteTemplate deletes a local template after confirmation, then runs
+// deleted if it is set.
+func (t *TabLoad) deleteTemplate(name string, deleted func()) {
 	if strings.HasSuffix(name, " (server)") {
 		dialog.ShowInformation("Cannot Delete", "Server-side templates cannot be deleted.", t.window)
 		return
@@ -127,16 +153,16 @@ func (t *TabLoad) deleteTemplate(name string) {
 
 	dialog.ShowConfirm("Delete Template", "Are you sure you want to delete this template?", func(confirm bool) {
 		if confirm {
-			normalizedName := t.normaliseTemplateName(name)
-			filePath := filepath.Join(t.getTemplatesDir(), normalizedName+".txt")
-
-			if err := os.Remove(filePath); err != nil {
+			if err := os.Remove(t.templatePath(name)); err != nil {
 				logging.Error("Failed to delete template file", err)
 				dialog.ShowError(err, t.window)
 				return
 			}
 
 			t.refreshTemplateList()
+			if deleted != nil {
+				deleted()
+			}
 			dialog.ShowInformation("Success", "Template deleted successfully", t.window)
 		}
 	}, t.window)
@@ -173,7 +199,7 @@ func (t *TabLoad) loadTemplates() map[string]string {
 
 	// Fetch server-side templates
 	if t.client != nil && t.client.BaseURL != "" {
-		serverTemplates, err := t.client.FetchServerTemplates()
+		serverTemplates, err := t.client.FetchServerTemplatesCtx(t.requestContext())
 		if err != nil {
 			logging.Error("Failed to fetch server templates", err)
 		} else {
@@ -188,3 +214,12 @@ func (t *TabLoad) loadTemplates() map[string]string {
 	logging.Info(fmt.Sprintf("Loaded %d templates (%d local, %d server)", len(templates), len(localTemplates), len(templates)-len(localTemplates)))
 	return templates
 }
+
+// localTemplate returns the content of a locally saved template.
+func (t *TabLoad) localTemplate(name string) (string, error) {
+	content, err := os.ReadFile(t.templatePath(name))
+	if err != nil {
+		return "", fmt.Errorf("reading template %s: %w", name, err)
+	}
+	return string(content), nil
+}
@@ -1,34 +1,61 @@
 package ui
 
 import (
+	"context"
+	"sync"
+
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/widget"
 	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/download"
+	"github.com/sammcj/tabload/hf"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
 )
 
 type TabLoad struct {
 	window fyne.Window
 	client *api.Client
 
+	presetStore  store.PresetStore
+	configStore  store.ConfigStore
+	samplerStore store.SamplerStore
+	secrets      secrets.Store
+	config       Config
+
 	ready bool // Flag to indicate if the UI is fully Initialised
 
+	// ctx scopes in-flight API calls to the current connection; it is
+	// cancelled on disconnect and when the window closes. Read it with
+	// requestContext, as goroutines use it while a connect replaces it.
+	ctx            context.Context
+	cancelRequests context.CancelFunc
+	ctxMu          sync.Mutex // Guards ctx and cancelRequests
+
 	// UI components
 	adminKeyEntry           *widget.Entry
+	applyPresetButton       *widget.Button
+	apiKeyEntry             *widget.Entry
 	apiURLEntry             *widget.Entry
 	autosplitReserveCheck   *widget.Check
 	autosplitReserveEntry   *widget.Entry
 	cacheModeDropdown       *widget.Select
 	cacheSizeCheck          *widget.Check
 	cacheSizeEntry          *widget.Entry
-	cancelDownloadButton    *widget.Button
+	chunkLimitEntry         *widget.Entry
+	caCertEntry             *widget.Entry
 	chunkSizeCheck          *widget.Check
 	chunkSizeEntry          *widget.Entry
 	connectButton           *widget.Button
-	currentLorasLabel       *widget.Label
 	currentModelInfo        *fyne.Container
 	currentModelLabel       *widget.Label
+	defaultPresetDropdown   *widget.Select
 	deletePresetButton      *widget.Button
+	disconnectButton        *widget.Button
 	downloadButton          *widget.Button
+	downloadTimeoutEntry    *widget.Entry
 	draftCacheModeDropdown  *widget.Select
 	draftModelNameCheck     *widget.Check
 	draftModelNameEntry     *widget.Entry
@@ -46,7 +73,6 @@ type TabLoad struct {
 	loadLorasButton         *widget.Button
 	loadModelButton         *widget.Button
 	logsTextArea            *widget.Entry
-	lorasDropdown           *widget.Select
 	maxSeqLenCheck          *widget.Check
 	maxSeqLenEntry          *widget.Entry
 	modelsDropdown          *widget.Select
@@ -55,6 +81,9 @@ type TabLoad struct {
 	overrideBaseSeqLenCheck *widget.Check
 	overrideBaseSeqLenEntry *widget.Entry
 	presetDropdown          *widget.Select
+	presetNotesLabel        *widget.Label
+	profileDropdown         *widget.Select
+	profileNameEntry        *widget.Entry
 	promptTemplateCheck     *widget.Check
 	promptTemplateEntry     *widget.Entry
 	repoIDEntry             *widget.Entry
@@ -65,10 +94,12 @@ type TabLoad struct {
 	ropeScaleCheck          *widget.Check
 	ropeScaleEntry          *widget.Entry
 	savePresetButton        *widget.Button
+	tlsInsecureCheck        *widget.Check
 	tokenEntry              *widget.Entry
 	unloadLorasButton       *widget.Button
 	unloadModelButton       *widget.Button
 	form                    *widget.Form
+	formRows                map[string]*formRow // Model form rows keyed by load request field
 	connectionStatus        *widget.Label
 	logsPane                *fyne.Container
 	showingLogs             bool
@@ -86,31 +117,77 @@ type TabLoad struct {
 	mirostatModeSelect      *widget.Select // Sampling parameters
 	mirostatTauSlider       *widget.Slider // Sampling parameters
 	mirostatEtaSlider       *widget.Slider // Sampling parameters
-	streamingCheck          *widget.Check  // Sampling parameters
 	grammarEntry            *widget.Entry  // Sampling parameters
 	logitBiasEntry          *widget.Entry  // Sampling parameters
 	negativePromptEntry     *widget.Entry  // Sampling parameters
-	jsonModeCheck           *widget.Check  // Sampling parameters
-	speculativeNgramCheck   *widget.Check  // Sampling parameters
+	samplerControls         []*samplerControl
+	samplerProfileDropdown  *widget.Select
+	samplerProfileEntry     *widget.Entry
+
+	// LoRAs tab
+	loraRows         []*loraRow
+	lorasList        *fyne.Container
+	loadedLoras      []api.LoraCard
+	loadedLorasTable *widget.Table
+
+	// Sampler overrides tab
+	overridesList       *widget.List
+	overrideStatus      *api.OverrideStatus
+	selectedOverride    string
+	activeOverrideLabel *widget.Label
+	overrideDiffLabel   *widget.Label
+
+	// HF Downloader tab
+	downloads     *download.Queue
+	downloadsList *fyne.Container
+	downloadRows  map[int]*downloadRow
+	downloadsMu   sync.Mutex // Guards the rows, updated from the queue's goroutine
+
+	// What to do after a download, copied to each job as it's queued
+	refreshAfterDownloadCheck *widget.Check
+	presetAfterDownloadCheck  *widget.Check
+	loadAfterDownloadCheck    *widget.Check
+
+	// Files of the repo last picked from in the repo browser, to size the
+	// download
+	downloadSizeLabel *widget.Label
+	repoFiles         []hf.File
+	repoFilesID       string
+	repoFilesRevision string
+	repoFilesMu       sync.Mutex // Guards the repo files, listed from a goroutine by the preview
+
+	// Chat tab
+	chatMu                sync.Mutex // Guards the history, added to by the generating goroutine
+	chatHistory           []api.ChatMessage
+	chatCleared           int // Counts clears, so a reply generated before one is dropped
+	chatMessages          *fyne.Container
+	chatScroll            *container.Scroll
+	chatSystemPromptEntry *widget.Entry
+	chatInputEntry        *widget.Entry
+	chatSendButton        *widget.Button
+	chatStopButton        *widget.Button
+	chatRegenerateButton  *widget.Button
+	chatStatsLabel        *widget.Label
+	cancelChat            context.CancelFunc
+
+	// Completion tab
+	completionPromptEntry      *widget.Entry
+	completionPreview          *widget.Label
+	completionUseTemplateCheck *widget.Check
+	completionMaxTokensEntry   *widget.Entry
+	completionStopEntry        *widget.Entry
+	completionLogprobsEntry    *widget.Entry
+	completionStreamCheck      *widget.Check
+	completionOutput           *widget.Entry
+	completionLogprobsLabel    *widget.Label
+	completionGenerateButton   *widget.Button
+	completionStopButton       *widget.Button
+	completionStatsLabel       *widget.Label
+	cancelCompletion           context.CancelFunc
 }
 
-type Preset struct {
-	Name               string   `json:"name"`
-	MaxSeqLen          *int     `json:"max_seq_len,omitempty"`
-	OverrideBaseSeqLen *int     `json:"override_base_seq_len,omitempty"`
-	CacheSize          *int     `json:"cache_size,omitempty"`
-	GPUSplitAuto       bool     `json:"gpu_split_auto,omitempty"`
-	GPUSplit           string   `json:"gpu_split,omitempty"`
-	RopeScale          *float64 `json:"rope_scale,omitempty"`
-	RopeAlpha          *float64 `json:"rope_alpha,omitempty"`
-	CacheMode          string   `json:"cache_mode,omitempty"`
-	PromptTemplate     *string  `json:"prompt_template,omitempty"`
-	NumExpertsPerToken *int     `json:"num_experts_per_token,omitempty"`
-	DraftModelName     *string  `json:"draft_model_name,omitempty"`
-	DraftRopeScale     *float64 `json:"draft_rope_scale,omitempty"`
-	DraftRopeAlpha     *float64 `json:"draft_rope_alpha,omitempty"`
-	DraftCacheMode     string   `json:"draft_cache_mode,omitempty"`
-	Fasttensors        bool     `json:"fasttensors,omitempty"`
-	AutosplitReserve   string   `json:"autosplit_reserve,omitempty"`
-	ChunkSize          *int     `json:"chunk_size,omitempty"`
+// formRow tracks a model form row so messages can be shown beneath it.
+type formRow struct {
+	label string
+	hint  *canvas.Text
 }
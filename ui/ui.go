@@ -11,22 +11,34 @@ import (
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 	"github.com/sammcj/tabload/api"
+	"github.com/sammcj/tabload/download"
 	"github.com/sammcj/tabload/logging"
+	"github.com/sammcj/tabload/secrets"
+	"github.com/sammcj/tabload/store"
 )
 
-func NewTabLoad(w fyne.Window) *TabLoad {
-	initConfig()
-	t := &TabLoad{window: w}
+func NewTabLoad(w fyne.Window, presetStore store.PresetStore, configStore store.ConfigStore, samplerStore store.SamplerStore, secretStore secrets.Store) *TabLoad {
+	t := &TabLoad{
+		window:       w,
+		presetStore:  presetStore,
+		configStore:  configStore,
+		samplerStore: samplerStore,
+		secrets:      secretStore,
+	}
+	t.loadConfig()
+	t.migrateSecrets()
+	t.resetRequestContext()
 
 	// initialise UI elements
 	t.apiURLEntry = widget.NewEntry()
 	t.adminKeyEntry = widget.NewPasswordEntry()
+	t.apiKeyEntry = widget.NewPasswordEntry()
 	t.connectButton = widget.NewButton("Connect", t.handleConnect)
+	t.disconnectButton = widget.NewButton("Disconnect", t.handleDisconnect)
 
 	// initialise other necessary UI elements
 	t.modelsDropdown = widget.NewSelect([]string{}, func(selected string) {})
 	t.currentModelLabel = widget.NewLabel("")
-	t.currentLorasLabel = widget.NewLabel("")
 
 	// initialise all entry fields and checkboxes
 	t.maxSeqLenEntry = widget.NewEntry()
@@ -61,12 +73,9 @@ func NewTabLoad(w fyne.Window) *TabLoad {
 	t.chunkSizeCheck = widget.NewCheck("", nil)
 
 	// Set the API URL from config
-	config := t.GetConfig()
-
-	// Set the API URL from config
-	serverURL := config.LastConnectedServer
+	serverURL := t.config.LastConnectedServer
 	if serverURL == "" {
-		serverURL = config.APIURL
+		serverURL = t.config.APIURL
 	}
 	if serverURL == "" {
 		serverURL = "http://localhost:5000" // Default URL only if no server is set
@@ -76,6 +85,7 @@ func NewTabLoad(w fyne.Window) *TabLoad {
 
 	// initialise the client with the server URL
 	t.client = api.NewClient(serverURL, "")
+	t.downloads = download.NewQueue(t.client)
 
 	// Load default parameters
 	t.LoadDefaultParams()
@@ -95,16 +105,27 @@ func (t *TabLoad) BuildUI() {
 		container.NewTabItem("Connection", t.buildConnectionTab()),
 		container.NewTabItem("Model", t.buildModelTab()),
 		container.NewTabItem("LoRAs", t.buildLorasTab()),
+		container.NewTabItem("Chat", t.buildChatTab()),
+		container.NewTabItem("Completion", t.buildCompletionTab()),
 		container.NewTabItem("HF Downloader", t.buildHFDownloaderTab()),
 		container.NewTabItem("Presets", t.buildPresetTab()),
 		container.NewTabItem("Settings", t.buildSettingsTab()),
 		container.NewTabItem("Advanced", t.buildAdvancedSettingsTab()),
+		container.NewTabItem("Sampler Overrides", t.buildOverridesTab()),
 	)
 	tabs.SetTabLocation(container.TabLocationLeading)
 
+	t.window.SetOnDropped(func(_ fyne.Position, uris []fyne.URI) {
+		if tabs.Selected().Text == "Presets" {
+			t.handlePresetsDropped(uris)
+		}
+	})
+
 	saveDefaultsButton := widget.NewButton("Save as Default", func() {
 		if err := t.SaveDefaultParams(); err != nil {
-			dialog.ShowError(err, t.window)
+			if !t.presetFieldErrors("save the defaults", err) {
+				dialog.ShowError(err, t.window)
+			}
 		} else {
 			dialog.ShowInformation("Success", "Parameters saved as default", t.window)
 		}
@@ -151,10 +172,12 @@ func (t *TabLoad) BuildUI() {
 	t.ready = true
 	logging.Info("UI built successfully")
 
-	// Attempt auto-connect after UI is built
-	if config.AutoConnect && config.LastConnectedServer != "" {
-		go t.AutoConnect()
-	}
+	// Attempt auto-connect after UI is built, once saved keys can be read
+	t.unlockSecrets(func() {
+		if t.ShouldAutoConnect() && (t.config.LastConnectedServer != "" || t.config.LastProfile != "") {
+			go t.AutoConnect()
+		}
+	})
 }
 func (t *TabLoad) createToolbar(...*widget.Button) *widget.Toolbar {
 
@@ -198,9 +221,8 @@ func (t *TabLoad) createStatusBar() fyne.CanvasObject {
 
 func (t *TabLoad) SetInitialFocus() {
 	logging.Debug("Setting initial focus")
-	lastServer, err := t.loadLastConnectedServer()
 	if t.window.Canvas().Focused() == nil {
-		if err == nil && lastServer != "" {
+		if t.config.LastConnectedServer != "" {
 			t.window.Canvas().Focus(t.connectButton)
 		} else {
 			t.window.Canvas().Focus(t.apiURLEntry)
@@ -213,9 +235,7 @@ func (t *TabLoad) SetInitialFocus() {
 func (t *TabLoad) RefreshUI() {
 	logging.Debug("Refreshing UI")
 	t.modelsDropdown.Refresh()
-	t.lorasDropdown.Refresh()
 	t.currentModelLabel.Refresh()
-	t.currentLorasLabel.Refresh()
 	t.refreshCurrentModel()
 }
 
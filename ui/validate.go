@@ -0,0 +1,66 @@
+package ui
+
+import (
+	"fmt"
+	"strings"
+
+	"fyne.io/fyne/v2/widget"
+	"github.com/sammcj/tabload/store"
+	"github.com/sammcj/tabload/utils"
+)
+
+// fieldParser reads the model form's entries, collecting an error for each
+// one that doesn't parse instead of silently dropping it.
+type fieldParser struct {
+	errs store.ValidationErrors
+}
+
+// text returns the entry's trimmed text if its checkbox is ticked.
+func (f *fieldParser) text(check *widget.Check, entry *widget.Entry) string {
+	if check != nil && !check.Checked {
+		return ""
+	}
+	return strings.TrimSpace(entry.Text)
+}
+
+func (f *fieldParser) stringPointer(check *widget.Check, entry *widget.Entry) *string {
+	text := f.text(check, entry)
+	if text == "" {
+		return nil
+	}
+	return &text
+}
+
+// int parses a whole number.
+func (f *fieldParser) int(field string, check *widget.Check, entry *widget.Entry) *int {
+	return parseField(f, field, check, entry, utils.Int)
+}
+
+// tokens parses a token count, allowing suffixes like 8k.
+func (f *fieldParser) tokens(field string, check *widget.Check, entry *widget.Entry) *int {
+	return parseField(f, field, check, entry, utils.Tokens)
+}
+
+func (f *fieldParser) float(field string, check *widget.Check, entry *widget.Entry) *float64 {
+	return parseField(f, field, check, entry, utils.Float)
+}
+
+func parseField[T any](f *fieldParser, field string, check *widget.Check, entry *widget.Entry, parse func(field, value string) (T, error)) *T {
+	text := f.text(check, entry)
+	if text == "" {
+		return nil
+	}
+	v, err := parse(field, text)
+	if err != nil {
+		f.errs.AddParseError(err)
+		return nil
+	}
+	return &v
+}
+
+// showValidationErrors marks each invalid field under its row in the model
+// form and summarises them all in a dialog.
+func (t *TabLoad) showValidationErrors(action string, errs store.ValidationErrors) {
+	t.clearFormErrors()
+	t.showFieldErrors(fmt.Sprintf("can't %s, fix these first", action), errs.FieldErrors())
+}
@@ -1,71 +1,203 @@
+// Package utils parses the values users type into TabLoad's fields. Every
+// parser returns a *ParseError naming the field and the offending value
+// rather than guessing, so bad input can be reported next to the field.
 package utils
 
 import (
-	"log"
+	"fmt"
+	"math"
 	"strconv"
+	"strings"
+	"time"
 )
 
-func ParseInt(value string) int {
-	result, err := strconv.Atoi(value)
+// ParseError is a value that couldn't be parsed for a field.
+type ParseError struct {
+	Field string
+	Value string
+	// Reason says what was expected, e.g. "is not a whole number".
+	Reason string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %q %s", e.Field, e.Value, e.Reason)
+}
+
+// Problem describes the error without the field name, for showing next to
+// the field itself.
+func (e *ParseError) Problem() string {
+	return fmt.Sprintf("%q %s", e.Value, e.Reason)
+}
+
+func parseError(field, value, reason string, args ...interface{}) *ParseError {
+	return &ParseError{Field: field, Value: value, Reason: fmt.Sprintf(reason, args...)}
+}
+
+// Int parses a whole number.
+func Int(field, value string) (int, error) {
+	text := strings.TrimSpace(value)
+	v, err := strconv.Atoi(text)
 	if err != nil {
-		log.Fatal(err)
+		return 0, parseError(field, value, "is not a whole number")
 	}
-	return result
+	return v, nil
 }
 
-func ParseFloat64(value string) float64 {
-	result, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		log.Fatal(err)
+// Float parses a decimal number.
+func Float(field, value string) (float64, error) {
+	text := strings.TrimSpace(value)
+	v, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, parseError(field, value, "is not a number")
 	}
-	return result
+	return v, nil
 }
 
-func ParseIntToString(value int) string {
-	return strconv.Itoa(value)
+// tokenUnits are the suffixes Tokens accepts. Context lengths are quoted in
+// binary thousands, so 8k is 8192 tokens.
+var tokenUnits = map[string]float64{
+	"":  1,
+	"k": 1 << 10,
+	"m": 1 << 20,
 }
 
-func ParseIntPointer(s string) *int {
-	if s == "" {
-		return nil
+// Tokens parses a token count such as a sequence length, cache size or chunk
+// size: a whole number, or a number with a k or m suffix ("8k" is 8192,
+// "1.5k" is 1536).
+func Tokens(field, value string) (int, error) {
+	number, unit := splitUnit(value)
+	multiplier, ok := tokenUnits[strings.ToLower(unit)]
+	if !ok && number != "" {
+		return 0, parseError(field, value, "has unknown unit %q, use k or m", unit)
 	}
-	v, err := strconv.Atoi(s)
-	if err != nil {
-		return nil
+	return scaled(field, value, number, multiplier, "is not a token count like 8192 or 8k")
+}
+
+// sizeUnits are the suffixes Megabytes accepts, as multiples of a MiB.
+// Decimal units are converted exactly; binary ones are what TabbyAPI uses.
+var sizeUnits = map[string]float64{
+	"":    1,
+	"b":   1.0 / (1 << 20),
+	"kb":  1000.0 / (1 << 20),
+	"kib": 1.0 / (1 << 10),
+	"mb":  1000.0 * 1000 / (1 << 20),
+	"mib": 1,
+	"gb":  1000.0 * 1000 * 1000 / (1 << 20),
+	"gib": 1 << 10,
+	"tb":  1000.0 * 1000 * 1000 * 1000 / (1 << 20),
+	"tib": 1 << 20,
+}
+
+// Megabytes parses a memory size into whole MiB, rounding up. A bare number
+// is already MiB; sizes like "512MiB", "1.5GiB" or "2GB" are converted.
+func Megabytes(field, value string) (int, error) {
+	number, unit := splitUnit(value)
+	multiplier, ok := sizeUnits[strings.ToLower(unit)]
+	if !ok && number != "" {
+		return 0, parseError(field, value, "has unknown unit %q, use MiB, GiB, MB or GB", unit)
+	}
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, parseError(field, value, "is not a size like 96, 512MiB or 1.5GiB")
+	}
+	if v < 0 {
+		return 0, parseError(field, value, "can't be negative")
+	}
+	mib := math.Ceil(v*multiplier - 1e-9)
+	if mib > math.MaxInt32 {
+		return 0, parseError(field, value, "is too large")
 	}
-	return &v
+	return int(mib), nil
 }
 
-func ParseFloat64Pointer(s string) *float64 {
-	if s == "" {
-		return nil
+// Seconds parses a duration as whole seconds: a bare number of seconds, or a
+// duration like "90s", "30m" or "1h30m".
+func Seconds(field, value string) (int, error) {
+	text := strings.TrimSpace(value)
+	if seconds, err := strconv.Atoi(text); err == nil {
+		if seconds < 0 {
+			return 0, parseError(field, value, "can't be negative")
+		}
+		return seconds, nil
 	}
-	v, err := strconv.ParseFloat(s, 64)
+	d, err := time.ParseDuration(text)
 	if err != nil {
-		return nil
+		return 0, parseError(field, value, "is not a duration like 600, 90s or 30m")
 	}
-	return &v
+	if d < 0 {
+		return 0, parseError(field, value, "can't be negative")
+	}
+	return int(math.Ceil(d.Seconds())), nil
 }
 
-func ParseStringPointer(s string) *string {
-	if s == "" {
-		return nil
+// FormatBytes formats a size in bytes with binary units, e.g. "4.2 GiB", the
+// same units Megabytes accepts.
+func FormatBytes(bytes int64) string {
+	units := []string{"B", "KiB", "MiB", "GiB", "TiB"}
+	size := float64(bytes)
+	unit := 0
+	for size >= 1024 && unit < len(units)-1 {
+		size /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d B", bytes)
 	}
-	return &s
+	return fmt.Sprintf("%.1f %s", size, units[unit])
 }
 
-func ParseIntOrZero(s string) int {
-	i, err := strconv.Atoi(s)
-	if err != nil {
-		return 0
+// FloatList parses comma-separated decimal numbers, e.g. a GPU split.
+func FloatList(field, value string) ([]float64, error) {
+	return list(field, value, Float)
+}
+
+// MegabytesList parses comma-separated memory sizes, e.g. a reserve per GPU.
+func MegabytesList(field, value string) ([]int, error) {
+	return list(field, value, Megabytes)
+}
+
+func list[T any](field, value string, parse func(field, value string) (T, error)) ([]T, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, parseError(field, value, "is empty")
+	}
+	parts := strings.Split(value, ",")
+	values := make([]T, 0, len(parts))
+	for i, part := range parts {
+		v, err := parse(fmt.Sprintf("%s[%d]", field, i), part)
+		if err != nil {
+			// Report the whole list, so the user can find the bad entry
+			return nil, parseError(field, value, "has a bad entry %d: %s", i+1, err.(*ParseError).Problem())
+		}
+		values = append(values, v)
 	}
-	return i
+	return values, nil
 }
 
-func ParseFloatOrZero(s string) float64 {
-	f, err := strconv.ParseFloat(s, 64)
-	if err != nil {
-		return 0
+// splitUnit splits "1.5GiB" into "1.5" and "GiB".
+func splitUnit(value string) (number, unit string) {
+	text := strings.TrimSpace(value)
+	i := len(text)
+	for i > 0 && isUnitLetter(text[i-1]) {
+		i--
+	}
+	return strings.TrimSpace(text[:i]), text[i:]
+}
+
+func isUnitLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func scaled(field, value, number string, multiplier float64, reason string) (int, error) {
+	v, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, parseError(field, value, "%s", reason)
+	}
+	result := v * multiplier
+	if result != math.Trunc(result) {
+		return 0, parseError(field, value, "is not a whole number of tokens")
+	}
+	if math.Abs(result) > math.MaxInt32 {
+		return 0, parseError(field, value, "is too large")
 	}
-	return f
+	return int(result), nil
 }
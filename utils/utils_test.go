@@ -0,0 +1,242 @@
+package utils
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// checkError checks err is a *ParseError for field and value, or nil if
+// wantErr is false.
+func checkError(t *testing.T, err error, wantErr bool, field, value string) {
+	t.Helper()
+	if !wantErr {
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		return
+	}
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got error %v, want a *ParseError", err)
+	}
+	if parseErr.Field != field || parseErr.Value != value {
+		t.Errorf("got Field %q, Value %q, want %q, %q", parseErr.Field, parseErr.Value, field, value)
+	}
+}
+
+func TestInt(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"42", 42, false},
+		{" 42 ", 42, false},
+		{"-3", -3, false},
+		{"4.5", 0, true},
+		{"8k", 0, true},
+		{"", 0, true},
+		{"abc", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Int("n", tt.value)
+			checkError(t, err, tt.wantErr, "n", tt.value)
+			if got != tt.want {
+				t.Errorf("Int(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFloat(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    float64
+		wantErr bool
+	}{
+		{"1.5", 1.5, false},
+		{" 2 ", 2, false},
+		{"-0.25", -0.25, false},
+		{"1e3", 1000, false},
+		{"NaN", 0, true},
+		{"Inf", 0, true},
+		{"1.5x", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Float("rope_alpha", tt.value)
+			checkError(t, err, tt.wantErr, "rope_alpha", tt.value)
+			if got != tt.want {
+				t.Errorf("Float(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokens(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"32768", 32768, false},
+		{"8k", 8192, false},
+		{"8K", 8192, false},
+		{" 1.5k ", 1536, false},
+		{"1m", 1 << 20, false},
+		{"0", 0, false},
+		{"1.0001k", 0, true}, // Not a whole number of tokens
+		{"8kb", 0, true},     // Unknown unit
+		{"8x", 0, true},
+		{"k", 0, true},
+		{"3000000k", 0, true}, // Too large
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Tokens("max_seq_len", tt.value)
+			checkError(t, err, tt.wantErr, "max_seq_len", tt.value)
+			if got != tt.want {
+				t.Errorf("Tokens(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMegabytes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"96", 96, false},
+		{"512MiB", 512, false},
+		{"1.5GiB", 1536, false},
+		{"1.5gib", 1536, false},
+		{"2GB", 1908, false}, // 2e9 bytes, rounded up to whole MiB
+		{"1TiB", 1 << 20, false},
+		{"1KiB", 1, false}, // Rounded up
+		{"0", 0, false},
+		{"1.5", 2, false},
+		{"-1", 0, true},
+		{"-1GiB", 0, true},
+		{"5PB", 0, true}, // Unknown unit
+		{"x", 0, true},
+		{"GiB", 0, true},
+		{"1e30GiB", 0, true}, // Too large
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Megabytes("autosplit_reserve", tt.value)
+			checkError(t, err, tt.wantErr, "autosplit_reserve", tt.value)
+			if got != tt.want {
+				t.Errorf("Megabytes(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSeconds(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{"600", 600, false},
+		{"90s", 90, false},
+		{"30m", 1800, false},
+		{"1h30m", 5400, false},
+		{"1.5s", 2, false}, // Rounded up
+		{"-5", 0, true},
+		{"-5m", 0, true},
+		{"5 minutes", 0, true},
+		{"", 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := Seconds("timeout", tt.value)
+			checkError(t, err, tt.wantErr, "timeout", tt.value)
+			if got != tt.want {
+				t.Errorf("Seconds(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFloatList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []float64
+		wantErr bool
+	}{
+		{"20", []float64{20}, false},
+		{"20,24", []float64{20, 24}, false},
+		{" 20 , 24.5 ", []float64{20, 24.5}, false},
+		{"20,,24", nil, true}, // Empty entry
+		{"20,", nil, true},
+		{"20,x", nil, true},
+		{"", nil, true},
+		{"  ", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := FloatList("gpu_split", tt.value)
+			checkError(t, err, tt.wantErr, "gpu_split", tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FloatList(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMegabytesList(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []int
+		wantErr bool
+	}{
+		{"96", []int{96}, false},
+		{"96,1.5GiB", []int{96, 1536}, false},
+		{"512MiB, 2GB", []int{512, 1908}, false},
+		{"96,,96", nil, true}, // Empty entry
+		{"96,-1", nil, true},
+		{"96,5PB", nil, true},
+		{"1e30GiB", nil, true},
+		{"", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := MegabytesList("autosplit_reserve", tt.value)
+			checkError(t, err, tt.wantErr, "autosplit_reserve", tt.value)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MegabytesList(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListErrorNamesEntry(t *testing.T) {
+	_, err := FloatList("gpu_split", "20,x")
+	want := `gpu_split: "20,x" has a bad entry 2: "x" is not a number`
+	if err == nil || err.Error() != want {
+		t.Errorf("got %v, want %s", err, want)
+	}
+}
+
+func TestParseErrorProblem(t *testing.T) {
+	_, err := Tokens("cache_size", "8x")
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("got %v, want a *ParseError", err)
+	}
+	if got, want := parseErr.Problem(), `"8x" has unknown unit "x", use k or m`; got != want {
+		t.Errorf("Problem() = %s, want %s", got, want)
+	}
+	if got, want := parseErr.Error(), `cache_size: "8x" has unknown unit "x", use k or m`; got != want {
+		t.Errorf("Error() = %s, want %s", got, want)
+	}
+}